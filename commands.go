@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/claykom/website/internal/assets"
+	"github.com/claykom/website/internal/config"
+	"github.com/claykom/website/internal/content"
+	"github.com/claykom/website/internal/export"
+	"github.com/claykom/website/internal/health"
+	"github.com/claykom/website/internal/linkcheck"
+	"github.com/claykom/website/internal/router"
+	"github.com/claykom/website/internal/tracing"
+)
+
+// check validates the configuration and every content file, printing all
+// problems rather than stopping at the first
+func check(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	options := configFlags(flags)
+	flags.Parse(args)
+
+	var problems []error
+	preferDisk := false
+	cfg, err := config.LoadFrom(options())
+	if err != nil {
+		problems = append(problems, err)
+	} else {
+		preferDisk = cfg.App.PreferDisk
+	}
+
+	site := siteFiles(preferDisk)
+	contentFiles, err := fs.Sub(site, "content")
+	if err != nil {
+		return err
+	}
+	checked, errs := content.Check(contentFiles)
+	problems = append(problems, errs...)
+
+	staticFiles, err := fs.Sub(site, "static")
+	if err != nil {
+		return err
+	}
+	if _, err := assets.NewManifest(staticFiles); err != nil {
+		problems = append(problems, fmt.Errorf("static files: %w", err))
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("found problems in the configuration or content")
+	}
+	fmt.Printf("Configuration and %d content files OK\n", checked)
+	return nil
+}
+
+// newContent scaffolds a post or project in the content directory
+func newContent(args []string) error {
+	if len(args) == 0 || (args[0] != "post" && args[0] != "project") {
+		return errors.New(`want "new post TITLE" or "new project TITLE"`)
+	}
+	kind := args[0]
+
+	flags := flag.NewFlagSet("new "+kind, flag.ExitOnError)
+	dir := flags.String("dir", "content", "content directory")
+	slug := flags.String("slug", "", "slug; defaults to one made from the title")
+	date := flags.String("date", time.Now().Format(content.DateFormat), "publish date, YYYY-MM-DD")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: website new %s [flags] TITLE\n", kind)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args[1:])
+
+	title := strings.Join(flags.Args(), " ")
+	day, err := time.Parse(content.DateFormat, *date)
+	if err != nil {
+		return fmt.Errorf("invalid -date: %w", err)
+	}
+
+	create := content.NewPost
+	if kind == "project" {
+		create = content.NewProject
+	}
+	path, err := create(*dir, title, *slug, day)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Created", path)
+	return nil
+}
+
+// localRouter builds the router from cfg for rendering pages in-process.
+// Every page is requested from the same address, and a redirect would
+// leave a page unrendered, so rate limiting and redirects are turned off.
+func localRouter(cfg *config.Config) (*router.Router, fs.FS, error) {
+	cfg.Security.RateLimitRequests = 0
+	// One request log line per page would bury the result
+	slog.SetLogLoggerLevel(slog.LevelWarn)
+
+	site := siteFiles(cfg.App.PreferDisk)
+	r := router.New(cfg, tracing.NewTracer(nil), site, router.WithoutRedirects())
+	for _, result := range r.Health().Run(context.Background()).Checks {
+		if result.Status != health.StatusOK {
+			return nil, nil, fmt.Errorf("%s check failed: %s", result.Name, result.Error)
+		}
+	}
+	return r, site, nil
+}
+
+// pagePaths returns the paths of the router's pages
+func pagePaths(r *router.Router) []string {
+	var paths []string
+	for _, page := range r.Pages() {
+		paths = append(paths, page.Path)
+	}
+	return paths
+}
+
+// exportSite renders every page through the router into a directory of
+// static files
+func exportSite(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	out := flags.String("out", "dist", "directory to write the site to; must be empty or not exist")
+	options := configFlags(flags)
+	flags.Parse(args)
+
+	cfg, err := config.LoadFrom(options())
+	if err != nil {
+		return err
+	}
+	// Without a request host, pages would name http://example.com as
+	// their canonical URL
+	if cfg.Site.BaseURL == "" {
+		return errors.New("BASE_URL is required to export; set it or pass -base-url")
+	}
+	// Object storage serves each page as a directory index, so pages link
+	// to each other and are listed with a trailing slash
+	cfg.Server.TrailingSlash = "add"
+	r, site, err := localRouter(cfg)
+	if err != nil {
+		return err
+	}
+
+	staticFiles, err := fs.Sub(site, "static")
+	if err != nil {
+		return err
+	}
+	manifest, err := assets.NewManifest(staticFiles)
+	if err != nil {
+		return err
+	}
+
+	result, err := export.Site(r, export.Options{
+		Out:      *out,
+		Pages:    pagePaths(r),
+		Files:    r.Files(),
+		Static:   staticFiles,
+		Manifest: manifest,
+	})
+	for _, skipped := range result.Skipped {
+		fmt.Fprintln(os.Stderr, "Skipped", skipped, "which the server refuses to serve")
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d pages and %d files to %s\n", result.Pages, result.Files, *out)
+	return nil
+}
+
+// checkLinks renders every page and reports broken links, missing anchors
+// and images without alt text
+func checkLinks(args []string) error {
+	flags := flag.NewFlagSet("links", flag.ExitOnError)
+	external := flags.String("external", "", "comma-separated hosts whose links are requested, or * for any host")
+	options := configFlags(flags)
+	flags.Parse(args)
+
+	cfg, err := config.LoadFrom(options())
+	if err != nil {
+		return err
+	}
+	// Routes only match paths without a trailing slash once the redirects
+	// are off, so pages must link to that form
+	cfg.Server.TrailingSlash = ""
+	r, _, err := localRouter(cfg)
+	if err != nil {
+		return err
+	}
+
+	var hosts []string
+	for _, host := range strings.Split(*external, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	paths := pagePaths(r)
+	problems := linkcheck.Check(r, linkcheck.Options{Pages: paths, ExternalHosts: hosts})
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problems", len(problems))
+	}
+	fmt.Printf("Links on %d pages OK\n", len(paths))
+	return nil
+}
+
+// configCommand handles "config print"
+func configCommand(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New(`want "config print"`)
+	}
+
+	flags := flag.NewFlagSet("config print", flag.ExitOnError)
+	options := configFlags(flags)
+	flags.Parse(args[1:])
+
+	cfg, err := config.LoadFrom(options())
+	if err != nil {
+		return err
+	}
+	return cfg.WriteYAML(os.Stdout)
+}
+
+// healthcheck requests the liveness probe, or with -ready the readiness
+// probe, of the server on this machine, for container health checks
+func healthcheck(args []string) error {
+	flags := flag.NewFlagSet("healthcheck", flag.ExitOnError)
+	ready := flags.Bool("ready", false, "check /readyz instead of /healthz")
+	timeout := flags.Duration("timeout", 3*time.Second, "request timeout")
+	options := configFlags(flags)
+	flags.Parse(args)
+
+	cfg, err := config.LoadFrom(options())
+	if err != nil {
+		return err
+	}
+
+	host := cfg.Server.Host
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	path := "/healthz"
+	if *ready {
+		path = "/readyz"
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	scheme := "http"
+	if cfg.TLS.Enabled {
+		scheme = "https"
+		// The certificate names the public host, not the address dialled
+		// here; the check is about the server answering, not its identity
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+
+	resp, err := client.Get(scheme + "://" + net.JoinHostPort(host, strconv.Itoa(cfg.Server.Port)) + path)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", path, resp.Status)
+	}
+	return nil
+}
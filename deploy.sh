@@ -10,6 +10,12 @@ APP_NAME="website"
 DOCKER_IMAGE="${APP_NAME}:latest"
 CONTAINER_NAME="${APP_NAME}-container"
 
+# Build metadata, see internal/buildinfo
+VERSION="$(git describe --tags --always --dirty 2>/dev/null || echo dev)"
+COMMIT="$(git rev-parse HEAD 2>/dev/null || true)"
+BUILD_DATE="$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+BUILDINFO="github.com/claykom/website/internal/buildinfo"
+
 # Colors for output
 RED='\033[0;31m'
 GREEN='\033[0;32m'
@@ -71,9 +77,10 @@ build_app() {
     go clean
     go mod tidy
     
-    # Build with security flags
+    # Build with security flags, embedding static files and content
     CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build \
-        -ldflags='-w -s -extldflags "-static"' \
+        -tags embed \
+        -ldflags="-w -s -extldflags '-static' -X ${BUILDINFO}.version=${VERSION} -X ${BUILDINFO}.commit=${COMMIT} -X ${BUILDINFO}.date=${BUILD_DATE}" \
         -a -installsuffix cgo \
         -o "${APP_NAME}" .
     
@@ -88,6 +95,9 @@ build_docker() {
     docker build \
         --no-cache \
         --pull \
+        --build-arg VERSION="${VERSION}" \
+        --build-arg COMMIT="${COMMIT}" \
+        --build-arg BUILD_DATE="${BUILD_DATE}" \
         -t "${DOCKER_IMAGE}" \
         .
     
@@ -1,9 +1,15 @@
 module github.com/claykom/website
 
-go 1.25
+go 1.25.0
 
 require (
 	github.com/a-h/templ v0.3.943
+	github.com/andybalholm/brotli v1.2.0
 	github.com/gomarkdown/markdown v0.0.0-20250810172220-2e2c11897d1a
 	github.com/gorilla/mux v1.8.1
+	golang.org/x/crypto v0.54.0
+	golang.org/x/net v0.56.0
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/text v0.40.0 // indirect
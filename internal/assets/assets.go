@@ -0,0 +1,157 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// URLPrefix is where the static file server is mounted
+const URLPrefix = "/static/"
+
+// hashLen is the number of hex characters of the content hash kept in
+// fingerprinted file names
+const hashLen = 8
+
+// Manifest maps static file names to content-hashed names such as
+// css/style.css -> css/style.3f9a1c2b.css, so that hashed URLs can be
+// cached forever and change whenever the file does
+type Manifest struct {
+	hashed  map[string]string
+	logical map[string]string
+}
+
+// NewManifest hashes every file in fsys. Precompressed .gz and .br
+// siblings are skipped since they are served in place of their originals.
+func NewManifest(fsys fs.FS) (*Manifest, error) {
+	m := &Manifest{
+		hashed:  make(map[string]string),
+		logical: make(map[string]string),
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || IsPrecompressed(name) {
+			return nil
+		}
+
+		sum, err := hashFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		hashed := fingerprint(name, sum[:hashLen])
+		m.hashed[name] = hashed
+		m.logical[hashed] = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fingerprint inserts the hash before the extension
+func fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// IsPrecompressed reports whether name is a .gz or .br sibling
+func IsPrecompressed(name string) bool {
+	return strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".br")
+}
+
+// Hashed returns the fingerprinted name for a file relative to the static
+// root, and whether the file is known
+func (m *Manifest) Hashed(name string) (string, bool) {
+	if m == nil {
+		return name, false
+	}
+	hashed, ok := m.hashed[name]
+	if !ok {
+		return name, false
+	}
+	return hashed, true
+}
+
+// Resolve maps a requested file name back to the file on disk. fingerprinted
+// reports whether the request used the current hashed name, which is what
+// makes long-lived immutable caching safe. A name carrying a stale hash
+// resolves to the current file with fingerprinted false, so pages cached
+// from a previous deploy still get their assets.
+func (m *Manifest) Resolve(name string) (logical string, fingerprinted bool) {
+	if m == nil {
+		return name, false
+	}
+	if logical, ok := m.logical[name]; ok {
+		return logical, true
+	}
+
+	// style.0123abcd.css -> style.css, if that file exists
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if dot := strings.LastIndexByte(base, '.'); dot >= 0 && isHash(base[dot+1:]) {
+		candidate := base[:dot] + ext
+		if _, ok := m.hashed[candidate]; ok {
+			return candidate, false
+		}
+	}
+	return name, false
+}
+
+func isHash(s string) bool {
+	if len(s) != hashLen {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultManifest backs the Path helper used from templates
+var defaultManifest atomic.Pointer[Manifest]
+
+// SetDefault sets the manifest used by Path
+func SetDefault(m *Manifest) {
+	defaultManifest.Store(m)
+}
+
+// Path rewrites a static URL such as /static/css/style.css to its
+// fingerprinted form. URLs outside /static/ or unknown to the manifest are
+// returned unchanged, so templates work before a manifest is loaded.
+func Path(url string) string {
+	name, ok := strings.CutPrefix(url, URLPrefix)
+	if !ok {
+		return url
+	}
+	hashed, ok := defaultManifest.Load().Hashed(name)
+	if !ok {
+		return url
+	}
+	return URLPrefix + hashed
+}
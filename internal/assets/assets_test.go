@@ -0,0 +1,113 @@
+package assets
+
+import (
+	"regexp"
+	"testing"
+	"testing/fstest"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"css/style.css":    {Data: []byte("body { color: red; }")},
+		"css/style.css.br": {Data: []byte("compressed")},
+		"css/style.css.gz": {Data: []byte("compressed")},
+		"images/logo.png":  {Data: []byte("png")},
+		"robots":           {Data: []byte("no extension")},
+	}
+}
+
+func TestNewManifest(t *testing.T) {
+	m, err := NewManifest(testFS())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	hashed, ok := m.Hashed("css/style.css")
+	if !ok {
+		t.Fatal("Expected css/style.css to be in the manifest")
+	}
+	if !regexp.MustCompile(`^css/style\.[0-9a-f]{8}\.css$`).MatchString(hashed) {
+		t.Errorf("Unexpected fingerprinted name %q", hashed)
+	}
+
+	if hashed, _ := m.Hashed("robots"); !regexp.MustCompile(`^robots\.[0-9a-f]{8}$`).MatchString(hashed) {
+		t.Errorf("Unexpected fingerprinted name for extensionless file %q", hashed)
+	}
+
+	if _, ok := m.Hashed("css/style.css.br"); ok {
+		t.Error("Precompressed siblings should not be fingerprinted")
+	}
+}
+
+func TestManifestHashChangesWithContent(t *testing.T) {
+	fsys := testFS()
+	before, _ := NewManifest(fsys)
+
+	fsys["css/style.css"] = &fstest.MapFile{Data: []byte("body { color: blue; }")}
+	after, _ := NewManifest(fsys)
+
+	a, _ := before.Hashed("css/style.css")
+	b, _ := after.Hashed("css/style.css")
+	if a == b {
+		t.Error("Expected fingerprint to change when content changes")
+	}
+}
+
+func TestManifestResolve(t *testing.T) {
+	m, _ := NewManifest(testFS())
+	hashed, _ := m.Hashed("css/style.css")
+
+	tests := []struct {
+		name          string
+		input         string
+		logical       string
+		fingerprinted bool
+	}{
+		{"current hash", hashed, "css/style.css", true},
+		{"plain name", "css/style.css", "css/style.css", false},
+		{"stale hash", "css/style.0123abcd.css", "css/style.css", false},
+		{"hash-like segment of unknown file", "css/other.0123abcd.css", "css/other.0123abcd.css", false},
+		{"unknown file", "js/app.js", "js/app.js", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logical, fingerprinted := m.Resolve(tt.input)
+			if logical != tt.logical || fingerprinted != tt.fingerprinted {
+				t.Errorf("Resolve(%q) = %q, %v; want %q, %v", tt.input, logical, fingerprinted, tt.logical, tt.fingerprinted)
+			}
+		})
+	}
+}
+
+func TestNilManifest(t *testing.T) {
+	var m *Manifest
+	if name, ok := m.Hashed("css/style.css"); ok || name != "css/style.css" {
+		t.Error("Expected nil manifest to return names unchanged")
+	}
+	if name, fingerprinted := m.Resolve("css/style.css"); fingerprinted || name != "css/style.css" {
+		t.Error("Expected nil manifest to resolve names unchanged")
+	}
+}
+
+func TestPath(t *testing.T) {
+	SetDefault(nil)
+	if got := Path("/static/css/style.css"); got != "/static/css/style.css" {
+		t.Errorf("Expected unchanged URL without a manifest, got %q", got)
+	}
+
+	m, _ := NewManifest(testFS())
+	SetDefault(m)
+	defer SetDefault(nil)
+
+	hashed, _ := m.Hashed("css/style.css")
+	if got := Path("/static/css/style.css"); got != "/static/"+hashed {
+		t.Errorf("Expected /static/%s, got %q", hashed, got)
+	}
+	if got := Path("/static/missing.css"); got != "/static/missing.css" {
+		t.Errorf("Expected unknown file unchanged, got %q", got)
+	}
+	if got := Path("https://example.com/style.css"); got != "https://example.com/style.css" {
+		t.Errorf("Expected external URL unchanged, got %q", got)
+	}
+}
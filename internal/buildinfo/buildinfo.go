@@ -0,0 +1,147 @@
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// Set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/claykom/website/internal/buildinfo.version=v1.2.0
+//	  -X github.com/claykom/website/internal/buildinfo.commit=$(git rev-parse HEAD)
+//	  -X github.com/claykom/website/internal/buildinfo.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Unset values fall back to what the Go toolchain stamps into the binary.
+// dirty may be set to "true" when building from a modified tree outside
+// version control, such as a Docker build context without .git.
+var (
+	version string
+	commit  string
+	date    string
+	dirty   string
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string       `json:"version"`
+	Commit    string       `json:"commit,omitempty"`
+	BuildTime string       `json:"build_time,omitempty"`
+	GoVersion string       `json:"go_version"`
+	Dirty     bool         `json:"dirty"`
+	Deps      []Dependency `json:"deps,omitempty"`
+}
+
+// Dependency is a module linked into the binary
+type Dependency struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Replace string `json:"replace,omitempty"`
+}
+
+// Get returns the build information, read once
+var Get = sync.OnceValue(func() Info {
+	bi, _ := debug.ReadBuildInfo()
+	return read(bi, version, commit, date, dirty == "true")
+})
+
+// read combines link-time values with the toolchain's build info, which
+// is nil when unavailable. Without ldflags the version is the module
+// version Go derived from VCS, the commit is vcs.revision and the build
+// time is the commit time, vcs.time.
+func read(bi *debug.BuildInfo, version, commit, date string, dirty bool) Info {
+	info := Info{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: date,
+		GoVersion: runtime.Version(),
+		Dirty:     dirty,
+	}
+	if bi == nil {
+		if info.Version == "" {
+			info.Version = "dev"
+		}
+		return info
+	}
+
+	if bi.GoVersion != "" {
+		info.GoVersion = bi.GoVersion
+	}
+	if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = s.Value
+			}
+		case "vcs.modified":
+			if s.Value == "true" {
+				info.Dirty = true
+			}
+		}
+	}
+
+	for _, dep := range bi.Deps {
+		d := Dependency{Path: dep.Path, Version: dep.Version}
+		if dep.Replace != nil {
+			d.Replace = dep.Replace.Path
+			if dep.Replace.Version != "" {
+				d.Replace += "@" + dep.Replace.Version
+			}
+		}
+		info.Deps = append(info.Deps, d)
+	}
+
+	return info
+}
+
+// ShortCommit returns the first 7 characters of the commit
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 7 {
+		return i.Commit[:7]
+	}
+	return i.Commit
+}
+
+// Summary returns the build information without dependencies, for
+// places where the module list would reveal more than needed
+func (i Info) Summary() Info {
+	i.Deps = nil
+	return i
+}
+
+// String formats the build information on one line, e.g.
+// "v1.2.0 (3f9a1c2, dirty) built 2026-01-02T15:04:05Z with go1.25.0"
+func (i Info) String() string {
+	var b strings.Builder
+	b.WriteString(i.Version)
+
+	var details []string
+	if i.Commit != "" {
+		details = append(details, i.ShortCommit())
+	}
+	if i.Dirty {
+		details = append(details, "dirty")
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(details, ", "))
+	}
+
+	if i.BuildTime != "" {
+		fmt.Fprintf(&b, " built %s", i.BuildTime)
+	}
+	fmt.Fprintf(&b, " with %s", i.GoVersion)
+	return b.String()
+}
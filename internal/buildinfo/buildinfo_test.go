@@ -0,0 +1,136 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func testBuildInfo(modified string) *debug.BuildInfo {
+	return &debug.BuildInfo{
+		GoVersion: "go1.25.0",
+		Main:      debug.Module{Path: "github.com/claykom/website", Version: "(devel)"},
+		Deps: []*debug.Module{
+			{Path: "github.com/gorilla/mux", Version: "v1.8.1"},
+			{Path: "github.com/a-h/templ", Version: "v0.3.943", Replace: &debug.Module{Path: "../templ"}},
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "3f9a1c2b8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a"},
+			{Key: "vcs.time", Value: "2026-01-02T15:04:05Z"},
+			{Key: "vcs.modified", Value: modified},
+		},
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name     string
+		bi       *debug.BuildInfo
+		version  string
+		commit   string
+		date     string
+		dirty    bool
+		expected Info
+	}{
+		{
+			name: "vcs stamping only",
+			bi:   testBuildInfo("false"),
+			expected: Info{
+				Version:   "dev",
+				Commit:    "3f9a1c2b8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a",
+				BuildTime: "2026-01-02T15:04:05Z",
+				GoVersion: "go1.25.0",
+			},
+		},
+		{
+			name:    "ldflags take precedence",
+			bi:      testBuildInfo("true"),
+			version: "v1.2.0",
+			commit:  "abcdef0123456789",
+			date:    "2026-02-03T10:00:00Z",
+			expected: Info{
+				Version:   "v1.2.0",
+				Commit:    "abcdef0123456789",
+				BuildTime: "2026-02-03T10:00:00Z",
+				GoVersion: "go1.25.0",
+				Dirty:     true,
+			},
+		},
+		{
+			name:     "no build info",
+			bi:       nil,
+			commit:   "abcdef0",
+			dirty:    true,
+			expected: Info{Version: "dev", Commit: "abcdef0", GoVersion: runtime.Version(), Dirty: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := read(tt.bi, tt.version, tt.commit, tt.date, tt.dirty)
+
+			if got := info.Summary(); got.Version != tt.expected.Version || got.Commit != tt.expected.Commit ||
+				got.BuildTime != tt.expected.BuildTime || got.GoVersion != tt.expected.GoVersion || got.Dirty != tt.expected.Dirty {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReadModuleVersion(t *testing.T) {
+	bi := testBuildInfo("false")
+	bi.Main.Version = "v1.3.0"
+
+	if info := read(bi, "", "", "", false); info.Version != "v1.3.0" {
+		t.Errorf("Expected the module version v1.3.0, got %s", info.Version)
+	}
+}
+
+func TestReadDeps(t *testing.T) {
+	info := read(testBuildInfo("false"), "", "", "", false)
+
+	if len(info.Deps) != 2 {
+		t.Fatalf("Expected 2 dependencies, got %d", len(info.Deps))
+	}
+	if info.Deps[0] != (Dependency{Path: "github.com/gorilla/mux", Version: "v1.8.1"}) {
+		t.Errorf("Unexpected dependency %+v", info.Deps[0])
+	}
+	if info.Deps[1].Replace != "../templ" {
+		t.Errorf("Expected replacement ../templ, got %q", info.Deps[1].Replace)
+	}
+	if info.Summary().Deps != nil {
+		t.Error("Expected Summary to drop dependencies")
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     Info
+		expected string
+	}{
+		{
+			name:     "release",
+			info:     Info{Version: "v1.2.0", Commit: "3f9a1c2b8d7e", BuildTime: "2026-01-02T15:04:05Z", GoVersion: "go1.25.0"},
+			expected: "v1.2.0 (3f9a1c2) built 2026-01-02T15:04:05Z with go1.25.0",
+		},
+		{
+			name:     "dirty",
+			info:     Info{Version: "dev", Commit: "3f9a1c2b8d7e", GoVersion: "go1.25.0", Dirty: true},
+			expected: "dev (3f9a1c2, dirty) with go1.25.0",
+		},
+		{
+			name:     "bare",
+			info:     Info{Version: "dev", GoVersion: "go1.25.0"},
+			expected: "dev with go1.25.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.String(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/claykom/website/internal/config"
+	"github.com/claykom/website/internal/health"
+	"github.com/claykom/website/internal/tracing"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Manager hands out server certificates. With ACME configured, certificates
+// for the configured domains are obtained and renewed automatically; a
+// file-based certificate, if also configured, is served whenever ACME
+// can't provide one. File certificates are reloaded when the files change.
+type Manager struct {
+	acme     *autocert.Manager
+	domains  []string
+	files    *fileCertificate
+	settings config.TLSConfig
+}
+
+// New creates a Manager from the TLS configuration
+func New(cfg config.TLSConfig) (*Manager, error) {
+	m := &Manager{domains: cfg.ACME.Domains, settings: cfg}
+
+	if cfg.CertFile != "" && cfg.KeyFile != "" {
+		files, err := loadFileCertificate(cfg.CertFile, cfg.KeyFile, cfg.OCSPStapleFile)
+		if err != nil {
+			return nil, err
+		}
+		m.files = files
+	}
+
+	if len(cfg.ACME.Domains) > 0 {
+		client, err := acmeClient(cfg.ACME)
+		if err != nil {
+			return nil, err
+		}
+		m.acme = &autocert.Manager{
+			Prompt:      autocert.AcceptTOS,
+			Cache:       autocert.DirCache(cfg.ACME.CacheDir),
+			HostPolicy:  autocert.HostWhitelist(cfg.ACME.Domains...),
+			RenewBefore: cfg.ACME.RenewBefore,
+			Client:      client,
+			Email:       cfg.ACME.Email,
+		}
+	}
+
+	if m.acme == nil && m.files == nil {
+		return nil, errors.New("no certificate source: set TLS_CERT_FILE and TLS_KEY_FILE or ACME_DOMAINS")
+	}
+	return m, nil
+}
+
+// acmeClient returns a client for the configured directory, trusting the
+// extra CA root if one is set. Its requests are traced.
+func acmeClient(cfg config.ACMEConfig) (*acme.Client, error) {
+	client := &acme.Client{DirectoryURL: cfg.DirectoryURL}
+	if client.DirectoryURL == "" {
+		client.DirectoryURL = autocert.DefaultACMEDirectory
+	}
+
+	var base http.RoundTripper
+	if cfg.CARootFile != "" {
+		pem, err := os.ReadFile(cfg.CARootFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading ACME CA root: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ACME CA root %s contains no PEM certificates", cfg.CARootFile)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+		base = transport
+	}
+	client.HTTPClient = &http.Client{Transport: tracing.NewTransport(base)}
+
+	return client, nil
+}
+
+// GetCertificate returns the certificate for a TLS handshake. ACME
+// failures fall back to the file certificate; they are logged when the
+// client asked for one of the ACME domains, since handshakes for other
+// names (scanners connecting by IP) are expected to fail.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if m.acme == nil {
+		return m.files.get(), nil
+	}
+
+	cert, err := m.acme.GetCertificate(hello)
+	if err == nil {
+		return cert, nil
+	}
+	if slices.Contains(m.domains, strings.ToLower(hello.ServerName)) {
+		slog.Warn("acme certificate unavailable", "server_name", hello.ServerName, "error", err, "fallback", m.files != nil)
+	}
+	if m.files != nil {
+		return m.files.get(), nil
+	}
+	return nil, err
+}
+
+// Reload re-reads the certificate files, e.g. on SIGHUP after a renewal.
+// The previous certificate stays in use if the new files can't be loaded.
+// It does nothing without file certificates.
+func (m *Manager) Reload() error {
+	if m.files == nil {
+		return nil
+	}
+	return m.files.reload()
+}
+
+// TLSConfig returns a server TLS configuration using the Manager's
+// certificates and the configured protocol settings. With ACME it also
+// answers TLS-ALPN-01 challenges.
+func (m *Manager) TLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		GetCertificate:   m.GetCertificate,
+		NextProtos:       []string{"h2", "http/1.1"},
+		MinVersion:       m.settings.MinVersion,
+		CipherSuites:     m.settings.CipherSuites,
+		CurvePreferences: m.settings.CurvePreferences,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	if m.acme != nil {
+		cfg.NextProtos = append(cfg.NextProtos, acme.ALPNProto)
+	}
+	return cfg
+}
+
+// HTTPHandler answers ACME HTTP-01 challenges on the plain HTTP listener
+// and passes every other request to fallback
+func (m *Manager) HTTPHandler(fallback http.Handler) http.Handler {
+	if m.acme == nil {
+		return fallback
+	}
+	return m.acme.HTTPHandler(fallback)
+}
+
+// ExpiryCheck returns a health check that fails when a certificate expires
+// within minValidity, which means renewal isn't working. With ACME the
+// cached certificates are checked; domains without one yet pass, since
+// the first handshake obtains it. A file certificate is only checked when
+// it is the only source, as it is merely a fallback otherwise.
+func (m *Manager) ExpiryCheck(minValidity time.Duration) health.Check {
+	return func(ctx context.Context) error {
+		if m.acme == nil {
+			return checkExpiry(m.files.get().Leaf, minValidity)
+		}
+		for _, domain := range m.domains {
+			// autocert caches ECDSA and RSA certificates separately
+			for _, key := range []string{domain, domain + "+rsa"} {
+				data, err := m.acme.Cache.Get(ctx, key)
+				if errors.Is(err, autocert.ErrCacheMiss) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				leaf, err := cachedLeaf(data)
+				if err != nil {
+					return fmt.Errorf("cached certificate for %s: %w", key, err)
+				}
+				if err := checkExpiry(leaf, minValidity); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// checkExpiry fails if leaf expires within minValidity
+func checkExpiry(leaf *x509.Certificate, minValidity time.Duration) error {
+	if remaining := time.Until(leaf.NotAfter); remaining < minValidity {
+		return fmt.Errorf("certificate for %s expires in %s", strings.Join(leaf.DNSNames, ", "), remaining.Round(time.Minute))
+	}
+	return nil
+}
+
+// cachedLeaf parses the leaf from an autocert cache entry, which holds the
+// private key followed by the certificate chain in PEM
+func cachedLeaf(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no certificate")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/claykom/website/internal/config"
+	"github.com/claykom/website/internal/testutils"
+	"golang.org/x/crypto/acme"
+)
+
+// writeTestCertificate writes a self-signed certificate for names and
+// returns the certificate and key paths
+func writeTestCertificate(t *testing.T, dir string, notAfter time.Time, names ...string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// leafName returns the first DNS name of a certificate
+func leafName(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.DNSNames[0]
+}
+
+// brokenACMEServer is an ACME directory that always fails, standing in for
+// a CA that is down. It answers 404 because the ACME client retries 5xx
+// responses with backoff. It returns a config trusting the server's
+// certificate.
+func brokenACMEServer(t *testing.T) config.ACMEConfig {
+	t.Helper()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	root := filepath.Join(t.TempDir(), "root.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(root, pemBytes, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return config.ACMEConfig{
+		Domains:      []string{"example.com"},
+		CacheDir:     t.TempDir(),
+		DirectoryURL: srv.URL + "/directory",
+		CARootFile:   root,
+		RenewBefore:  720 * time.Hour,
+	}
+}
+
+func TestNewRequiresCertificateSource(t *testing.T) {
+	if _, err := New(config.TLSConfig{}); err == nil {
+		t.Error("Expected an error without certificate files or ACME domains")
+	}
+}
+
+func TestNewRejectsBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, err := New(config.TLSConfig{CertFile: filepath.Join(dir, "missing.pem"), KeyFile: filepath.Join(dir, "missing.key")})
+	if err == nil {
+		t.Error("Expected an error for missing certificate files")
+	}
+
+	bad := filepath.Join(dir, "root.pem")
+	os.WriteFile(bad, []byte("not pem"), 0600)
+	_, err = New(config.TLSConfig{ACME: config.ACMEConfig{Domains: []string{"example.com"}, CARootFile: bad}})
+	if err == nil {
+		t.Error("Expected an error for a CA root without certificates")
+	}
+}
+
+func TestFileCertificate(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir(), time.Now().Add(time.Hour), "files.example.com")
+	m, err := New(config.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "anything.example.com"})
+	if err != nil {
+		t.Fatalf("GetCertificate failed: %v", err)
+	}
+	if name := leafName(t, cert); name != "files.example.com" {
+		t.Errorf("Expected the file certificate, got one for %s", name)
+	}
+
+	if slices.Contains(m.TLSConfig().NextProtos, acme.ALPNProto) {
+		t.Error("Expected no TLS-ALPN-01 protocol without ACME")
+	}
+}
+
+func TestACMEFallsBackToFileCertificate(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir(), time.Now().Add(time.Hour), "files.example.com")
+	m, err := New(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ACME: brokenACMEServer(t)})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	for _, serverName := range []string{"example.com", "", "not-configured.example.org"} {
+		cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+		if err != nil {
+			t.Fatalf("Expected fallback for %q, got error %v", serverName, err)
+		}
+		if name := leafName(t, cert); name != "files.example.com" {
+			t.Errorf("Expected the file certificate for %q, got one for %s", serverName, name)
+		}
+	}
+}
+
+func TestACMEWithoutFallbackFails(t *testing.T) {
+	m, err := New(config.TLSConfig{ACME: brokenACMEServer(t)})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com"}); err == nil {
+		t.Error("Expected an error when the CA is unavailable and there is no fallback")
+	}
+}
+
+func TestACMEChallenges(t *testing.T) {
+	m, err := New(config.TLSConfig{ACME: brokenACMEServer(t)})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if !slices.Contains(m.TLSConfig().NextProtos, acme.ALPNProto) {
+		t.Error("Expected TLS-ALPN-01 protocol to be offered")
+	}
+
+	fallbackCalled := false
+	handler := m.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fallbackCalled = true
+		w.WriteHeader(http.StatusMovedPermanently)
+	}))
+
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, testutils.NewTestRequest("GET", "/blog", ""))
+	rr.AssertStatusCode(t, http.StatusMovedPermanently)
+	if !fallbackCalled {
+		t.Error("Expected ordinary requests to reach the fallback handler")
+	}
+
+	// Unknown challenge tokens are answered by the ACME handler itself
+	fallbackCalled = false
+	req := testutils.NewTestRequest("GET", "/.well-known/acme-challenge/unknown", "")
+	req.Host = "example.com"
+	rr = testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, req)
+	if fallbackCalled {
+		t.Error("Expected challenge requests not to reach the fallback handler")
+	}
+	if rr.Code == http.StatusOK {
+		t.Error("Expected an unknown challenge token to be rejected")
+	}
+}
+
+func TestExpiryCheck(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir(), time.Now().Add(48*time.Hour), "example.com")
+	m, err := New(config.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if err := m.ExpiryCheck(24 * time.Hour)(context.Background()); err != nil {
+		t.Errorf("Expected a certificate valid for 48h to pass, got %v", err)
+	}
+	if err := m.ExpiryCheck(7 * 24 * time.Hour)(context.Background()); err == nil {
+		t.Error("Expected a certificate valid for 48h to fail a 7 day minimum")
+	}
+}
+
+func TestExpiryCheckACME(t *testing.T) {
+	cfg := brokenACMEServer(t)
+	m, err := New(config.TLSConfig{ACME: cfg})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	check := m.ExpiryCheck(7 * 24 * time.Hour)
+
+	// Not issued yet: the first handshake will obtain it
+	if err := check(context.Background()); err != nil {
+		t.Errorf("Expected a missing certificate to pass, got %v", err)
+	}
+
+	// autocert caches the key followed by the chain under the domain name
+	certFile, keyFile := writeTestCertificate(t, t.TempDir(), time.Now().Add(48*time.Hour), "example.com")
+	keyPEM, _ := os.ReadFile(keyFile)
+	certPEM, _ := os.ReadFile(certFile)
+	if err := os.WriteFile(filepath.Join(cfg.CacheDir, "example.com"), append(keyPEM, certPEM...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := check(context.Background()); err == nil {
+		t.Error("Expected a cached certificate close to expiry to fail")
+	}
+}
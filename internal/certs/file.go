@@ -0,0 +1,156 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// certCheckInterval limits how often handshakes check the certificate
+// files for changes
+const certCheckInterval = 10 * time.Second
+
+// fileCertificate is a certificate loaded from PEM files, with an optional
+// DER-encoded OCSP response to staple. It is reloaded when the files change
+// or on demand, and keeps serving the previous certificate if a reload
+// fails, so a half-written renewal never takes the site down.
+type fileCertificate struct {
+	certFile string
+	keyFile  string
+	ocspFile string
+
+	cert      atomic.Pointer[tls.Certificate]
+	lastCheck atomic.Int64
+
+	mu         sync.Mutex // serialises reloads
+	stamp      string
+	ocspExpiry time.Time
+}
+
+// loadFileCertificate loads the certificate, failing if the files can't be
+// read
+func loadFileCertificate(certFile, keyFile, ocspFile string) (*fileCertificate, error) {
+	f := &fileCertificate{certFile: certFile, keyFile: keyFile, ocspFile: ocspFile}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	f.lastCheck.Store(time.Now().UnixNano())
+	return f, nil
+}
+
+// get returns the current certificate, reloading it first if the files
+// changed or the stapled OCSP response expired since the last check
+func (f *fileCertificate) get() *tls.Certificate {
+	now := time.Now()
+	last := f.lastCheck.Load()
+	if now.UnixNano()-last >= int64(certCheckInterval) && f.lastCheck.CompareAndSwap(last, now.UnixNano()) {
+		if f.stale(now) {
+			if err := f.reload(); err != nil {
+				slog.Error("reloading TLS certificate", "cert_file", f.certFile, "error", err)
+			}
+		}
+	}
+	return f.cert.Load()
+}
+
+// stale reports whether the files changed or the staple expired
+func (f *fileCertificate) stale(now time.Time) bool {
+	stamp, err := f.fileStamp()
+	if err != nil {
+		// Mid-rotation; the next check will see the new files
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return stamp != f.stamp || (!f.ocspExpiry.IsZero() && now.After(f.ocspExpiry))
+}
+
+// reload reads the files and swaps in the new certificate
+func (f *fileCertificate) reload() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stamp, err := f.fileStamp()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading certificate: %w", err)
+	}
+
+	f.ocspExpiry = time.Time{}
+	if f.ocspFile != "" {
+		staple, expiry, err := loadOCSPStaple(f.ocspFile, cert)
+		if err != nil {
+			// Serving without a staple is better than not serving
+			slog.Warn("not stapling OCSP response", "file", f.ocspFile, "error", err)
+		} else {
+			cert.OCSPStaple = staple
+			f.ocspExpiry = expiry
+		}
+	}
+
+	if previous := f.cert.Swap(&cert); previous != nil {
+		slog.Info("TLS certificate reloaded", "subject", cert.Leaf.Subject.String(), "not_after", cert.Leaf.NotAfter, "ocsp_stapled", cert.OCSPStaple != nil)
+	}
+	f.stamp = stamp
+	return nil
+}
+
+// fileStamp identifies the current version of the files by size and
+// modification time. Stat follows symlinks, so secrets mounted through a
+// swapped symlink (as in Kubernetes) are picked up too.
+func (f *fileCertificate) fileStamp() (string, error) {
+	var stamp string
+	for _, name := range []string{f.certFile, f.keyFile, f.ocspFile} {
+		if name == "" {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return "", err
+		}
+		stamp += fmt.Sprintf("%d:%d;", info.Size(), info.ModTime().UnixNano())
+	}
+	return stamp, nil
+}
+
+// loadOCSPStaple reads a DER OCSP response and checks that it is a current
+// "good" response for the certificate. It returns the response and the
+// time it stops being valid.
+func loadOCSPStaple(name string, cert tls.Certificate) ([]byte, time.Time, error) {
+	der, err := os.ReadFile(name)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var issuer *x509.Certificate
+	if len(cert.Certificate) > 1 {
+		if issuer, err = x509.ParseCertificate(cert.Certificate[1]); err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+	resp, err := ocsp.ParseResponseForCert(der, cert.Leaf, issuer)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	switch {
+	case resp.SerialNumber.Cmp(cert.Leaf.SerialNumber) != 0:
+		return nil, time.Time{}, errors.New("response is for a different certificate")
+	case resp.Status != ocsp.Good:
+		return nil, time.Time{}, fmt.Errorf("certificate status is not good (%d)", resp.Status)
+	case resp.NextUpdate.IsZero() || time.Now().After(resp.NextUpdate):
+		return nil, time.Time{}, errors.New("response has expired")
+	}
+	return der, resp.NextUpdate, nil
+}
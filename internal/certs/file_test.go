@@ -0,0 +1,258 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/claykom/website/internal/config"
+	"golang.org/x/crypto/ocsp"
+)
+
+// testChain is a leaf certificate issued by a test CA, written to disk
+type testChain struct {
+	certFile, keyFile string
+	leaf, ca          *x509.Certificate
+	caKey             crypto.Signer
+}
+
+func writeTestChain(t *testing.T, dir string) testChain {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(leafDER)
+	keyDER, _ := x509.MarshalECPrivateKey(leafKey)
+
+	chain := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})
+	chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})...)
+	c := testChain{
+		certFile: filepath.Join(dir, "chain.pem"),
+		keyFile:  filepath.Join(dir, "chain.key"),
+		leaf:     leaf,
+		ca:       ca,
+		caKey:    caKey,
+	}
+	os.WriteFile(c.certFile, chain, 0600)
+	os.WriteFile(c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return c
+}
+
+// writeOCSPResponse writes an OCSP response from the chain's CA
+func (c testChain) writeOCSPResponse(t *testing.T, name string, serial *big.Int, status int, nextUpdate time.Time) {
+	t.Helper()
+	der, err := ocsp.CreateResponse(c.ca, c.ca, ocsp.Response{
+		SerialNumber: serial,
+		Status:       status,
+		ThisUpdate:   time.Now().Add(-time.Hour),
+		NextUpdate:   nextUpdate,
+	}, c.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, der, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// touch moves a file's modification time forward so a rewrite within the
+// same clock tick is still seen as a change
+func touch(t *testing.T, name string) {
+	t.Helper()
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(name, future, future); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, time.Now().Add(time.Hour), "old.example.com")
+	m, err := New(config.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	writeTestCertificate(t, dir, time.Now().Add(time.Hour), "new.example.com")
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	cert, _ := m.GetCertificate(&tls.ClientHelloInfo{})
+	if name := leafName(t, cert); name != "new.example.com" {
+		t.Errorf("Expected the reloaded certificate, got one for %s", name)
+	}
+
+	// A broken rewrite keeps the previous certificate
+	os.WriteFile(certFile, []byte("partial"), 0600)
+	if err := m.Reload(); err == nil {
+		t.Error("Expected an error reloading a broken certificate")
+	}
+	cert, _ = m.GetCertificate(&tls.ClientHelloInfo{})
+	if name := leafName(t, cert); name != "new.example.com" {
+		t.Errorf("Expected the previous certificate to be kept, got one for %s", name)
+	}
+}
+
+func TestFileCertificateReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, time.Now().Add(time.Hour), "old.example.com")
+	m, err := New(config.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	writeTestCertificate(t, dir, time.Now().Add(time.Hour), "new.example.com")
+	touch(t, certFile)
+
+	// Within the check interval the files aren't looked at
+	cert, _ := m.GetCertificate(&tls.ClientHelloInfo{})
+	if name := leafName(t, cert); name != "old.example.com" {
+		t.Errorf("Expected no check within the interval, got a certificate for %s", name)
+	}
+
+	m.files.lastCheck.Store(0)
+	cert, _ = m.GetCertificate(&tls.ClientHelloInfo{})
+	if name := leafName(t, cert); name != "new.example.com" {
+		t.Errorf("Expected the changed files to be reloaded, got a certificate for %s", name)
+	}
+}
+
+func TestOCSPStaple(t *testing.T) {
+	tests := []struct {
+		name          string
+		serial        int64
+		status        int
+		nextUpdate    time.Duration
+		expectStapled bool
+	}{
+		{"good", 42, ocsp.Good, time.Hour, true},
+		{"revoked", 42, ocsp.Revoked, time.Hour, false},
+		{"expired", 42, ocsp.Good, -time.Minute, false},
+		{"other certificate", 7, ocsp.Good, time.Hour, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			chain := writeTestChain(t, dir)
+			ocspFile := filepath.Join(dir, "ocsp.der")
+			chain.writeOCSPResponse(t, ocspFile, big.NewInt(tt.serial), tt.status, time.Now().Add(tt.nextUpdate))
+
+			m, err := New(config.TLSConfig{CertFile: chain.certFile, KeyFile: chain.keyFile, OCSPStapleFile: ocspFile})
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+
+			cert, _ := m.GetCertificate(&tls.ClientHelloInfo{})
+			if stapled := cert.OCSPStaple != nil; stapled != tt.expectStapled {
+				t.Errorf("Expected stapled %v, got %v", tt.expectStapled, stapled)
+			}
+		})
+	}
+}
+
+func TestOCSPStapleRefresh(t *testing.T) {
+	dir := t.TempDir()
+	chain := writeTestChain(t, dir)
+	ocspFile := filepath.Join(dir, "ocsp.der")
+	chain.writeOCSPResponse(t, ocspFile, chain.leaf.SerialNumber, ocsp.Good, time.Now().Add(time.Hour))
+
+	m, err := New(config.TLSConfig{CertFile: chain.certFile, KeyFile: chain.keyFile, OCSPStapleFile: ocspFile})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if m.files.stale(time.Now()) {
+		t.Error("Expected unchanged files with a current staple not to be stale")
+	}
+	if !m.files.stale(time.Now().Add(2 * time.Hour)) {
+		t.Error("Expected the staple's expiry to trigger a reload")
+	}
+
+	// A replaced response that has expired is not stapled
+	m.files.lastCheck.Store(0)
+	chain.writeOCSPResponse(t, ocspFile, chain.leaf.SerialNumber, ocsp.Good, time.Now().Add(-time.Minute))
+	touch(t, ocspFile)
+	cert, _ := m.GetCertificate(&tls.ClientHelloInfo{})
+	if cert.OCSPStaple != nil {
+		t.Error("Expected the expired staple to be dropped")
+	}
+
+	// A fresh response is stapled again
+	m.files.lastCheck.Store(0)
+	chain.writeOCSPResponse(t, ocspFile, chain.leaf.SerialNumber, ocsp.Good, time.Now().Add(time.Hour))
+	os.Chtimes(ocspFile, time.Now().Add(2*time.Minute), time.Now().Add(2*time.Minute))
+	cert, _ = m.GetCertificate(&tls.ClientHelloInfo{})
+	if cert.OCSPStaple == nil {
+		t.Error("Expected the refreshed response to be stapled")
+	}
+}
+
+func TestTLSConfigSettings(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir(), time.Now().Add(time.Hour), "example.com")
+	m, err := New(config.TLSConfig{
+		CertFile:         certFile,
+		KeyFile:          keyFile,
+		MinVersion:       tls.VersionTLS13,
+		CurvePreferences: []tls.CurveID{tls.X25519},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	cfg := m.TLSConfig()
+	if cfg.MinVersion != tls.VersionTLS13 {
+		t.Errorf("Expected minimum version TLS 1.3, got %x", cfg.MinVersion)
+	}
+	if !slices.Equal(cfg.CurvePreferences, []tls.CurveID{tls.X25519}) {
+		t.Errorf("Expected curve preferences [X25519], got %v", cfg.CurvePreferences)
+	}
+
+	m, _ = New(config.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	if cfg := m.TLSConfig(); cfg.MinVersion != tls.VersionTLS12 || cfg.CipherSuites != nil {
+		t.Errorf("Expected TLS 1.2 minimum and default cipher suites, got %x and %v", cfg.MinVersion, cfg.CipherSuites)
+	}
+}
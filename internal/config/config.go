@@ -1,96 +1,490 @@
 package config
 
 import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config holds the application configuration
 type Config struct {
-	Server ServerConfig
-	TLS    TLSConfig
-	App    AppConfig
+	Server      ServerConfig
+	TLS         TLSConfig
+	App         AppConfig
+	Metrics     MetricsConfig
+	Tracing     TracingConfig
+	Compression CompressionConfig
+	Security    SecurityConfig
+	API         APIConfig
+	Health      HealthConfig
+	Site        SiteConfig
+
+	// values holds each setting as it was given, by environment variable
+	// name, for comparing configurations on reload
+	values map[string]string
 }
 
-// ServerConfig holds server-specific configuration
+// ServerConfig holds server-specific configuration. CanonicalHost, when
+// set, is the one host name pages are served under; requests for other
+// names are redirected to it. TrailingSlash is "strip", "add" or "" to
+// leave paths alone.
 type ServerConfig struct {
-	Host         string
-	Port         int
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	Host          string
+	Port          int
+	ReadTimeout   time.Duration
+	WriteTimeout  time.Duration
+	IdleTimeout   time.Duration
+	CanonicalHost string
+	TrailingSlash string
 }
 
-// TLSConfig holds TLS/HTTPS configuration
+// TLSConfig holds TLS/HTTPS configuration. RedirectAddr starts a second,
+// plain HTTP listener that redirects to HTTPS on PublicPort, the port
+// clients reach the HTTPS listener on.
+//
+// Certificates come from CertFile/KeyFile, from an ACME CA for ACMEDomains,
+// or from ACME with the files as a fallback while ACME is unavailable.
+// OCSPStapleFile is a DER OCSP response for the file certificate.
+// CipherSuites and CurvePreferences are nil for Go's defaults; cipher
+// suites only apply to TLS 1.2.
 type TLSConfig struct {
-	Enabled  bool
-	CertFile string
-	KeyFile  string
+	Enabled          bool
+	CertFile         string
+	KeyFile          string
+	OCSPStapleFile   string
+	RedirectAddr     string
+	PublicPort       int
+	MinVersion       uint16
+	CipherSuites     []uint16
+	CurvePreferences []tls.CurveID
+	ACME             ACMEConfig
+}
+
+// ACMEConfig configures automatic certificates. DirectoryURL defaults to
+// Let's Encrypt; CARootFile adds a PEM root for ACME servers with a private
+// CA, such as a local Pebble. Certificates are renewed RenewBefore expiry.
+type ACMEConfig struct {
+	Domains      []string
+	Email        string
+	CacheDir     string
+	DirectoryURL string
+	CARootFile   string
+	RenewBefore  time.Duration
 }
 
-// AppConfig holds application-specific configuration
+// AppConfig holds application-specific configuration. PreferDisk serves
+// static/ and content/ from the working directory even when the binary was
+// built with them embedded, so edits show up without a rebuild.
+// PageCacheSize bounds the number of rendered pages kept in memory; 0
+// turns the page cache off.
 type AppConfig struct {
-	Environment string
-	LogLevel    string
+	Environment   string
+	LogLevel      string
+	PreferDisk    bool
+	PageCacheSize int
+}
+
+// MetricsConfig holds configuration for the Prometheus /metrics endpoint.
+// Metrics are served on a separate admin listener when Addr is set, and on
+// the main listener behind a bearer token when Token is set.
+type MetricsConfig struct {
+	Addr  string
+	Token Secret
+}
+
+// Enabled reports whether /metrics is exposed anywhere
+func (m MetricsConfig) Enabled() bool {
+	return m.Addr != "" || m.Token.IsSet()
+}
+
+// TracingConfig holds distributed tracing configuration. Exporter is one
+// of "none", "otlp", "stdout" or "file".
+type TracingConfig struct {
+	Exporter    string
+	Endpoint    string
+	File        string
+	ServiceName string
+}
+
+// CompressionConfig holds response compression configuration
+type CompressionConfig struct {
+	Enabled bool
+	MinSize int
 }
 
-// Load loads configuration from environment variables with sensible defaults
+// SecurityConfig holds security header configuration. CSPReportURI is where
+// browsers send Content-Security-Policy violation reports; empty disables
+// reporting. CSPReportOnly sends the policy as
+// Content-Security-Policy-Report-Only so a new policy can be rolled out
+// without blocking anything.
+//
+// HeaderProfile names a set of security headers: "strict", "default" or
+// "development". RouteHeaders overrides individual headers below a path
+// prefix, e.g. {"/embed/": {"X-Frame-Options": "SAMEORIGIN"}}; an empty
+// value removes the header.
+//
+// Each client IP may make RateLimitRequests requests per RateLimitWindow;
+// zero requests disables rate limiting.
+type SecurityConfig struct {
+	CSPReportURI      string
+	CSPReportOnly     bool
+	HeaderProfile     string
+	RouteHeaders      map[string]map[string]string
+	RateLimitRequests int
+	RateLimitWindow   time.Duration
+}
+
+// APIConfig holds configuration for the JSON API under /api. CORS is
+// enabled for the listed origins, which may be exact ("https://app.example.com"),
+// wildcard subdomains ("https://*.example.com") or "*".
+type APIConfig struct {
+	Enabled              bool
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+}
+
+// HealthConfig holds configuration for the readiness checks. Each check
+// gets CheckTimeout. Readiness fails when a certificate expires within
+// CertMinValidity or DiskPath has less than DiskMinFreeMB megabytes free
+// (0 disables the disk check). DrainDelay keeps serving for a while after
+// readiness starts failing on shutdown, so load balancers notice first.
+type HealthConfig struct {
+	CheckTimeout    time.Duration
+	CertMinValidity time.Duration
+	DiskPath        string
+	DiskMinFreeMB   int
+	DrainDelay      time.Duration
+}
+
+// SiteConfig describes the public site. BaseURL is the scheme, host and
+// optional path prefix pages are reached under, without a trailing slash,
+// for absolute links such as those in the sitemap; when empty they are
+// built from the request. RobotsDisallow lists the path prefixes
+// robots.txt asks crawlers to stay out of.
+type SiteConfig struct {
+	BaseURL        string
+	RobotsDisallow []string
+}
+
+// Load loads configuration from the file named by CONFIG_FILE, .env and
+// environment variables, with sensible defaults
 func Load() (*Config, error) {
-	port, err := parsePort(getEnv("PORT", "8080"))
+	return LoadFrom(Options{File: os.Getenv("CONFIG_FILE"), DotEnv: ".env"})
+}
+
+// LoadFrom loads configuration from the sources in opts. Every invalid
+// setting is reported, not just the first.
+func LoadFrom(opts Options) (*Config, error) {
+	s, errs := newSource(opts)
+
+	port, err := parsePort(s.get("PORT", "8080"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("PORT"), err))
+	}
+
+	readTimeout, err := parseDuration(s.get("READ_TIMEOUT", "15s"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("READ_TIMEOUT"), err))
+	}
+
+	writeTimeout, err := parseDuration(s.get("WRITE_TIMEOUT", "15s"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("WRITE_TIMEOUT"), err))
+	}
+
+	idleTimeout, err := parseDuration(s.get("IDLE_TIMEOUT", "60s"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("IDLE_TIMEOUT"), err))
+	}
+
+	tracingExporter := s.get("TRACING_EXPORTER", "none")
+	switch tracingExporter {
+	case "none", "otlp", "stdout":
+	case "file":
+		if s.get("TRACING_FILE", "") == "" {
+			errs = append(errs, fmt.Errorf("%s is required when %s is file", s.name("TRACING_FILE"), s.name("TRACING_EXPORTER")))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid %s: %q", s.name("TRACING_EXPORTER"), tracingExporter))
+	}
+
+	compressionEnabled, err := parseBool(s.get("COMPRESSION_ENABLED", "true"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("COMPRESSION_ENABLED"), err))
+	}
+
+	compressionMinSize, err := parseSize(s.get("COMPRESSION_MIN_SIZE", "1024"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("COMPRESSION_MIN_SIZE"), err))
+	}
+
+	preferDisk, err := parseBool(s.get("PREFER_DISK", "false"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("PREFER_DISK"), err))
+	}
+
+	pageCacheSize, err := parseSize(s.get("PAGE_CACHE_SIZE", "256"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("PAGE_CACHE_SIZE"), err))
+	}
+
+	cspReportOnly, err := parseBool(s.get("CSP_REPORT_ONLY", "false"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("CSP_REPORT_ONLY"), err))
+	}
+
+	// "none" turns violation reporting off
+	cspReportURI := s.get("CSP_REPORT_URI", "/csp-report")
+	if cspReportURI == "none" {
+		cspReportURI = ""
+	}
+
+	headerProfile := s.get("SECURITY_PROFILE", "default")
+	switch headerProfile {
+	case "strict", "default", "development":
+	default:
+		errs = append(errs, fmt.Errorf("invalid %s: %q", s.name("SECURITY_PROFILE"), headerProfile))
+	}
+
+	routeHeaders, err := parseRouteHeaders(s.get("SECURITY_ROUTE_HEADERS", ""))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("SECURITY_ROUTE_HEADERS"), err))
+	}
+
+	rateLimitRequests, err := parseSize(s.get("RATE_LIMIT_REQUESTS", "100"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("RATE_LIMIT_REQUESTS"), err))
+	}
+
+	rateLimitWindow, err := parseDuration(s.get("RATE_LIMIT_WINDOW", "1m"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("RATE_LIMIT_WINDOW"), err))
+	} else if rateLimitRequests > 0 && rateLimitWindow/time.Duration(rateLimitRequests) == 0 {
+		errs = append(errs, fmt.Errorf("invalid %s: too short for %d requests", s.name("RATE_LIMIT_WINDOW"), rateLimitRequests))
+	}
+
+	apiEnabled, err := parseBool(s.get("API_ENABLED", "false"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("API_ENABLED"), err))
+	}
+
+	corsOrigins, err := parseOrigins(s.get("CORS_ALLOWED_ORIGINS", ""))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("CORS_ALLOWED_ORIGINS"), err))
+	}
+
+	corsCredentials, err := parseBool(s.get("CORS_ALLOW_CREDENTIALS", "false"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("CORS_ALLOW_CREDENTIALS"), err))
+	}
+	if corsCredentials && slices.Contains(corsOrigins, "*") {
+		errs = append(errs, fmt.Errorf("CORS_ALLOW_CREDENTIALS cannot be used with CORS_ALLOWED_ORIGINS=*"))
+	}
+
+	corsMaxAge, err := parseDuration(s.get("CORS_MAX_AGE", "10m"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("CORS_MAX_AGE"), err))
+	}
+
+	canonicalHost := strings.ToLower(s.get("CANONICAL_HOST", ""))
+	if canonicalHost != "" && !validHostname(canonicalHost) {
+		errs = append(errs, fmt.Errorf("invalid %s: %q is not a host name", s.name("CANONICAL_HOST"), canonicalHost))
+	}
+
+	trailingSlash := s.get("TRAILING_SLASH", "")
+	switch trailingSlash {
+	case "", "strip", "add":
+	default:
+		errs = append(errs, fmt.Errorf("invalid %s: %q (want strip or add)", s.name("TRAILING_SLASH"), trailingSlash))
+	}
+
+	healthCheckTimeout, err := parseDuration(s.get("HEALTH_CHECK_TIMEOUT", "2s"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("HEALTH_CHECK_TIMEOUT"), err))
+	}
+
+	certMinValidity, err := parseDuration(s.get("HEALTH_CERT_MIN_VALIDITY", "168h"))
 	if err != nil {
-		return nil, fmt.Errorf("invalid PORT: %w", err)
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("HEALTH_CERT_MIN_VALIDITY"), err))
 	}
 
-	readTimeout, err := parseDuration(getEnv("READ_TIMEOUT", "15s"))
+	diskMinFree, err := parseSize(s.get("HEALTH_DISK_MIN_FREE_MB", "100"))
 	if err != nil {
-		return nil, fmt.Errorf("invalid READ_TIMEOUT: %w", err)
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("HEALTH_DISK_MIN_FREE_MB"), err))
 	}
 
-	writeTimeout, err := parseDuration(getEnv("WRITE_TIMEOUT", "15s"))
+	drainDelay, err := parseDuration(s.get("SHUTDOWN_DRAIN_DELAY", "0s"))
 	if err != nil {
-		return nil, fmt.Errorf("invalid WRITE_TIMEOUT: %w", err)
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("SHUTDOWN_DRAIN_DELAY"), err))
 	}
 
-	idleTimeout, err := parseDuration(getEnv("IDLE_TIMEOUT", "60s"))
+	baseURL, err := parseBaseURL(s.get("BASE_URL", ""))
 	if err != nil {
-		return nil, fmt.Errorf("invalid IDLE_TIMEOUT: %w", err)
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("BASE_URL"), err))
+	}
+
+	robotsDisallow := parseList(s.get("ROBOTS_DISALLOW", "/admin/,/preview/,/api/"))
+	for _, prefix := range robotsDisallow {
+		if !strings.HasPrefix(prefix, "/") {
+			errs = append(errs, fmt.Errorf("invalid %s: %q must start with /", s.name("ROBOTS_DISALLOW"), prefix))
+		}
 	}
 
 	// TLS configuration
-	tlsCertFile := getEnv("TLS_CERT_FILE", "")
-	tlsKeyFile := getEnv("TLS_KEY_FILE", "")
-	tlsEnabled := tlsCertFile != "" && tlsKeyFile != ""
+	tlsCertFile := s.get("TLS_CERT_FILE", "")
+	tlsKeyFile := s.get("TLS_KEY_FILE", "")
+
+	acmeDomains := parseList(strings.ToLower(s.get("ACME_DOMAINS", "")))
+	for _, domain := range acmeDomains {
+		if !validHostname(domain) {
+			errs = append(errs, fmt.Errorf("invalid %s: %q is not a host name", s.name("ACME_DOMAINS"), domain))
+		}
+	}
+
+	acmeDirectoryURL := s.get("ACME_DIRECTORY_URL", "")
+	if acmeDirectoryURL != "" {
+		u, err := url.Parse(acmeDirectoryURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid %s: must be an https URL", s.name("ACME_DIRECTORY_URL")))
+		}
+	}
+
+	acmeRenewBefore, err := parseDuration(s.get("ACME_RENEW_BEFORE", "720h"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("ACME_RENEW_BEFORE"), err))
+	}
+
+	tlsEnabled := (tlsCertFile != "" && tlsKeyFile != "") || len(acmeDomains) > 0
+
+	ocspStapleFile := s.get("TLS_OCSP_STAPLE_FILE", "")
+	if ocspStapleFile != "" && (tlsCertFile == "" || tlsKeyFile == "") {
+		errs = append(errs, fmt.Errorf("TLS_OCSP_STAPLE_FILE requires TLS_CERT_FILE and TLS_KEY_FILE"))
+	}
+
+	tlsMinVersion, err := parseTLSVersion(s.get("TLS_MIN_VERSION", "1.2"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("TLS_MIN_VERSION"), err))
+	}
+
+	cipherSuites, err := parseCipherSuites(s.get("TLS_CIPHER_SUITES", ""))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("TLS_CIPHER_SUITES"), err))
+	}
+	if len(cipherSuites) > 0 && tlsMinVersion == tls.VersionTLS13 {
+		errs = append(errs, fmt.Errorf("TLS_CIPHER_SUITES has no effect with TLS_MIN_VERSION=1.3"))
+	}
+
+	curves, err := parseCurves(s.get("TLS_CURVES", ""))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("TLS_CURVES"), err))
+	}
+
+	redirectAddr := s.get("HTTP_REDIRECT_ADDR", "")
+	if redirectAddr != "" && !tlsEnabled {
+		errs = append(errs, fmt.Errorf("HTTP_REDIRECT_ADDR requires TLS_CERT_FILE and TLS_KEY_FILE or ACME_DOMAINS"))
+	}
+
+	publicPort := port
+	if v := s.get("HTTPS_PUBLIC_PORT", ""); v != "" {
+		if publicPort, err = parsePort(v); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", s.name("HTTPS_PUBLIC_PORT"), err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
 
 	return &Config{
 		Server: ServerConfig{
-			Host:         getEnv("HOST", "0.0.0.0"),
-			Port:         port,
-			ReadTimeout:  readTimeout,
-			WriteTimeout: writeTimeout,
-			IdleTimeout:  idleTimeout,
+			Host:          s.get("HOST", "0.0.0.0"),
+			Port:          port,
+			ReadTimeout:   readTimeout,
+			WriteTimeout:  writeTimeout,
+			IdleTimeout:   idleTimeout,
+			CanonicalHost: canonicalHost,
+			TrailingSlash: trailingSlash,
 		},
 		TLS: TLSConfig{
-			Enabled:  tlsEnabled,
-			CertFile: tlsCertFile,
-			KeyFile:  tlsKeyFile,
+			Enabled:          tlsEnabled,
+			CertFile:         tlsCertFile,
+			KeyFile:          tlsKeyFile,
+			OCSPStapleFile:   ocspStapleFile,
+			RedirectAddr:     redirectAddr,
+			PublicPort:       publicPort,
+			MinVersion:       tlsMinVersion,
+			CipherSuites:     cipherSuites,
+			CurvePreferences: curves,
+			ACME: ACMEConfig{
+				Domains:      acmeDomains,
+				Email:        s.get("ACME_EMAIL", ""),
+				CacheDir:     s.get("ACME_CACHE_DIR", "acme-cache"),
+				DirectoryURL: acmeDirectoryURL,
+				CARootFile:   s.get("ACME_CA_ROOT", ""),
+				RenewBefore:  acmeRenewBefore,
+			},
 		},
 		App: AppConfig{
-			Environment: getEnv("ENV", "development"),
-			LogLevel:    getEnv("LOG_LEVEL", "info"),
+			Environment:   s.get("ENV", "development"),
+			LogLevel:      s.get("LOG_LEVEL", "info"),
+			PreferDisk:    preferDisk,
+			PageCacheSize: pageCacheSize,
+		},
+		Metrics: MetricsConfig{
+			Addr:  s.get("METRICS_ADDR", ""),
+			Token: NewSecret(s.get("METRICS_TOKEN", "")),
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracingExporter,
+			Endpoint:    s.get("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+			File:        s.get("TRACING_FILE", ""),
+			ServiceName: s.get("OTEL_SERVICE_NAME", "website"),
+		},
+		Compression: CompressionConfig{
+			Enabled: compressionEnabled,
+			MinSize: compressionMinSize,
+		},
+		Security: SecurityConfig{
+			CSPReportURI:      cspReportURI,
+			CSPReportOnly:     cspReportOnly,
+			HeaderProfile:     headerProfile,
+			RouteHeaders:      routeHeaders,
+			RateLimitRequests: rateLimitRequests,
+			RateLimitWindow:   rateLimitWindow,
 		},
+		API: APIConfig{
+			Enabled:              apiEnabled,
+			CORSAllowedOrigins:   corsOrigins,
+			CORSAllowedMethods:   parseList(s.get("CORS_ALLOWED_METHODS", "GET,HEAD")),
+			CORSAllowedHeaders:   parseList(s.get("CORS_ALLOWED_HEADERS", "Content-Type")),
+			CORSAllowCredentials: corsCredentials,
+			CORSMaxAge:           corsMaxAge,
+		},
+		Health: HealthConfig{
+			CheckTimeout:    healthCheckTimeout,
+			CertMinValidity: certMinValidity,
+			DiskPath:        s.get("HEALTH_DISK_PATH", "."),
+			DiskMinFreeMB:   diskMinFree,
+			DrainDelay:      drainDelay,
+		},
+		Site: SiteConfig{
+			BaseURL:        baseURL,
+			RobotsDisallow: robotsDisallow,
+		},
+		values: s.values,
 	}, nil
 }
 
-// getEnv retrieves an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
 // parsePort parses a port string into an integer
 func parsePort(portStr string) (int, error) {
 	port, err := strconv.Atoi(portStr)
@@ -114,3 +508,178 @@ func parseDuration(durationStr string) (time.Duration, error) {
 	}
 	return duration, nil
 }
+
+// parseBool parses a boolean string such as "true", "false", "1" or "0"
+func parseBool(boolStr string) (bool, error) {
+	return strconv.ParseBool(boolStr)
+}
+
+// parseSize parses a non-negative byte count
+func parseSize(sizeStr string) (int, error) {
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil {
+		return 0, err
+	}
+	if size < 0 {
+		return 0, fmt.Errorf("size must not be negative")
+	}
+	return size, nil
+}
+
+// parseRouteHeaders parses a JSON object mapping path prefixes to header
+// overrides
+func parseRouteHeaders(jsonStr string) (map[string]map[string]string, error) {
+	if jsonStr == "" {
+		return nil, nil
+	}
+
+	var routes map[string]map[string]string
+	if err := json.Unmarshal([]byte(jsonStr), &routes); err != nil {
+		return nil, err
+	}
+	for prefix, headers := range routes {
+		if !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("path prefix %q must start with /", prefix)
+		}
+		for name := range headers {
+			if !validHeaderName(name) {
+				return nil, fmt.Errorf("invalid header name %q", name)
+			}
+		}
+	}
+	return routes, nil
+}
+
+// parseList splits a comma-separated list, dropping empty items
+func parseList(listStr string) []string {
+	var items []string
+	for _, item := range strings.Split(listStr, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseOrigins parses a comma-separated list of CORS origins. Each is "*",
+// "scheme://host[:port]" or "scheme://*.domain[:port]".
+func parseOrigins(originsStr string) ([]string, error) {
+	origins := parseList(originsStr)
+	for _, origin := range origins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme == "" || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.User != nil {
+			return nil, fmt.Errorf("origin %q must be scheme://host[:port]", origin)
+		}
+		if host := u.Hostname(); strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+			return nil, fmt.Errorf("origin %q may only use a wildcard as the first label", origin)
+		}
+	}
+	return origins, nil
+}
+
+// parseBaseURL parses the site's public URL, scheme://host[:port][/path],
+// and drops any trailing slash
+func parseBaseURL(baseURL string) (string, error) {
+	if baseURL == "" {
+		return "", nil
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("%q must be http(s)://host[:port][/path]", baseURL)
+	}
+	return strings.TrimRight(baseURL, "/"), nil
+}
+
+// parseTLSVersion parses a minimum TLS version, "1.2" or "1.3"
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("%q (want 1.2 or 1.3)", version)
+	}
+}
+
+// parseCipherSuites parses a comma-separated list of TLS 1.2 cipher suite
+// names such as TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256. Suites Go
+// considers insecure are rejected.
+func parseCipherSuites(suitesStr string) ([]uint16, error) {
+	var ids []uint16
+	for _, name := range parseList(suitesStr) {
+		i := slices.IndexFunc(tls.CipherSuites(), func(s *tls.CipherSuite) bool { return s.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("%q is not a supported cipher suite", name)
+		}
+		suite := tls.CipherSuites()[i]
+		if !slices.Contains(suite.SupportedVersions, tls.VersionTLS12) {
+			return nil, fmt.Errorf("%q is a TLS 1.3 suite, which can't be configured", name)
+		}
+		ids = append(ids, suite.ID)
+	}
+	return ids, nil
+}
+
+// tlsCurves maps TLS_CURVES names to curve IDs
+var tlsCurves = map[string]tls.CurveID{
+	"x25519mlkem768": tls.X25519MLKEM768,
+	"x25519":         tls.X25519,
+	"p-256":          tls.CurveP256,
+	"p-384":          tls.CurveP384,
+	"p-521":          tls.CurveP521,
+}
+
+// parseCurves parses a comma-separated list of key exchange curves in
+// order of preference, e.g. "X25519MLKEM768, X25519, P-256"
+func parseCurves(curvesStr string) ([]tls.CurveID, error) {
+	var ids []tls.CurveID
+	for _, name := range parseList(curvesStr) {
+		id, ok := tlsCurves[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("%q is not a supported curve", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// validHostname reports whether host is a DNS name such as example.com
+func validHostname(host string) bool {
+	if len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validHeaderName reports whether name is a non-empty HTTP token
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c > 0x7e || c <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
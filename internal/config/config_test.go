@@ -1,7 +1,10 @@
 package config
 
 import (
+	"crypto/tls"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -9,7 +12,7 @@ import (
 func TestLoad(t *testing.T) {
 	// Save original environment variables
 	originalEnv := make(map[string]string)
-	envVars := []string{"PORT", "HOST", "READ_TIMEOUT", "WRITE_TIMEOUT", "IDLE_TIMEOUT", "TLS_CERT_FILE", "TLS_KEY_FILE", "ENV", "LOG_LEVEL"}
+	envVars := []string{"PORT", "HOST", "READ_TIMEOUT", "WRITE_TIMEOUT", "IDLE_TIMEOUT", "TLS_CERT_FILE", "TLS_KEY_FILE", "ENV", "LOG_LEVEL", "METRICS_ADDR", "METRICS_TOKEN", "TRACING_EXPORTER", "TRACING_FILE", "COMPRESSION_ENABLED", "COMPRESSION_MIN_SIZE", "PREFER_DISK", "CSP_REPORT_URI", "CSP_REPORT_ONLY", "SECURITY_PROFILE", "SECURITY_ROUTE_HEADERS", "API_ENABLED", "CORS_ALLOWED_ORIGINS", "CORS_ALLOWED_METHODS", "CORS_ALLOWED_HEADERS", "CORS_ALLOW_CREDENTIALS", "CORS_MAX_AGE", "PAGE_CACHE_SIZE", "CANONICAL_HOST", "TRAILING_SLASH", "HTTP_REDIRECT_ADDR", "HTTPS_PUBLIC_PORT", "ACME_DOMAINS", "ACME_EMAIL", "ACME_CACHE_DIR", "ACME_DIRECTORY_URL", "ACME_CA_ROOT", "ACME_RENEW_BEFORE", "TLS_OCSP_STAPLE_FILE", "TLS_MIN_VERSION", "TLS_CIPHER_SUITES", "TLS_CURVES", "HEALTH_CHECK_TIMEOUT", "HEALTH_CERT_MIN_VALIDITY", "HEALTH_DISK_PATH", "HEALTH_DISK_MIN_FREE_MB", "SHUTDOWN_DRAIN_DELAY", "CONFIG_FILE", "RATE_LIMIT_REQUESTS", "RATE_LIMIT_WINDOW", "METRICS_TOKEN_FILE", "BASE_URL", "ROBOTS_DISALLOW"}
 
 	for _, env := range envVars {
 		if val := os.Getenv(env); val != "" {
@@ -54,6 +57,9 @@ func TestLoad(t *testing.T) {
 				if cfg.App.Environment != "development" {
 					t.Errorf("Expected default environment to be development, got %s", cfg.App.Environment)
 				}
+				if cfg.App.PageCacheSize != 256 {
+					t.Errorf("Expected default page cache size to be 256, got %d", cfg.App.PageCacheSize)
+				}
 			},
 		},
 		{
@@ -105,6 +111,590 @@ func TestLoad(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "metrics configuration",
+			envVars: map[string]string{
+				"METRICS_ADDR":  "127.0.0.1:9090",
+				"METRICS_TOKEN": "scrape-token",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if !cfg.Metrics.Enabled() {
+					t.Error("Expected metrics to be enabled")
+				}
+				if cfg.Metrics.Addr != "127.0.0.1:9090" {
+					t.Errorf("Expected metrics addr to be 127.0.0.1:9090, got %s", cfg.Metrics.Addr)
+				}
+				if cfg.Metrics.Token.Value() != "scrape-token" {
+					t.Errorf("Expected metrics token to be scrape-token, got %s", cfg.Metrics.Token.Value())
+				}
+			},
+		},
+		{
+			name:        "tracing defaults",
+			envVars:     map[string]string{},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Tracing.Exporter != "none" {
+					t.Errorf("Expected tracing exporter to default to none, got %s", cfg.Tracing.Exporter)
+				}
+				if cfg.Tracing.Endpoint != "http://localhost:4318" {
+					t.Errorf("Expected default OTLP endpoint, got %s", cfg.Tracing.Endpoint)
+				}
+			},
+		},
+		{
+			name: "invalid tracing exporter",
+			envVars: map[string]string{
+				"TRACING_EXPORTER": "zipkin",
+			},
+			expectError: true,
+		},
+		{
+			name: "file tracing exporter without file",
+			envVars: map[string]string{
+				"TRACING_EXPORTER": "file",
+			},
+			expectError: true,
+		},
+		{
+			name: "compression configuration",
+			envVars: map[string]string{
+				"COMPRESSION_ENABLED":  "false",
+				"COMPRESSION_MIN_SIZE": "512",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Compression.Enabled {
+					t.Error("Expected compression to be disabled")
+				}
+				if cfg.Compression.MinSize != 512 {
+					t.Errorf("Expected compression min size to be 512, got %d", cfg.Compression.MinSize)
+				}
+			},
+		},
+		{
+			name: "invalid compression flag",
+			envVars: map[string]string{
+				"COMPRESSION_ENABLED": "maybe",
+			},
+			expectError: true,
+		},
+		{
+			name: "negative compression min size",
+			envVars: map[string]string{
+				"COMPRESSION_MIN_SIZE": "-1",
+			},
+			expectError: true,
+		},
+		{
+			name: "prefer disk",
+			envVars: map[string]string{
+				"PREFER_DISK": "true",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if !cfg.App.PreferDisk {
+					t.Error("Expected PreferDisk to be enabled")
+				}
+			},
+		},
+		{
+			name: "page cache size",
+			envVars: map[string]string{
+				"PAGE_CACHE_SIZE": "0",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.App.PageCacheSize != 0 {
+					t.Errorf("Expected page cache to be disabled, got size %d", cfg.App.PageCacheSize)
+				}
+			},
+		},
+		{
+			name: "negative page cache size",
+			envVars: map[string]string{
+				"PAGE_CACHE_SIZE": "-1",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid prefer disk flag",
+			envVars: map[string]string{
+				"PREFER_DISK": "sometimes",
+			},
+			expectError: true,
+		},
+		{
+			name:        "csp reporting defaults",
+			envVars:     map[string]string{},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Security.CSPReportURI != "/csp-report" {
+					t.Errorf("Expected CSP report URI to default to /csp-report, got %s", cfg.Security.CSPReportURI)
+				}
+				if cfg.Security.CSPReportOnly {
+					t.Error("Expected CSP to be enforced by default")
+				}
+			},
+		},
+		{
+			name: "csp reporting configuration",
+			envVars: map[string]string{
+				"CSP_REPORT_URI":  "none",
+				"CSP_REPORT_ONLY": "true",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Security.CSPReportURI != "" {
+					t.Errorf("Expected CSP reporting to be disabled, got %s", cfg.Security.CSPReportURI)
+				}
+				if !cfg.Security.CSPReportOnly {
+					t.Error("Expected CSP report-only mode")
+				}
+			},
+		},
+		{
+			name: "invalid csp report-only flag",
+			envVars: map[string]string{
+				"CSP_REPORT_ONLY": "perhaps",
+			},
+			expectError: true,
+		},
+		{
+			name: "security header profile and route overrides",
+			envVars: map[string]string{
+				"SECURITY_PROFILE":       "strict",
+				"SECURITY_ROUTE_HEADERS": `{"/embed/": {"X-Frame-Options": "SAMEORIGIN"}}`,
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Security.HeaderProfile != "strict" {
+					t.Errorf("Expected strict profile, got %s", cfg.Security.HeaderProfile)
+				}
+				if cfg.Security.RouteHeaders["/embed/"]["X-Frame-Options"] != "SAMEORIGIN" {
+					t.Errorf("Expected /embed/ override, got %v", cfg.Security.RouteHeaders)
+				}
+			},
+		},
+		{
+			name: "unknown security profile",
+			envVars: map[string]string{
+				"SECURITY_PROFILE": "paranoid",
+			},
+			expectError: true,
+		},
+		{
+			name: "malformed route headers",
+			envVars: map[string]string{
+				"SECURITY_ROUTE_HEADERS": `{"/embed/": "SAMEORIGIN"}`,
+			},
+			expectError: true,
+		},
+		{
+			name: "route headers with relative prefix",
+			envVars: map[string]string{
+				"SECURITY_ROUTE_HEADERS": `{"embed": {"X-Frame-Options": "SAMEORIGIN"}}`,
+			},
+			expectError: true,
+		},
+		{
+			name: "route headers with invalid header name",
+			envVars: map[string]string{
+				"SECURITY_ROUTE_HEADERS": `{"/embed/": {"Bad Header": "x"}}`,
+			},
+			expectError: true,
+		},
+		{
+			name:        "api defaults",
+			envVars:     map[string]string{},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.API.Enabled {
+					t.Error("Expected API to be disabled by default")
+				}
+				if len(cfg.API.CORSAllowedOrigins) != 0 {
+					t.Errorf("Expected no CORS origins by default, got %v", cfg.API.CORSAllowedOrigins)
+				}
+				if cfg.API.CORSMaxAge != 10*time.Minute {
+					t.Errorf("Expected default CORS max age to be 10m, got %v", cfg.API.CORSMaxAge)
+				}
+			},
+		},
+		{
+			name: "api cors configuration",
+			envVars: map[string]string{
+				"API_ENABLED":            "true",
+				"CORS_ALLOWED_ORIGINS":   "https://app.example.com, https://*.example.org",
+				"CORS_ALLOWED_HEADERS":   "Content-Type,Authorization",
+				"CORS_ALLOW_CREDENTIALS": "true",
+				"CORS_MAX_AGE":           "1h",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if !cfg.API.Enabled {
+					t.Error("Expected API to be enabled")
+				}
+				if len(cfg.API.CORSAllowedOrigins) != 2 || cfg.API.CORSAllowedOrigins[1] != "https://*.example.org" {
+					t.Errorf("Expected two CORS origins, got %v", cfg.API.CORSAllowedOrigins)
+				}
+				if len(cfg.API.CORSAllowedHeaders) != 2 {
+					t.Errorf("Expected two CORS headers, got %v", cfg.API.CORSAllowedHeaders)
+				}
+				if !cfg.API.CORSAllowCredentials {
+					t.Error("Expected CORS credentials to be allowed")
+				}
+				if cfg.API.CORSMaxAge != time.Hour {
+					t.Errorf("Expected CORS max age to be 1h, got %v", cfg.API.CORSMaxAge)
+				}
+			},
+		},
+		{
+			name: "cors origin with path",
+			envVars: map[string]string{
+				"CORS_ALLOWED_ORIGINS": "https://app.example.com/api",
+			},
+			expectError: true,
+		},
+		{
+			name: "cors origin without scheme",
+			envVars: map[string]string{
+				"CORS_ALLOWED_ORIGINS": "app.example.com",
+			},
+			expectError: true,
+		},
+		{
+			name: "cors wildcard inside host",
+			envVars: map[string]string{
+				"CORS_ALLOWED_ORIGINS": "https://app.*.example.com",
+			},
+			expectError: true,
+		},
+		{
+			name: "cors credentials with any origin",
+			envVars: map[string]string{
+				"CORS_ALLOWED_ORIGINS":   "*",
+				"CORS_ALLOW_CREDENTIALS": "true",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid cors max age",
+			envVars: map[string]string{
+				"CORS_MAX_AGE": "forever",
+			},
+			expectError: true,
+		},
+		{
+			name: "redirect and canonical host configuration",
+			envVars: map[string]string{
+				"TLS_CERT_FILE":      "/path/to/cert.pem",
+				"TLS_KEY_FILE":       "/path/to/key.pem",
+				"PORT":               "8443",
+				"HTTP_REDIRECT_ADDR": ":8080",
+				"CANONICAL_HOST":     "Example.com",
+				"TRAILING_SLASH":     "strip",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.TLS.RedirectAddr != ":8080" {
+					t.Errorf("Expected redirect address :8080, got %s", cfg.TLS.RedirectAddr)
+				}
+				if cfg.TLS.PublicPort != 8443 {
+					t.Errorf("Expected public HTTPS port to default to PORT, got %d", cfg.TLS.PublicPort)
+				}
+				if cfg.Server.CanonicalHost != "example.com" {
+					t.Errorf("Expected lower-cased canonical host, got %s", cfg.Server.CanonicalHost)
+				}
+				if cfg.Server.TrailingSlash != "strip" {
+					t.Errorf("Expected trailing slash mode strip, got %s", cfg.Server.TrailingSlash)
+				}
+			},
+		},
+		{
+			name: "https public port",
+			envVars: map[string]string{
+				"HTTPS_PUBLIC_PORT": "443",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.TLS.PublicPort != 443 {
+					t.Errorf("Expected public HTTPS port 443, got %d", cfg.TLS.PublicPort)
+				}
+			},
+		},
+		{
+			name: "redirect listener without tls",
+			envVars: map[string]string{
+				"HTTP_REDIRECT_ADDR": ":80",
+			},
+			expectError: true,
+		},
+		{
+			name: "canonical host with scheme",
+			envVars: map[string]string{
+				"CANONICAL_HOST": "https://example.com",
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown trailing slash mode",
+			envVars: map[string]string{
+				"TRAILING_SLASH": "always",
+			},
+			expectError: true,
+		},
+		{
+			name: "acme configuration",
+			envVars: map[string]string{
+				"ACME_DOMAINS":       "Example.com, www.example.com",
+				"ACME_EMAIL":         "admin@example.com",
+				"ACME_DIRECTORY_URL": "https://acme-staging-v02.api.letsencrypt.org/directory",
+				"HTTP_REDIRECT_ADDR": ":80",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if !cfg.TLS.Enabled {
+					t.Error("Expected ACME domains to enable TLS")
+				}
+				if len(cfg.TLS.ACME.Domains) != 2 || cfg.TLS.ACME.Domains[0] != "example.com" {
+					t.Errorf("Expected lower-cased ACME domains, got %v", cfg.TLS.ACME.Domains)
+				}
+				if cfg.TLS.ACME.Email != "admin@example.com" {
+					t.Errorf("Expected ACME email admin@example.com, got %s", cfg.TLS.ACME.Email)
+				}
+				if cfg.TLS.ACME.CacheDir != "acme-cache" {
+					t.Errorf("Expected default ACME cache dir acme-cache, got %s", cfg.TLS.ACME.CacheDir)
+				}
+				if cfg.TLS.ACME.RenewBefore != 720*time.Hour {
+					t.Errorf("Expected default renewal window of 720h, got %v", cfg.TLS.ACME.RenewBefore)
+				}
+			},
+		},
+		{
+			name: "invalid acme domain",
+			envVars: map[string]string{
+				"ACME_DOMAINS": "example.com/blog",
+			},
+			expectError: true,
+		},
+		{
+			name: "acme directory over http",
+			envVars: map[string]string{
+				"ACME_DOMAINS":       "example.com",
+				"ACME_DIRECTORY_URL": "http://localhost:14000/dir",
+			},
+			expectError: true,
+		},
+		{
+			name: "tls protocol configuration",
+			envVars: map[string]string{
+				"TLS_CERT_FILE":        "/path/to/cert.pem",
+				"TLS_KEY_FILE":         "/path/to/key.pem",
+				"TLS_OCSP_STAPLE_FILE": "/path/to/ocsp.der",
+				"TLS_CIPHER_SUITES":    "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+				"TLS_CURVES":           "X25519MLKEM768, x25519, P-256",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.TLS.MinVersion != tls.VersionTLS12 {
+					t.Errorf("Expected default minimum version TLS 1.2, got %x", cfg.TLS.MinVersion)
+				}
+				if cfg.TLS.OCSPStapleFile != "/path/to/ocsp.der" {
+					t.Errorf("Expected OCSP staple file /path/to/ocsp.der, got %s", cfg.TLS.OCSPStapleFile)
+				}
+				expectedSuites := []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256}
+				if !slices.Equal(cfg.TLS.CipherSuites, expectedSuites) {
+					t.Errorf("Expected cipher suites %v, got %v", expectedSuites, cfg.TLS.CipherSuites)
+				}
+				expectedCurves := []tls.CurveID{tls.X25519MLKEM768, tls.X25519, tls.CurveP256}
+				if !slices.Equal(cfg.TLS.CurvePreferences, expectedCurves) {
+					t.Errorf("Expected curves %v, got %v", expectedCurves, cfg.TLS.CurvePreferences)
+				}
+			},
+		},
+		{
+			name: "tls 1.3 only",
+			envVars: map[string]string{
+				"TLS_MIN_VERSION": "1.3",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.TLS.MinVersion != tls.VersionTLS13 {
+					t.Errorf("Expected minimum version TLS 1.3, got %x", cfg.TLS.MinVersion)
+				}
+			},
+		},
+		{
+			name: "unsupported tls version",
+			envVars: map[string]string{
+				"TLS_MIN_VERSION": "1.0",
+			},
+			expectError: true,
+		},
+		{
+			name: "insecure cipher suite",
+			envVars: map[string]string{
+				"TLS_CIPHER_SUITES": "TLS_RSA_WITH_RC4_128_SHA",
+			},
+			expectError: true,
+		},
+		{
+			name: "tls 1.3 cipher suite",
+			envVars: map[string]string{
+				"TLS_CIPHER_SUITES": "TLS_AES_128_GCM_SHA256",
+			},
+			expectError: true,
+		},
+		{
+			name: "cipher suites with tls 1.3 minimum",
+			envVars: map[string]string{
+				"TLS_MIN_VERSION":   "1.3",
+				"TLS_CIPHER_SUITES": "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown curve",
+			envVars: map[string]string{
+				"TLS_CURVES": "P-224",
+			},
+			expectError: true,
+		},
+		{
+			name: "ocsp staple without certificate files",
+			envVars: map[string]string{
+				"ACME_DOMAINS":         "example.com",
+				"TLS_OCSP_STAPLE_FILE": "/path/to/ocsp.der",
+			},
+			expectError: true,
+		},
+		{
+			name:        "health defaults",
+			envVars:     map[string]string{},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Health.CheckTimeout != 2*time.Second {
+					t.Errorf("Expected default check timeout 2s, got %v", cfg.Health.CheckTimeout)
+				}
+				if cfg.Health.CertMinValidity != 168*time.Hour {
+					t.Errorf("Expected default certificate minimum validity 168h, got %v", cfg.Health.CertMinValidity)
+				}
+				if cfg.Health.DiskPath != "." || cfg.Health.DiskMinFreeMB != 100 {
+					t.Errorf("Expected disk check of 100 MB on ., got %d MB on %s", cfg.Health.DiskMinFreeMB, cfg.Health.DiskPath)
+				}
+				if cfg.Health.DrainDelay != 0 {
+					t.Errorf("Expected no drain delay by default, got %v", cfg.Health.DrainDelay)
+				}
+			},
+		},
+		{
+			name: "health configuration",
+			envVars: map[string]string{
+				"HEALTH_CHECK_TIMEOUT":     "500ms",
+				"HEALTH_DISK_PATH":         "/var/lib/website",
+				"HEALTH_DISK_MIN_FREE_MB":  "0",
+				"SHUTDOWN_DRAIN_DELAY":     "10s",
+				"HEALTH_CERT_MIN_VALIDITY": "72h",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Health.CheckTimeout != 500*time.Millisecond {
+					t.Errorf("Expected check timeout 500ms, got %v", cfg.Health.CheckTimeout)
+				}
+				if cfg.Health.DiskPath != "/var/lib/website" || cfg.Health.DiskMinFreeMB != 0 {
+					t.Errorf("Expected disabled disk check on /var/lib/website, got %d MB on %s", cfg.Health.DiskMinFreeMB, cfg.Health.DiskPath)
+				}
+				if cfg.Health.DrainDelay != 10*time.Second {
+					t.Errorf("Expected drain delay 10s, got %v", cfg.Health.DrainDelay)
+				}
+				if cfg.Health.CertMinValidity != 72*time.Hour {
+					t.Errorf("Expected certificate minimum validity 72h, got %v", cfg.Health.CertMinValidity)
+				}
+			},
+		},
+		{
+			name: "invalid drain delay",
+			envVars: map[string]string{
+				"SHUTDOWN_DRAIN_DELAY": "-5s",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid disk minimum",
+			envVars: map[string]string{
+				"HEALTH_DISK_MIN_FREE_MB": "lots",
+			},
+			expectError: true,
+		},
+		{
+			name: "rate limit",
+			envVars: map[string]string{
+				"RATE_LIMIT_REQUESTS": "30",
+				"RATE_LIMIT_WINDOW":   "10s",
+			},
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Security.RateLimitRequests != 30 || cfg.Security.RateLimitWindow != 10*time.Second {
+					t.Errorf("Expected 30 requests per 10s, got %d per %v", cfg.Security.RateLimitRequests, cfg.Security.RateLimitWindow)
+				}
+			},
+		},
+		{
+			name: "rate limit window too short",
+			envVars: map[string]string{
+				"RATE_LIMIT_REQUESTS": "100",
+				"RATE_LIMIT_WINDOW":   "10ns",
+			},
+			expectError: true,
+		},
+		{
+			name:    "site defaults",
+			envVars: map[string]string{},
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Site.BaseURL != "" {
+					t.Errorf("Expected no base URL by default, got %s", cfg.Site.BaseURL)
+				}
+				if !slices.Equal(cfg.Site.RobotsDisallow, []string{"/admin/", "/preview/", "/api/"}) {
+					t.Errorf("Expected default robots disallow list, got %v", cfg.Site.RobotsDisallow)
+				}
+			},
+		},
+		{
+			name: "site settings",
+			envVars: map[string]string{
+				"BASE_URL":        "https://example.com/site/",
+				"ROBOTS_DISALLOW": "/",
+			},
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Site.BaseURL != "https://example.com/site" {
+					t.Errorf("Expected base URL without trailing slash, got %s", cfg.Site.BaseURL)
+				}
+				if !slices.Equal(cfg.Site.RobotsDisallow, []string{"/"}) {
+					t.Errorf("Expected robots to disallow everything, got %v", cfg.Site.RobotsDisallow)
+				}
+			},
+		},
+		{
+			name: "invalid base URL",
+			envVars: map[string]string{
+				"BASE_URL": "example.com",
+			},
+			expectError: true,
+		},
+		{
+			name: "base URL with query",
+			envVars: map[string]string{
+				"BASE_URL": "https://example.com/?ref=1",
+			},
+			expectError: true,
+		},
+		{
+			name: "relative robots path",
+			envVars: map[string]string{
+				"ROBOTS_DISALLOW": "admin",
+			},
+			expectError: true,
+		},
 		{
 			name: "invalid port",
 			envVars: map[string]string{
@@ -173,6 +763,30 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestTracingFileRequired(t *testing.T) {
+	t.Setenv("TRACING_EXPORTER", "")
+	t.Setenv("TRACING_FILE", "")
+	file := writeFile(t, "config.yaml", "tracing:\n  exporter: file\n  file: \"\"\n")
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{"flag", Options{Flags: map[string]string{"TRACING_EXPORTER": "file"}}, "TRACING_FILE is required when -tracing-exporter is file"},
+		{"file", Options{File: file}, "tracing.file (" + file + ":3) is required when tracing.exporter (" + file + ":2) is file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFrom(tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
 func TestParsePort(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -252,55 +866,6 @@ func TestParseDuration(t *testing.T) {
 	}
 }
 
-func TestGetEnv(t *testing.T) {
-	tests := []struct {
-		name         string
-		key          string
-		defaultValue string
-		envValue     string
-		expected     string
-	}{
-		{
-			name:         "environment variable set",
-			key:          "TEST_VAR",
-			defaultValue: "default",
-			envValue:     "custom",
-			expected:     "custom",
-		},
-		{
-			name:         "environment variable not set",
-			key:          "TEST_VAR_UNSET",
-			defaultValue: "default",
-			envValue:     "",
-			expected:     "default",
-		},
-		{
-			name:         "empty environment variable",
-			key:          "TEST_VAR_EMPTY",
-			defaultValue: "default",
-			envValue:     "",
-			expected:     "default",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Cleanup
-			defer os.Unsetenv(tt.key)
-
-			if tt.envValue != "" {
-				os.Setenv(tt.key, tt.envValue)
-			}
-
-			result := getEnv(tt.key, tt.defaultValue)
-
-			if result != tt.expected {
-				t.Errorf("Expected %s, got %s", tt.expected, result)
-			}
-		})
-	}
-}
-
 // Benchmark tests for performance
 func BenchmarkLoad(b *testing.B) {
 	// Set up a valid environment
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WriteYAML writes the configuration in config file form, with every
+// setting at the value in effect. Secrets are written as "[redacted]", so
+// the output can be shared.
+func (c *Config) WriteYAML(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, st := range settings {
+		v := c.values[st.env]
+		if secretSettings[st.env] {
+			v = Secret{v}.String()
+		}
+
+		parts := strings.Split(st.key, ".")
+		section := root
+		for _, part := range parts[:len(parts)-1] {
+			section = child(section, part)
+		}
+
+		valueNode := &yaml.Node{Kind: yaml.ScalarNode, Value: v}
+		if v == "" {
+			valueNode.Style = yaml.DoubleQuotedStyle
+		}
+		section.Content = append(section.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: parts[len(parts)-1]},
+			valueNode,
+		)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Format implements fmt.Formatter. The settings as given, which hold a
+// marker for each secret, are left out; the Secret fields redact
+// themselves.
+func (c Config) Format(f fmt.State, verb rune) {
+	type plain Config
+	p := plain(c)
+	p.values = nil
+	fmt.Fprintf(f, fmt.FormatString(f, verb), p)
+}
+
+// LogValue implements slog.LogValuer, logging every setting by its config
+// file key with secrets redacted, as WriteYAML does
+func (c Config) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(settings))
+	for _, st := range settings {
+		v := c.values[st.env]
+		if secretSettings[st.env] {
+			v = Secret{v}.String()
+		}
+		attrs = append(attrs, slog.String(st.key, v))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// child returns the mapping under key in node, adding it if needed
+func child(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	section := &yaml.Node{Kind: yaml.MappingNode}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, section)
+	return section
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
+	"testing"
+)
+
+func clearSettings(t *testing.T) {
+	t.Helper()
+	for _, st := range settings {
+		t.Setenv(st.env, "")
+		if secretSettings[st.env] {
+			t.Setenv(st.env+"_FILE", "")
+		}
+	}
+}
+
+func TestWriteYAMLRoundTrip(t *testing.T) {
+	clearSettings(t)
+	t.Setenv("PORT", "9000")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example, https://b.example")
+	t.Setenv("LOG_LEVEL", "debug")
+
+	cfg, err := LoadFrom(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := cfg.WriteYAML(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	clearSettings(t)
+	reloaded, err := LoadFrom(Options{File: writeFile(t, "config.yaml", out.String())})
+	if err != nil {
+		t.Fatalf("Expected printed config to load, got %v\n%s", err, out.String())
+	}
+	if !maps.Equal(cfg.values, reloaded.values) {
+		t.Errorf("Expected the same values after a round trip, got %v and %v", cfg.values, reloaded.values)
+	}
+}
+
+func TestWriteYAMLRedactsSecrets(t *testing.T) {
+	clearSettings(t)
+	t.Setenv("METRICS_TOKEN", "hunter2")
+
+	cfg, err := LoadFrom(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := cfg.WriteYAML(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(out.String(), "hunter2") {
+		t.Error("Expected the metrics token to be redacted")
+	}
+	if !strings.Contains(out.String(), "token: '[redacted]'") {
+		t.Errorf("Expected a redacted token, got:\n%s", out.String())
+	}
+}
+
+func TestConfigRedactsSecrets(t *testing.T) {
+	clearSettings(t)
+	t.Setenv("METRICS_TOKEN", "hunter2")
+
+	cfg, err := LoadFrom(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	marker := cfg.values["METRICS_TOKEN"]
+	if marker == "" || strings.Contains(marker, "hunter2") {
+		t.Fatalf("Expected a change marker for the token, got %q", marker)
+	}
+
+	var logged bytes.Buffer
+	slog.New(slog.NewTextHandler(&logged, nil)).Info("config", "config", cfg)
+
+	outputs := map[string]string{
+		"%v":    fmt.Sprintf("%v", cfg),
+		"%+v":   fmt.Sprintf("%+v", cfg),
+		"%#v":   fmt.Sprintf("%#v", cfg),
+		"%s":    fmt.Sprintf("%s", cfg),
+		"value": fmt.Sprintf("%+v", *cfg),
+		"slog":  logged.String(),
+	}
+	for name, out := range outputs {
+		if strings.Contains(out, "hunter2") || strings.Contains(out, marker) {
+			t.Errorf("Expected %s to hide the token, got %s", name, out)
+		}
+	}
+	if !strings.Contains(logged.String(), "config.metrics.token=[redacted]") {
+		t.Errorf("Expected the token logged as redacted, got %s", logged.String())
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import "fmt"
+
+// restartSettings only take effect when the server starts: they configure
+// listeners, TLS, tracing and the readiness checks registered at startup.
+// CANONICAL_HOST is among them because the HTTP redirect listener uses it.
+var restartSettings = map[string]bool{
+	"HOST":                        true,
+	"PORT":                        true,
+	"READ_TIMEOUT":                true,
+	"WRITE_TIMEOUT":               true,
+	"IDLE_TIMEOUT":                true,
+	"CANONICAL_HOST":              true,
+	"TLS_CERT_FILE":               true,
+	"TLS_KEY_FILE":                true,
+	"TLS_OCSP_STAPLE_FILE":        true,
+	"TLS_MIN_VERSION":             true,
+	"TLS_CIPHER_SUITES":           true,
+	"TLS_CURVES":                  true,
+	"HTTP_REDIRECT_ADDR":          true,
+	"HTTPS_PUBLIC_PORT":           true,
+	"ACME_DOMAINS":                true,
+	"ACME_EMAIL":                  true,
+	"ACME_CACHE_DIR":              true,
+	"ACME_DIRECTORY_URL":          true,
+	"ACME_CA_ROOT":                true,
+	"ACME_RENEW_BEFORE":           true,
+	"METRICS_ADDR":                true,
+	"TRACING_EXPORTER":            true,
+	"OTEL_EXPORTER_OTLP_ENDPOINT": true,
+	"TRACING_FILE":                true,
+	"OTEL_SERVICE_NAME":           true,
+	"HEALTH_CHECK_TIMEOUT":        true,
+	"HEALTH_CERT_MIN_VALIDITY":    true,
+	"HEALTH_DISK_PATH":            true,
+	"HEALTH_DISK_MIN_FREE_MB":     true,
+	"SHUTDOWN_DRAIN_DELAY":        true,
+}
+
+// Change is a setting that differs between two configurations
+type Change struct {
+	// Key is the setting's config file key, e.g. "server.port"
+	Key string
+	// Env is the setting's environment variable, e.g. "PORT"
+	Env string
+	// Old and New are the values as given; secrets are "[redacted]", or
+	// empty when unset
+	Old string
+	New string
+	// Restart is set when the change was ignored because it only takes
+	// effect when the server starts
+	Restart bool
+}
+
+// String describes the change for logs
+func (c Change) String() string {
+	s := fmt.Sprintf("%s: %q -> %q", c.Key, c.Old, c.New)
+	if c.Restart {
+		s += " (ignored until restart)"
+	}
+	return s
+}
+
+// Reload compares a newly loaded configuration with the running one. It
+// returns the configuration to apply, which is next with the settings
+// that need a restart kept as they are in current, and every change in
+// settings order.
+func Reload(current, next *Config) (*Config, []Change) {
+	var changes []Change
+	for _, st := range settings {
+		from, to := current.values[st.env], next.values[st.env]
+		if from == to {
+			continue
+		}
+		if secretSettings[st.env] {
+			from, to = Secret{from}.String(), Secret{to}.String()
+		}
+		changes = append(changes, Change{
+			Key:     st.key,
+			Env:     st.env,
+			Old:     from,
+			New:     to,
+			Restart: restartSettings[st.env],
+		})
+	}
+
+	applied := *next
+	applied.Server = current.Server
+	applied.Server.TrailingSlash = next.Server.TrailingSlash
+	applied.TLS = current.TLS
+	applied.Metrics.Addr = current.Metrics.Addr
+	applied.Tracing = current.Tracing
+	applied.Health = current.Health
+
+	applied.values = make(map[string]string, len(next.values))
+	for env, v := range next.values {
+		if restartSettings[env] {
+			v = current.values[env]
+		}
+		applied.values[env] = v
+	}
+
+	return &applied, changes
+}
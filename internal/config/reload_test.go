@@ -0,0 +1,74 @@
+package config
+
+import "testing"
+
+func TestReload(t *testing.T) {
+	t.Setenv("PORT", "")
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("TRAILING_SLASH", "")
+	t.Setenv("METRICS_TOKEN", "")
+	t.Setenv("RATE_LIMIT_REQUESTS", "")
+
+	current, err := LoadFrom(Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	next, err := LoadFrom(Options{Flags: map[string]string{
+		"PORT":                "9090",
+		"LOG_LEVEL":           "debug",
+		"TRAILING_SLASH":      "strip",
+		"METRICS_TOKEN":       "s3cret",
+		"RATE_LIMIT_REQUESTS": "10",
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	applied, changes := Reload(current, next)
+
+	expected := []string{
+		`server.port: "8080" -> "9090" (ignored until restart)`,
+		`server.trailing_slash: "" -> "strip"`,
+		`app.log_level: "info" -> "debug"`,
+		`metrics.token: "" -> "[redacted]"`,
+		`security.rate_limit_requests: "100" -> "10"`,
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %v", len(expected), changes)
+	}
+	for i, change := range changes {
+		if change.String() != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], change)
+		}
+	}
+
+	if applied.Server.Port != 8080 {
+		t.Errorf("Expected the port to need a restart, got %d", applied.Server.Port)
+	}
+	if applied.Server.TrailingSlash != "strip" || applied.App.LogLevel != "debug" || applied.Security.RateLimitRequests != 10 {
+		t.Errorf("Expected live settings to be applied, got %+v", applied)
+	}
+
+	// The ignored change is reported again on the next reload
+	_, changes = Reload(applied, next)
+	if len(changes) != 1 || changes[0].Env != "PORT" || !changes[0].Restart {
+		t.Errorf("Expected only the port change, got %v", changes)
+	}
+}
+
+func TestRestartSettingsExist(t *testing.T) {
+	known := map[string]bool{}
+	for _, st := range settings {
+		known[st.env] = true
+	}
+	for env := range restartSettings {
+		if !known[env] {
+			t.Errorf("Expected %s to be a setting", env)
+		}
+	}
+	for env := range secretSettings {
+		if !known[env] {
+			t.Errorf("Expected %s to be a setting", env)
+		}
+	}
+}
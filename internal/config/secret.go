@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// redacted replaces secret values wherever configuration is printed
+const redacted = "[redacted]"
+
+// Secret is a configuration value that must never be printed, such as a
+// password or token. However it is formatted, encoded or logged it shows
+// as "[redacted]", or empty when unset; Value returns the contents.
+type Secret struct {
+	value string
+}
+
+// NewSecret wraps a secret value
+func NewSecret(value string) Secret {
+	return Secret{value: value}
+}
+
+// Value returns the secret itself, for the code that uses it
+func (s Secret) Value() string {
+	return s.value
+}
+
+// IsSet reports whether the secret has a value
+func (s Secret) IsSet() bool {
+	return s.value != ""
+}
+
+// String returns "[redacted]", or "" for an unset secret
+func (s Secret) String() string {
+	if s.value == "" {
+		return ""
+	}
+	return redacted
+}
+
+// Format implements fmt.Formatter so that no verb, including %d and %#v,
+// prints the value
+func (s Secret) Format(f fmt.State, verb rune) {
+	io.WriteString(f, s.String())
+}
+
+// LogValue implements slog.LogValuer
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// MarshalText implements encoding.TextMarshaler, which JSON and YAML
+// encoders use
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
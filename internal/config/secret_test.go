@@ -0,0 +1,51 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSecretNeverPrints(t *testing.T) {
+	secret := NewSecret("hunter2")
+
+	outputs := map[string]string{}
+	for _, verb := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%d"} {
+		outputs[verb] = fmt.Sprintf(verb, secret)
+	}
+	outputs["struct"] = fmt.Sprintf("%+v", MetricsConfig{Token: secret})
+	outputs["config"] = fmt.Sprintf("%+v", &Config{Metrics: MetricsConfig{Token: secret}})
+
+	b, err := json.Marshal(MetricsConfig{Token: secret})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	outputs["json"] = string(b)
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("config", "token", secret)
+	outputs["slog"] = buf.String()
+
+	for name, output := range outputs {
+		if strings.Contains(output, "hunter2") {
+			t.Errorf("%s: expected the secret to be redacted, got %s", name, output)
+		}
+		if !strings.Contains(output, "[redacted]") {
+			t.Errorf("%s: expected [redacted], got %s", name, output)
+		}
+	}
+
+	if secret.Value() != "hunter2" {
+		t.Errorf("Expected Value to return the secret, got %s", secret.Value())
+	}
+}
+
+func TestSecretUnset(t *testing.T) {
+	var secret Secret
+	if secret.IsSet() || secret.String() != "" {
+		t.Errorf("Expected an unset secret to print empty, got %q", secret.String())
+	}
+}
@@ -0,0 +1,436 @@
+package config
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setting ties an environment variable to its key in the config file.
+// Command-line flags are named after the variable: TLS_CERT_FILE is
+// -tls-cert-file.
+type setting struct {
+	env   string
+	key   string
+	usage string
+}
+
+// settings lists every configuration value. The file sections mirror the
+// Config structs.
+var settings = []setting{
+	{"HOST", "server.host", "address to listen on"},
+	{"PORT", "server.port", "port to listen on"},
+	{"READ_TIMEOUT", "server.read_timeout", "maximum duration for reading a request"},
+	{"WRITE_TIMEOUT", "server.write_timeout", "maximum duration for writing a response"},
+	{"IDLE_TIMEOUT", "server.idle_timeout", "keep-alive timeout"},
+	{"CANONICAL_HOST", "server.canonical_host", "host name other hosts redirect to"},
+	{"TRAILING_SLASH", "server.trailing_slash", "trailing slash policy: strip or add"},
+
+	{"TLS_CERT_FILE", "tls.cert_file", "TLS certificate file"},
+	{"TLS_KEY_FILE", "tls.key_file", "TLS private key file"},
+	{"TLS_OCSP_STAPLE_FILE", "tls.ocsp_staple_file", "DER OCSP response to staple"},
+	{"TLS_MIN_VERSION", "tls.min_version", "minimum TLS version: 1.2 or 1.3"},
+	{"TLS_CIPHER_SUITES", "tls.cipher_suites", "TLS 1.2 cipher suites"},
+	{"TLS_CURVES", "tls.curves", "key exchange curves in order of preference"},
+	{"HTTP_REDIRECT_ADDR", "tls.redirect_addr", "plain HTTP listener that redirects to HTTPS"},
+	{"HTTPS_PUBLIC_PORT", "tls.public_port", "HTTPS port used in redirects"},
+	{"ACME_DOMAINS", "tls.acme.domains", "domains to obtain ACME certificates for"},
+	{"ACME_EMAIL", "tls.acme.email", "ACME account contact"},
+	{"ACME_CACHE_DIR", "tls.acme.cache_dir", "ACME certificate cache directory"},
+	{"ACME_DIRECTORY_URL", "tls.acme.directory_url", "ACME directory URL"},
+	{"ACME_CA_ROOT", "tls.acme.ca_root", "extra PEM root for the ACME directory"},
+	{"ACME_RENEW_BEFORE", "tls.acme.renew_before", "renew ACME certificates this long before expiry"},
+
+	{"ENV", "app.environment", "environment: development or production"},
+	{"LOG_LEVEL", "app.log_level", "log level"},
+	{"PREFER_DISK", "app.prefer_disk", "serve static/ and content/ from disk even when embedded"},
+	{"PAGE_CACHE_SIZE", "app.page_cache_size", "rendered pages to cache; 0 disables"},
+
+	{"METRICS_ADDR", "metrics.addr", "admin listener address for /metrics"},
+	{"METRICS_TOKEN", "metrics.token", "bearer token for /metrics on the main listener"},
+
+	{"TRACING_EXPORTER", "tracing.exporter", "trace exporter: none, otlp, stdout or file"},
+	{"OTEL_EXPORTER_OTLP_ENDPOINT", "tracing.endpoint", "OTLP/HTTP endpoint"},
+	{"TRACING_FILE", "tracing.file", "file for the file exporter"},
+	{"OTEL_SERVICE_NAME", "tracing.service_name", "service name on exported spans"},
+
+	{"COMPRESSION_ENABLED", "compression.enabled", "compress responses"},
+	{"COMPRESSION_MIN_SIZE", "compression.min_size", "smallest response to compress, in bytes"},
+
+	{"CSP_REPORT_URI", "security.csp_report_uri", "CSP violation report URI, or none"},
+	{"CSP_REPORT_ONLY", "security.csp_report_only", "send CSP as report-only"},
+	{"SECURITY_PROFILE", "security.header_profile", "security header profile: strict, default or development"},
+	{"SECURITY_ROUTE_HEADERS", "security.route_headers", "per-path header overrides as JSON"},
+	{"RATE_LIMIT_REQUESTS", "security.rate_limit_requests", "requests per client IP per window; 0 disables"},
+	{"RATE_LIMIT_WINDOW", "security.rate_limit_window", "rate limit window"},
+
+	{"API_ENABLED", "api.enabled", "serve the JSON API under /api"},
+	{"CORS_ALLOWED_ORIGINS", "api.cors.allowed_origins", "origins allowed to call the API"},
+	{"CORS_ALLOWED_METHODS", "api.cors.allowed_methods", "methods allowed in CORS requests"},
+	{"CORS_ALLOWED_HEADERS", "api.cors.allowed_headers", "headers allowed in CORS requests"},
+	{"CORS_ALLOW_CREDENTIALS", "api.cors.allow_credentials", "allow credentialed CORS requests"},
+	{"CORS_MAX_AGE", "api.cors.max_age", "how long browsers may cache preflights"},
+
+	{"HEALTH_CHECK_TIMEOUT", "health.check_timeout", "timeout for each readiness check"},
+	{"HEALTH_CERT_MIN_VALIDITY", "health.cert_min_validity", "minimum certificate validity for readiness"},
+	{"HEALTH_DISK_PATH", "health.disk_path", "directory checked for free space"},
+	{"HEALTH_DISK_MIN_FREE_MB", "health.disk_min_free_mb", "minimum free space in MB; 0 disables"},
+	{"SHUTDOWN_DRAIN_DELAY", "health.drain_delay", "time to keep serving after readiness fails on shutdown"},
+
+	{"BASE_URL", "site.base_url", "public URL of the site for absolute links, e.g. https://example.com"},
+	{"ROBOTS_DISALLOW", "site.robots_disallow", "path prefixes robots.txt disallows"},
+}
+
+// secretSettings hold passwords and tokens. They are typed Secret in
+// Config, redacted when changes are logged, and can be read from a file
+// named by KEY_FILE, following the Docker secrets convention.
+var secretSettings = map[string]bool{
+	"METRICS_TOKEN": true,
+}
+
+// secretKey keys the change markers recorded for secrets. It is random
+// and never leaves the process, so a marker can't be matched against
+// guessed values; it only tells two loads apart.
+var secretKey = rand.Text()
+
+// Options selects where configuration is read from. Later sources
+// override earlier ones: built-in defaults, File, DotEnv, the process
+// environment, then Flags.
+type Options struct {
+	// File is a YAML config file; empty skips it
+	File string
+	// DotEnv is a file of KEY=value lines; it is skipped if it doesn't exist
+	DotEnv string
+	// Flags holds command-line values by environment variable name, as
+	// returned by the function from Flags
+	Flags map[string]string
+}
+
+// value is a configuration value and where it came from
+type value struct {
+	value  string
+	origin string
+}
+
+// source looks values up through the configuration layers
+type source struct {
+	file   map[string]value
+	dotEnv map[string]value
+	flags  map[string]string
+	// envFiles holds secrets read from files named by KEY_FILE
+	// environment variables
+	envFiles map[string]value
+	// values records what get returned, for Config.values. Secrets are
+	// recorded as a keyed hash, which is enough to tell they changed.
+	values map[string]string
+}
+
+// get returns the value for an environment variable name from the
+// highest-precedence layer that sets it. An empty environment variable
+// counts as unset, as it always has; the other layers can set a value to
+// empty explicitly.
+func (s *source) get(key, defaultValue string) string {
+	v := s.lookup(key, defaultValue)
+	s.values[key] = v
+	if secretSettings[key] && v != "" {
+		mac := hmac.New(sha256.New, []byte(secretKey))
+		mac.Write([]byte(v))
+		s.values[key] = hex.EncodeToString(mac.Sum(nil))
+	}
+	return v
+}
+
+func (s *source) lookup(key, defaultValue string) string {
+	if v, ok := s.flags[key]; ok {
+		return v
+	}
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	if v, ok := s.envFiles[key]; ok {
+		return v.value
+	}
+	if v, ok := s.dotEnv[key]; ok {
+		return v.value
+	}
+	if v, ok := s.file[key]; ok {
+		return v.value
+	}
+	return defaultValue
+}
+
+// name describes where the value for key was set, for error messages
+func (s *source) name(key string) string {
+	if _, ok := s.flags[key]; ok {
+		return "-" + flagName(key)
+	}
+	if os.Getenv(key) != "" {
+		return key
+	}
+	if v, ok := s.envFiles[key]; ok {
+		return v.origin
+	}
+	if v, ok := s.dotEnv[key]; ok {
+		return fmt.Sprintf("%s (%s)", key, v.origin)
+	}
+	if v, ok := s.file[key]; ok {
+		return v.origin
+	}
+	return key
+}
+
+// newSource reads the files named in opts. Every problem in them is
+// reported, not just the first.
+func newSource(opts Options) (*source, []error) {
+	s := &source{flags: opts.Flags, values: map[string]string{}}
+	var errs []error
+
+	if opts.File != "" {
+		file, fileErrs := readConfigFile(opts.File)
+		s.file = file
+		errs = append(errs, fileErrs...)
+		errs = append(errs, readSecretFiles(file, func(key string) bool {
+			_, ok := file[key]
+			return ok
+		})...)
+	}
+	if opts.DotEnv != "" {
+		dotEnv, dotEnvErrs := readDotEnv(opts.DotEnv)
+		s.dotEnv = dotEnv
+		errs = append(errs, dotEnvErrs...)
+		errs = append(errs, readSecretFiles(dotEnv, func(key string) bool {
+			_, ok := dotEnv[key]
+			return ok
+		})...)
+	}
+
+	s.envFiles = map[string]value{}
+	for env := range secretSettings {
+		if path := os.Getenv(env + "_FILE"); path != "" {
+			s.envFiles[env+"_FILE"] = value{value: path, origin: env + "_FILE"}
+		}
+	}
+	errs = append(errs, readSecretFiles(s.envFiles, func(key string) bool {
+		return os.Getenv(key) != ""
+	})...)
+
+	return s, errs
+}
+
+// readSecretFiles sets each secret setting that has a KEY_FILE entry in
+// values to the contents of the file it names, without a trailing
+// newline. isSet reports whether KEY is also set in the same layer, which
+// is an error.
+func readSecretFiles(values map[string]value, isSet func(key string) bool) []error {
+	var errs []error
+	for _, st := range settings {
+		if !secretSettings[st.env] {
+			continue
+		}
+		ref, ok := values[st.env+"_FILE"]
+		if !ok || ref.value == "" {
+			continue
+		}
+		if isSet(st.env) {
+			errs = append(errs, fmt.Errorf("%s: %s and %s_FILE are both set", ref.origin, st.env, st.env))
+			continue
+		}
+		data, err := os.ReadFile(ref.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: reading secret: %w", ref.origin, err))
+			continue
+		}
+		values[st.env] = value{value: strings.TrimRight(string(data), "\r\n"), origin: ref.origin}
+	}
+	return errs
+}
+
+// readConfigFile reads a YAML config file into values keyed by environment
+// variable name. Keys that don't name a setting are errors, so typos
+// don't go unnoticed.
+func readConfigFile(path string) (map[string]value, []error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, []error{fmt.Errorf("reading config file: %w", err)}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", path, err)}
+	}
+	values := map[string]value{}
+	if len(doc.Content) == 0 {
+		return values, nil
+	}
+
+	byKey := make(map[string]string, len(settings))
+	sections := map[string]bool{}
+	for _, st := range settings {
+		byKey[st.key] = st.env
+		parts := strings.Split(st.key, ".")
+		for i := 1; i < len(parts); i++ {
+			sections[strings.Join(parts[:i], ".")] = true
+		}
+	}
+
+	var errs []error
+	var walk func(node *yaml.Node, prefix string)
+	walk = func(node *yaml.Node, prefix string) {
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+			return // an empty section, e.g. one with every key commented out
+		}
+		if node.Kind != yaml.MappingNode {
+			errs = append(errs, fmt.Errorf("%s:%d: expected a mapping of settings", path, node.Line))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			key := keyNode.Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+
+			// Secrets can name a file instead, e.g. metrics.token_file
+			env := byKey[key]
+			if base, ok := strings.CutSuffix(key, "_file"); ok && env == "" && secretSettings[byKey[base]] {
+				env = byKey[base] + "_FILE"
+			}
+
+			switch {
+			case env != "":
+				v, err := nodeString(valueNode)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s:%d: %s: %w", path, valueNode.Line, key, err))
+					continue
+				}
+				values[env] = value{value: v, origin: fmt.Sprintf("%s (%s:%d)", key, path, keyNode.Line)}
+			case sections[key]:
+				walk(valueNode, key)
+			default:
+				errs = append(errs, fmt.Errorf("%s:%d: unknown key %s", path, keyNode.Line, key))
+			}
+		}
+	}
+	walk(doc.Content[0], "")
+
+	return values, errs
+}
+
+// nodeString converts a YAML value to the string form the environment
+// variable would have: scalars as written, lists joined with commas and
+// mappings as JSON
+func nodeString(node *yaml.Node) (string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return "", nil
+		}
+		return node.Value, nil
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", errors.New("list items must be plain values")
+			}
+			items = append(items, item.Value)
+		}
+		return strings.Join(items, ","), nil
+	case yaml.MappingNode:
+		var m any
+		if err := node.Decode(&m); err != nil {
+			return "", err
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	default:
+		return "", errors.New("unsupported value")
+	}
+}
+
+// readDotEnv reads KEY=value lines. Blank lines and lines starting with #
+// are skipped, an "export " prefix is allowed and values may be quoted.
+// A missing file is not an error.
+func readDotEnv(path string) (map[string]value, []error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{fmt.Errorf("reading %s: %w", path, err)}
+	}
+	defer f.Close()
+
+	values := map[string]value{}
+	var errs []error
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, v, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			errs = append(errs, fmt.Errorf("%s:%d: expected KEY=value", path, n))
+			continue
+		}
+		values[key] = value{value: unquote(strings.TrimSpace(v)), origin: fmt.Sprintf("%s:%d", path, n)}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("reading %s: %w", path, err))
+	}
+	return values, errs
+}
+
+// unquote strips matching quotes from a .env value, or a trailing
+// " # comment" from an unquoted one
+func unquote(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	if i := strings.Index(v, " #"); i >= 0 {
+		return strings.TrimSpace(v[:i])
+	}
+	return v
+}
+
+// flagName returns the command-line flag for an environment variable
+func flagName(env string) string {
+	return strings.ToLower(strings.ReplaceAll(env, "_", "-"))
+}
+
+// Flags defines a string flag on fs for every setting. After fs.Parse, the
+// returned function reports the flags that were given, for Options.Flags.
+func Flags(fs *flag.FlagSet) func() map[string]string {
+	envs := make(map[string]string, len(settings))
+	for _, st := range settings {
+		name := flagName(st.env)
+		envs[name] = st.env
+		fs.String(name, "", fmt.Sprintf("%s (%s)", st.usage, st.env))
+	}
+
+	return func() map[string]string {
+		set := map[string]string{}
+		fs.Visit(func(f *flag.Flag) {
+			if env, ok := envs[f.Name]; ok {
+				set[env] = f.Value.String()
+			}
+		})
+		return set
+	}
+}
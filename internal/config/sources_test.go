@@ -0,0 +1,328 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFromPrecedence(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+server:
+  port: 1000
+  host: file.example
+  read_timeout: 1s
+  write_timeout: 1s
+app:
+  log_level: debug
+`)
+	dotEnv := writeFile(t, ".env", `
+PORT=2000
+HOST=dotenv.example
+READ_TIMEOUT=2s
+`)
+	t.Setenv("PORT", "3000")
+	t.Setenv("HOST", "")
+	t.Setenv("READ_TIMEOUT", "3s")
+	t.Setenv("WRITE_TIMEOUT", "")
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("ENV", "")
+
+	cfg, err := LoadFrom(Options{File: file, DotEnv: dotEnv, Flags: map[string]string{"PORT": "4000"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		got      any
+		expected any
+	}{
+		{"flag over environment", cfg.Server.Port, 4000},
+		{"environment over .env", cfg.Server.ReadTimeout.String(), "3s"},
+		{".env over file, empty environment ignored", cfg.Server.Host, "dotenv.example"},
+		{"file over default", cfg.Server.WriteTimeout.String(), "1s"},
+		{"file only", cfg.App.LogLevel, "debug"},
+		{"default", cfg.App.Environment, "development"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, tt.got)
+			}
+		})
+	}
+}
+
+func TestLoadFromReportsAllErrors(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+server:
+  port: abc
+  prot: 8080
+tls:
+  min_version: "1.1"
+`)
+	t.Setenv("PORT", "")
+	t.Setenv("TLS_MIN_VERSION", "")
+	t.Setenv("IDLE_TIMEOUT", "")
+
+	_, err := LoadFrom(Options{File: file, Flags: map[string]string{"IDLE_TIMEOUT": "soon"}})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	for _, expected := range []string{
+		"config.yaml:4: unknown key server.prot",
+		"invalid server.port (" + file + ":3)",
+		"invalid tls.min_version (" + file + ":6)",
+		"invalid -idle-timeout",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing %q, got %q", expected, err)
+		}
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    map[string]string
+		expectError string
+	}{
+		{
+			name:     "scalars",
+			content:  "server:\n  port: 8080\n  canonical_host: example.com\n",
+			expected: map[string]string{"PORT": "8080", "CANONICAL_HOST": "example.com"},
+		},
+		{
+			name:     "nested sections and lists",
+			content:  "tls:\n  acme:\n    domains: [example.com, www.example.com]\n",
+			expected: map[string]string{"ACME_DOMAINS": "example.com,www.example.com"},
+		},
+		{
+			name:     "mapping as JSON",
+			content:  "security:\n  route_headers:\n    /embed/:\n      X-Frame-Options: SAMEORIGIN\n",
+			expected: map[string]string{"SECURITY_ROUTE_HEADERS": `{"/embed/":{"X-Frame-Options":"SAMEORIGIN"}}`},
+		},
+		{
+			name:     "empty section",
+			content:  "metrics:\n  # addr: 127.0.0.1:9090\n",
+			expected: map[string]string{},
+		},
+		{
+			name:     "empty file",
+			content:  "",
+			expected: map[string]string{},
+		},
+		{
+			name:        "unknown section",
+			content:     "database:\n  url: postgres://\n",
+			expectError: ":1: unknown key database",
+		},
+		{
+			name:        "nested list",
+			content:     "tls:\n  acme:\n    domains: [[example.com]]\n",
+			expectError: "tls.acme.domains: list items must be plain values",
+		},
+		{
+			name:        "section used as a setting",
+			content:     "server: 8080\n",
+			expectError: ":1: expected a mapping of settings",
+		},
+		{
+			name:        "invalid YAML",
+			content:     "server: [\n",
+			expectError: "yaml:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, errs := readConfigFile(writeFile(t, "config.yaml", tt.content))
+
+			if tt.expectError != "" {
+				if len(errs) == 0 || !strings.Contains(errs[0].Error(), tt.expectError) {
+					t.Errorf("Expected error containing %q, got %v", tt.expectError, errs)
+				}
+				return
+			}
+			if len(errs) > 0 {
+				t.Fatalf("Unexpected errors: %v", errs)
+			}
+			if len(values) != len(tt.expected) {
+				t.Errorf("Expected %d values, got %d", len(tt.expected), len(values))
+			}
+			for key, expected := range tt.expected {
+				if got := values[key].value; got != expected {
+					t.Errorf("Expected %s=%s, got %s", key, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestReadDotEnv(t *testing.T) {
+	path := writeFile(t, ".env", `# comment
+PORT=8080
+export HOST=localhost
+CSP_REPORT_URI="/csp-report # not a comment"
+LOG_LEVEL=debug # comment
+EMPTY=
+not a setting
+`)
+
+	values, errs := readDotEnv(path)
+
+	expected := map[string]string{
+		"PORT":           "8080",
+		"HOST":           "localhost",
+		"CSP_REPORT_URI": "/csp-report # not a comment",
+		"LOG_LEVEL":      "debug",
+		"EMPTY":          "",
+	}
+	for key, value := range expected {
+		if got, ok := values[key]; !ok || got.value != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, got.value)
+		}
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), ":7: expected KEY=value") {
+		t.Errorf("Expected an error for line 7, got %v", errs)
+	}
+
+	if values, errs := readDotEnv(filepath.Join(t.TempDir(), ".env")); values != nil || errs != nil {
+		t.Errorf("Expected a missing .env to be ignored, got %v, %v", values, errs)
+	}
+}
+
+func TestFlags(t *testing.T) {
+	fs := flag.NewFlagSet("website", flag.ContinueOnError)
+	overrides := Flags(fs)
+
+	if err := fs.Parse([]string{"-port", "9000", "-tls-cert-file", "cert.pem", "-canonical-host="}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]string{"PORT": "9000", "TLS_CERT_FILE": "cert.pem", "CANONICAL_HOST": ""}
+	got := overrides()
+	if len(got) != len(expected) {
+		t.Errorf("Expected only the given flags, got %v", got)
+	}
+	for key, value := range expected {
+		if v, ok := got[key]; !ok || v != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, v)
+		}
+	}
+}
+
+func BenchmarkReadConfigFile(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "config.yaml")
+	content := "server:\n  port: 8080\ntls:\n  acme:\n    domains: [example.com]\nsecurity:\n  route_headers:\n    /embed/:\n      X-Frame-Options: SAMEORIGIN\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		readConfigFile(path)
+	}
+}
+
+func TestSecretFiles(t *testing.T) {
+	secretFile := writeFile(t, "metrics_token", "from-file\n")
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		file        string
+		dotEnv      string
+		expected    string
+		expectError string
+	}{
+		{
+			name:     "environment",
+			env:      map[string]string{"METRICS_TOKEN_FILE": secretFile},
+			expected: "from-file",
+		},
+		{
+			name:     ".env",
+			dotEnv:   "METRICS_TOKEN_FILE=" + secretFile + "\n",
+			expected: "from-file",
+		},
+		{
+			name:     "config file",
+			file:     "metrics:\n  token_file: " + secretFile + "\n",
+			expected: "from-file",
+		},
+		{
+			name:     "environment over .env",
+			env:      map[string]string{"METRICS_TOKEN_FILE": secretFile},
+			dotEnv:   "METRICS_TOKEN=from-dotenv\n",
+			expected: "from-file",
+		},
+		{
+			name:        "both in one layer",
+			env:         map[string]string{"METRICS_TOKEN": "plain", "METRICS_TOKEN_FILE": secretFile},
+			expectError: "METRICS_TOKEN and METRICS_TOKEN_FILE are both set",
+		},
+		{
+			name:        "missing file",
+			file:        "metrics:\n  token_file: " + missing + "\n",
+			expectError: "metrics.token_file (",
+		},
+		{
+			name:        "not a secret",
+			file:        "server:\n  port_file: /run/secrets/port\n",
+			expectError: "unknown key server.port_file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("METRICS_TOKEN", "")
+			t.Setenv("METRICS_TOKEN_FILE", "")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			var opts Options
+			if tt.file != "" {
+				opts.File = writeFile(t, "config.yaml", tt.file)
+			}
+			if tt.dotEnv != "" {
+				opts.DotEnv = writeFile(t, ".env", tt.dotEnv)
+			}
+
+			cfg, err := LoadFrom(opts)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("Expected error containing %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if cfg.Metrics.Token.Value() != tt.expected {
+				t.Errorf("Expected token %q, got %q", tt.expected, cfg.Metrics.Token.Value())
+			}
+			if strings.Contains(fmt.Sprintf("%+v", cfg), tt.expected) {
+				t.Error("Expected the token to be redacted when the config is printed")
+			}
+		})
+	}
+}
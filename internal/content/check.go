@@ -0,0 +1,99 @@
+package content
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/claykom/website/internal/middleware"
+)
+
+// Directories under the content root
+const (
+	BlogDir      = "blog"
+	PortfolioDir = "portfolio"
+)
+
+// Check validates every post and project under fsys, the content root,
+// and returns all problems found. It also returns the number of files
+// checked.
+func Check(fsys fs.FS) (int, []error) {
+	validator := middleware.NewValidator()
+	checked := 0
+	var errs []error
+
+	for _, dir := range []string{BlogDir, PortfolioDir} {
+		files, err := fs.ReadDir(fsys, dir)
+		if errors.Is(err, fs.ErrNotExist) && dir == PortfolioDir {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		slugs := map[string]string{}
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(file.Name(), ".md") {
+				continue
+			}
+			filePath := path.Join(dir, file.Name())
+			checked++
+
+			data, err := fs.ReadFile(fsys, filePath)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			fm, _, err := Split(data)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", filePath, err))
+				continue
+			}
+
+			for _, problem := range checkFrontmatter(fm, dir, validator) {
+				errs = append(errs, fmt.Errorf("%s: %s", filePath, problem))
+			}
+			if other, ok := slugs[fm["slug"]]; ok && fm["slug"] != "" {
+				errs = append(errs, fmt.Errorf("%s: slug %q is also used by %s", filePath, fm["slug"], other))
+			}
+			slugs[fm["slug"]] = filePath
+		}
+	}
+
+	return checked, errs
+}
+
+// checkFrontmatter returns the problems with one file's frontmatter
+func checkFrontmatter(fm Frontmatter, dir string, validator *middleware.ValidateInput) []string {
+	var problems []string
+
+	if fm["title"] == "" {
+		problems = append(problems, "missing title")
+	}
+	if fm["slug"] == "" {
+		problems = append(problems, "missing slug")
+	} else if !validator.ValidateSlug(fm["slug"]) {
+		problems = append(problems, fmt.Sprintf("invalid slug %q: use letters, digits, - and _", fm["slug"]))
+	}
+
+	if fm["date"] == "" {
+		problems = append(problems, "missing date")
+	}
+	for _, key := range []string{"date", "updated"} {
+		if _, err := fm.Date(key); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid %s %q: want YYYY-MM-DD", key, fm[key]))
+		}
+	}
+
+	if dir == PortfolioDir && fm["featured"] != "" {
+		if _, err := strconv.ParseBool(fm["featured"]); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid featured %q: want true or false", fm["featured"]))
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,103 @@
+package content
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func file(data string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(data)}
+}
+
+func TestCheck(t *testing.T) {
+	valid := "---\ntitle: T\nslug: %s\ndate: 2026-01-02\n---\nBody\n"
+
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		checked  int
+		problems []string
+	}{
+		{
+			name: "valid",
+			files: fstest.MapFS{
+				"blog/a.md":      file(strings.Replace(valid, "%s", "a", 1)),
+				"blog/notes.txt": file("ignored"),
+				"portfolio/a.md": file(strings.Replace(valid, "%s", "a", 1) + "featured: true\n"),
+				"portfolio/b.md": file(strings.Replace(valid, "%s", "b", 1)),
+			},
+			checked: 3,
+		},
+		{
+			name:    "no portfolio directory",
+			files:   fstest.MapFS{"blog/a.md": file(strings.Replace(valid, "%s", "a", 1))},
+			checked: 1,
+		},
+		{
+			name:     "no blog directory",
+			files:    fstest.MapFS{},
+			problems: []string{"blog"},
+		},
+		{
+			name:     "missing frontmatter",
+			files:    fstest.MapFS{"blog/a.md": file("# Title\n")},
+			checked:  1,
+			problems: []string{"blog/a.md: missing --- frontmatter"},
+		},
+		{
+			name:    "bad fields",
+			files:   fstest.MapFS{"blog/a.md": file("---\nslug: Bad Slug!\nupdated: soon\n---\n")},
+			checked: 1,
+			problems: []string{
+				"blog/a.md: missing title",
+				`blog/a.md: invalid slug "Bad Slug!"`,
+				"blog/a.md: missing date",
+				`blog/a.md: invalid updated "soon"`,
+			},
+		},
+		{
+			name:     "bad featured",
+			files:    fstest.MapFS{"portfolio/a.md": file("---\ntitle: T\nslug: a\ndate: 2026-01-02\nfeatured: maybe\n---\n"), "blog": {Mode: 0755 | os.ModeDir}},
+			checked:  1,
+			problems: []string{`portfolio/a.md: invalid featured "maybe"`},
+		},
+		{
+			name: "duplicate slug",
+			files: fstest.MapFS{
+				"blog/a.md": file(strings.Replace(valid, "%s", "same", 1)),
+				"blog/b.md": file(strings.Replace(valid, "%s", "same", 1)),
+			},
+			checked:  2,
+			problems: []string{`blog/b.md: slug "same" is also used by blog/a.md`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checked, errs := Check(tt.files)
+			if checked != tt.checked {
+				t.Errorf("Expected %d files checked, got %d", tt.checked, checked)
+			}
+			if len(errs) != len(tt.problems) {
+				t.Fatalf("Expected %d problems, got %v", len(tt.problems), errs)
+			}
+			for i, problem := range tt.problems {
+				if !strings.Contains(errs[i].Error(), problem) {
+					t.Errorf("Expected problem containing %q, got %q", problem, errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckSiteContent(t *testing.T) {
+	checked, errs := Check(os.DirFS("../../content"))
+	for _, err := range errs {
+		t.Error(err)
+	}
+	if checked == 0 {
+		t.Error("Expected the site content to be checked")
+	}
+}
@@ -0,0 +1,59 @@
+package content
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+)
+
+// DateFormat is the layout of dates in frontmatter
+const DateFormat = "2006-01-02"
+
+// Frontmatter holds the "key: value" lines between the --- markers at the
+// top of a content file
+type Frontmatter map[string]string
+
+// Split separates a content file into its frontmatter and body. Lines
+// without a colon are ignored.
+func Split(data []byte) (Frontmatter, string, error) {
+	parts := bytes.SplitN(data, []byte("---"), 3)
+	if len(parts) < 3 {
+		return nil, "", errors.New("missing --- frontmatter")
+	}
+
+	fm := Frontmatter{}
+	scanner := bufio.NewScanner(bytes.NewReader(parts[1]))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok {
+			continue
+		}
+		fm[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return fm, string(parts[2]), nil
+}
+
+// Date parses a date in DateFormat; a missing or empty value is the zero
+// time
+func (f Frontmatter) Date(key string) (time.Time, error) {
+	if f[key] == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(DateFormat, f[key])
+}
+
+// List parses a list written as [a, b, c]
+func (f Frontmatter) List(key string) []string {
+	value := strings.Trim(f[key], "[]")
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		items = append(items, strings.TrimSpace(item))
+	}
+	return items
+}
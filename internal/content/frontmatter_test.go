@@ -0,0 +1,109 @@
+package content
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		expected    Frontmatter
+		body        string
+		expectError bool
+	}{
+		{
+			name:     "frontmatter and body",
+			data:     "---\ntitle: Hello: World\nslug: hello\n---\n\nBody\n",
+			expected: Frontmatter{"title": "Hello: World", "slug": "hello"},
+			body:     "\n\nBody\n",
+		},
+		{
+			name:     "lines without a colon are ignored",
+			data:     "---\ntitle: T\njust text\n---\n",
+			expected: Frontmatter{"title": "T"},
+			body:     "\n",
+		},
+		{
+			name:     "empty value",
+			data:     "---\nauthor:\n---\n",
+			expected: Frontmatter{"author": ""},
+			body:     "\n",
+		},
+		{name: "no frontmatter", data: "# Just markdown\n", expectError: true},
+		{name: "unterminated", data: "---\ntitle: T\n", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, body, err := Split([]byte(tt.data))
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(fm) != len(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, fm)
+			}
+			for key, value := range tt.expected {
+				if fm[key] != value {
+					t.Errorf("Expected %s %q, got %q", key, value, fm[key])
+				}
+			}
+			if body != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, body)
+			}
+		})
+	}
+}
+
+func TestFrontmatterDate(t *testing.T) {
+	fm := Frontmatter{"date": "2026-03-04", "bad": "04/03/2026", "empty": ""}
+
+	date, err := fm.Date("date")
+	if err != nil || !date.Equal(time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 2026-03-04, got %v, %v", date, err)
+	}
+	if _, err := fm.Date("bad"); err == nil {
+		t.Error("Expected an error for a malformed date")
+	}
+	for _, key := range []string{"empty", "missing"} {
+		if date, err := fm.Date(key); err != nil || !date.IsZero() {
+			t.Errorf("Expected zero time for %s, got %v, %v", key, date, err)
+		}
+	}
+}
+
+func TestFrontmatterList(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{"[Go, HTMX,  CSS ]", []string{"Go", "HTMX", "CSS"}},
+		{"[one]", []string{"one"}},
+		{"[]", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got := Frontmatter{"tags": tt.value}.List("tags")
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func BenchmarkSplit(b *testing.B) {
+	data := []byte("---\ntitle: Title\nslug: slug\ndate: 2026-01-01\ntags: [a, b, c]\n---\n\nBody text\n")
+	for i := 0; i < b.N; i++ {
+		Split(data)
+	}
+}
@@ -0,0 +1,111 @@
+package content
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/claykom/website/internal/middleware"
+)
+
+// maxSlugLength keeps generated slugs well under ValidateSlug's limit
+const maxSlugLength = 80
+
+const postTemplate = `---
+title: %s
+slug: %s
+author:
+date: %s
+tags: []
+excerpt:
+image:
+---
+
+Write the post here in Markdown.
+`
+
+const projectTemplate = `---
+title: %s
+slug: %s
+description:
+date: %s
+image:
+project_url:
+github_url:
+technologies: []
+featured: false
+---
+
+Describe the project here.
+`
+
+// Slugify turns a title into a slug: lowercase ASCII letters and digits
+// separated by single hyphens. Other characters, including non-ASCII
+// letters, separate words.
+func Slugify(title string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+		default:
+			hyphen = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	return slug
+}
+
+// NewPost writes a blog post skeleton dated date to blog/<slug>.md under
+// root, the content directory, and returns its path. slug defaults to the
+// slugified title.
+func NewPost(root, title, slug string, date time.Time) (string, error) {
+	return scaffold(root, BlogDir, postTemplate, title, slug, date)
+}
+
+// NewProject writes a portfolio project skeleton to portfolio/<slug>.md
+// under root and returns its path
+func NewProject(root, title, slug string, date time.Time) (string, error) {
+	return scaffold(root, PortfolioDir, projectTemplate, title, slug, date)
+}
+
+// scaffold fills in template and writes it to a new file; existing files
+// are never overwritten
+func scaffold(root, dir, template, title, slug string, date time.Time) (string, error) {
+	title = strings.TrimSpace(title)
+	if title == "" || strings.ContainsAny(title, "\r\n") {
+		return "", errors.New("title must be a single non-empty line")
+	}
+	if slug == "" {
+		slug = Slugify(title)
+	}
+	if !middleware.NewValidator().ValidateSlug(slug) {
+		return "", fmt.Errorf("invalid slug %q: use letters, digits, - and _, or pass -slug", slug)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(root, dir, slug+".md")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := fmt.Fprintf(f, template, title, slug, date.Format(DateFormat)); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
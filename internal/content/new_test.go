@@ -0,0 +1,107 @@
+package content
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		title    string
+		expected string
+	}{
+		{"Hello World", "hello-world"},
+		{"  Go 1.25: What's New?  ", "go-1-25-what-s-new"},
+		{"Café & Crème", "caf-cr-me"},
+		{"---", ""},
+		{strings.Repeat("word ", 30), strings.TrimRight(strings.Repeat("word-", 16), "-")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := Slugify(tt.title); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewPost(t *testing.T) {
+	root := t.TempDir()
+	date := time.Date(2026, 5, 6, 0, 0, 0, 0, time.UTC)
+
+	path, err := NewPost(root, "My First Post", "", date)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path != filepath.Join(root, BlogDir, "my-first-post.md") {
+		t.Errorf("Expected the post in the blog directory, got %s", path)
+	}
+
+	// The scaffold passes check as written
+	checked, errs := Check(os.DirFS(root))
+	if checked != 1 || len(errs) != 0 {
+		t.Errorf("Expected the new post to pass check, got %d checked, %v", checked, errs)
+	}
+	data, _ := os.ReadFile(path)
+	fm, _, _ := Split(data)
+	if fm["date"] != "2026-05-06" || fm["title"] != "My First Post" {
+		t.Errorf("Expected title and date in the frontmatter, got %v", fm)
+	}
+
+	if _, err := NewPost(root, "My First Post", "", date); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Expected an existing post not to be overwritten, got %v", err)
+	}
+}
+
+func TestNewProject(t *testing.T) {
+	root := t.TempDir()
+
+	path, err := NewProject(root, "Tool", "custom_slug", time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path != filepath.Join(root, PortfolioDir, "custom_slug.md") {
+		t.Errorf("Expected the project in the portfolio directory, got %s", path)
+	}
+	os.MkdirAll(filepath.Join(root, BlogDir), 0755)
+	if _, errs := Check(os.DirFS(root)); len(errs) != 0 {
+		t.Errorf("Expected the new project to pass check, got %v", errs)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		slug  string
+	}{
+		{"empty title", "  ", ""},
+		{"multi-line title", "one\ntwo", ""},
+		{"no slug from title", "!!!", ""},
+		{"invalid slug", "Title", "../escape"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			if _, err := NewPost(root, tt.title, tt.slug, time.Now()); err == nil {
+				t.Error("Expected an error")
+			}
+			if entries, _ := os.ReadDir(filepath.Join(root, BlogDir)); len(entries) != 0 {
+				t.Error("Expected no file to be written")
+			}
+		})
+	}
+}
+
+func BenchmarkSlugify(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Slugify("Building a Secure Go Website: Part 2")
+	}
+}
@@ -0,0 +1,87 @@
+package csp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+
+	"github.com/a-h/templ"
+)
+
+// NewNonce returns a random 128-bit nonce suitable for a 'nonce-...' source
+func NewNonce() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read never returns an error
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+type contextKey struct{}
+
+// requestPolicy is the policy being assembled for one response
+type requestPolicy struct {
+	mu     sync.Mutex
+	policy *Policy
+	nonce  string
+}
+
+// NewContext attaches the response's policy and nonce to ctx. The nonce is
+// also registered with templ, so components can read it with
+// templ.GetNonce and templ's own script elements carry it.
+func NewContext(ctx context.Context, policy *Policy, nonce string) context.Context {
+	ctx = context.WithValue(ctx, contextKey{}, &requestPolicy{policy: policy, nonce: nonce})
+	if nonce != "" {
+		ctx = templ.WithNonce(ctx, nonce)
+	}
+	return ctx
+}
+
+func fromContext(ctx context.Context) *requestPolicy {
+	rp, _ := ctx.Value(contextKey{}).(*requestPolicy)
+	return rp
+}
+
+// Nonce returns the nonce for inline <script> and <style> elements in the
+// current response, or "" outside the SecureHeaders middleware
+func Nonce(ctx context.Context) string {
+	if rp := fromContext(ctx); rp != nil {
+		return rp.nonce
+	}
+	return ""
+}
+
+// AddSources allows extra sources for one directive in the current
+// response, e.g. AddSources(ctx, "frame-src", "https://www.youtube-nocookie.com").
+// It must be called before the response headers are written.
+func AddSources(ctx context.Context, directive string, sources ...string) {
+	rp := fromContext(ctx)
+	if rp == nil {
+		return
+	}
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.policy.Add(directive, sources...)
+}
+
+// Extend adds every source of p to the current response's policy
+func Extend(ctx context.Context, p *Policy) {
+	rp := fromContext(ctx)
+	if rp == nil {
+		return
+	}
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.policy.Merge(p)
+}
+
+// Header renders the current response's policy, or "" if there is none
+func Header(ctx context.Context) string {
+	rp := fromContext(ctx)
+	if rp == nil {
+		return ""
+	}
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return rp.policy.String()
+}
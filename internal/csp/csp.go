@@ -0,0 +1,181 @@
+package csp
+
+import (
+	"slices"
+	"strings"
+)
+
+// Policy is a Content-Security-Policy built from individual directives, so
+// sources can be added without rewriting the whole header value
+type Policy struct {
+	directives []directive
+}
+
+type directive struct {
+	name    string
+	sources []string
+}
+
+// New returns an empty policy
+func New() *Policy {
+	return &Policy{}
+}
+
+// Default returns the site's baseline policy. Inline scripts and styles are
+// only allowed through a per-request nonce.
+func Default() *Policy {
+	return New().
+		Set("default-src", "'self'").
+		Set("script-src", "'self'").
+		Set("style-src", "'self'").
+		Set("img-src", "'self'", "data:", "https:").
+		Set("font-src", "'self'").
+		Set("connect-src", "'self'").
+		Set("media-src", "'self'").
+		Set("object-src", "'none'").
+		Set("child-src", "'none'").
+		Set("frame-src", "'none'").
+		Set("worker-src", "'none'").
+		Set("frame-ancestors", "'none'").
+		Set("form-action", "'self'").
+		Set("base-uri", "'self'").
+		Set("manifest-src", "'self'")
+}
+
+// Parse reads a policy in header syntax such as
+// "default-src 'self'; img-src 'self' https:". Later duplicates of a
+// directive are ignored, as browsers do.
+func Parse(s string) *Policy {
+	p := New()
+	for _, part := range strings.Split(s, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if p.index(name) >= 0 {
+			continue
+		}
+		p.Set(name, fields[1:]...)
+	}
+	return p
+}
+
+// Set replaces the sources of a directive
+func (p *Policy) Set(name string, sources ...string) *Policy {
+	sources = slices.Clone(sources)
+	if i := p.index(name); i >= 0 {
+		p.directives[i].sources = sources
+		return p
+	}
+	p.directives = append(p.directives, directive{name: name, sources: sources})
+	return p
+}
+
+// Add allows extra sources for a directive. A directive that isn't in the
+// policy yet starts from the one browsers would fall back to, so adding a
+// source never tightens what was already allowed. 'none' is dropped once
+// there is something to allow.
+func (p *Policy) Add(name string, sources ...string) *Policy {
+	if len(sources) == 0 {
+		return p
+	}
+
+	i := p.index(name)
+	if i < 0 {
+		p.Set(name, p.effective(name)...)
+		i = len(p.directives) - 1
+	}
+
+	d := &p.directives[i]
+	d.sources = slices.DeleteFunc(d.sources, func(s string) bool { return s == "'none'" })
+	for _, source := range sources {
+		if !slices.Contains(d.sources, source) {
+			d.sources = append(d.sources, source)
+		}
+	}
+	return p
+}
+
+// Merge adds every source of other to p
+func (p *Policy) Merge(other *Policy) *Policy {
+	if other == nil {
+		return p
+	}
+	for _, d := range other.directives {
+		if len(d.sources) == 0 {
+			// Valueless directives such as upgrade-insecure-requests
+			if p.index(d.name) < 0 {
+				p.Set(d.name)
+			}
+			continue
+		}
+		p.Add(d.name, d.sources...)
+	}
+	return p
+}
+
+// Sources returns the sources listed for a directive
+func (p *Policy) Sources(name string) []string {
+	if i := p.index(name); i >= 0 {
+		return slices.Clone(p.directives[i].sources)
+	}
+	return nil
+}
+
+// Clone returns a deep copy, so a shared base policy can be extended per
+// request
+func (p *Policy) Clone() *Policy {
+	c := &Policy{directives: make([]directive, len(p.directives))}
+	for i, d := range p.directives {
+		c.directives[i] = directive{name: d.name, sources: slices.Clone(d.sources)}
+	}
+	return c
+}
+
+// String renders the policy as a header value
+func (p *Policy) String() string {
+	var b strings.Builder
+	for i, d := range p.directives {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(d.name)
+		for _, source := range d.sources {
+			b.WriteByte(' ')
+			b.WriteString(source)
+		}
+	}
+	return b.String()
+}
+
+func (p *Policy) index(name string) int {
+	return slices.IndexFunc(p.directives, func(d directive) bool { return d.name == name })
+}
+
+// effective returns the sources that currently apply to a directive,
+// following the CSP fallback chain when it isn't set explicitly
+func (p *Policy) effective(name string) []string {
+	for _, candidate := range fallbacks(name) {
+		if i := p.index(candidate); i >= 0 {
+			return slices.Clone(p.directives[i].sources)
+		}
+	}
+	return nil
+}
+
+// fallbacks lists the directives consulted, in order, when name is absent
+func fallbacks(name string) []string {
+	switch name {
+	case "script-src-elem", "script-src-attr":
+		return []string{"script-src", "default-src"}
+	case "style-src-elem", "style-src-attr":
+		return []string{"style-src", "default-src"}
+	case "frame-src", "worker-src":
+		return []string{"child-src", "default-src"}
+	}
+	if strings.HasSuffix(name, "-src") && name != "default-src" {
+		return []string{"default-src"}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package csp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := Default().String()
+
+	if strings.Contains(policy, "unsafe-inline") {
+		t.Errorf("Default policy must not allow unsafe-inline: %s", policy)
+	}
+	if !strings.HasPrefix(policy, "default-src 'self'; script-src 'self'; style-src 'self'") {
+		t.Errorf("Unexpected default policy: %s", policy)
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"default-src 'self'", "default-src 'self'"},
+		{"default-src 'self';  img-src 'self' https: ;", "default-src 'self'; img-src 'self' https:"},
+		{"DEFAULT-SRC 'none'; upgrade-insecure-requests", "default-src 'none'; upgrade-insecure-requests"},
+		{"script-src 'self'; script-src https://evil.example", "script-src 'self'"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Parse(tt.input).String(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      string
+		directive string
+		sources   []string
+		expected  string
+	}{
+		{
+			name:      "existing directive",
+			base:      "default-src 'self'; img-src 'self'",
+			directive: "img-src",
+			sources:   []string{"https://cdn.example.com"},
+			expected:  "default-src 'self'; img-src 'self' https://cdn.example.com",
+		},
+		{
+			name:      "duplicates are skipped",
+			base:      "img-src 'self'",
+			directive: "img-src",
+			sources:   []string{"'self'"},
+			expected:  "img-src 'self'",
+		},
+		{
+			name:      "none is replaced",
+			base:      "frame-src 'none'",
+			directive: "frame-src",
+			sources:   []string{"https://www.youtube-nocookie.com"},
+			expected:  "frame-src https://www.youtube-nocookie.com",
+		},
+		{
+			name:      "missing directive starts from default-src",
+			base:      "default-src 'self'",
+			directive: "media-src",
+			sources:   []string{"https://media.example.com"},
+			expected:  "default-src 'self'; media-src 'self' https://media.example.com",
+		},
+		{
+			name:      "missing frame-src starts from child-src",
+			base:      "default-src 'self'; child-src 'none'",
+			directive: "frame-src",
+			sources:   []string{"https://player.example.com"},
+			expected:  "default-src 'self'; child-src 'none'; frame-src https://player.example.com",
+		},
+		{
+			name:      "non-fetch directive has no fallback",
+			base:      "default-src 'self'",
+			directive: "form-action",
+			sources:   []string{"https://forms.example.com"},
+			expected:  "default-src 'self'; form-action https://forms.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.base).Add(tt.directive, tt.sources...).String()
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := Parse("default-src 'self'; frame-src 'none'")
+	base.Merge(Parse("frame-src https://a.example; img-src https://b.example; upgrade-insecure-requests"))
+
+	expected := "default-src 'self'; frame-src https://a.example; img-src 'self' https://b.example; upgrade-insecure-requests"
+	if got := base.String(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	base := Default()
+	clone := base.Clone()
+	clone.Add("img-src", "https://cdn.example.com")
+
+	if strings.Contains(base.String(), "cdn.example.com") {
+		t.Error("Modifying a clone changed the original policy")
+	}
+}
+
+func TestNewNonce(t *testing.T) {
+	a, b := NewNonce(), NewNonce()
+	if a == b {
+		t.Error("Expected nonces to differ")
+	}
+	if len(a) != 24 {
+		t.Errorf("Expected 24-character base64 nonce, got %q", a)
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := NewContext(context.Background(), Default(), "abc123")
+
+	if got := Nonce(ctx); got != "abc123" {
+		t.Errorf("Expected nonce abc123, got %q", got)
+	}
+	if got := templ.GetNonce(ctx); got != "abc123" {
+		t.Errorf("Expected templ nonce abc123, got %q", got)
+	}
+
+	AddSources(ctx, "frame-src", "https://www.youtube-nocookie.com")
+	Extend(ctx, Parse("img-src https://i.ytimg.com"))
+
+	header := Header(ctx)
+	if !strings.Contains(header, "frame-src https://www.youtube-nocookie.com") {
+		t.Errorf("Expected frame-src source in %q", header)
+	}
+	if !strings.Contains(header, "img-src 'self' data: https: https://i.ytimg.com") {
+		t.Errorf("Expected img-src source in %q", header)
+	}
+}
+
+func TestContextWithoutPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	// Must be safe to call outside the middleware
+	AddSources(ctx, "img-src", "https://cdn.example.com")
+	Extend(ctx, Default())
+
+	if Nonce(ctx) != "" || Header(ctx) != "" {
+		t.Error("Expected empty nonce and header without a policy")
+	}
+}
+
+func BenchmarkPolicyString(b *testing.B) {
+	base := Default()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p := base.Clone()
+		p.Add("script-src", "'nonce-abc'")
+		_ = p.String()
+	}
+}
@@ -0,0 +1,174 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/claykom/website/internal/assets"
+)
+
+// NotFoundPage is the file the not-found page is written to, which most
+// object storage hosts can be pointed at as the error document
+const NotFoundPage = "404.html"
+
+// notFoundPath is requested to render the not-found page
+const notFoundPath = "/export-not-found"
+
+// hrefPattern matches site-relative links, up to any query or fragment
+var hrefPattern = regexp.MustCompile(`href="(/[^"?#]*)`)
+
+// Options says what to export and where
+type Options struct {
+	// Out is the directory to write to. It must be empty or not exist, so
+	// pages that were removed from the site don't linger.
+	Out string
+	// Pages are the paths of the HTML pages to render
+	Pages []string
+	// Files are paths written under their own name, such as /robots.txt
+	Files []string
+	// Static holds the files served under /static/, and Manifest their
+	// fingerprinted names
+	Static   fs.FS
+	Manifest *assets.Manifest
+}
+
+// Result counts what was written
+type Result struct {
+	Pages int
+	// Files counts Options.Files and static files
+	Files int
+	// Skipped lists static files the handler refused to serve, which the
+	// live site doesn't serve either
+	Skipped []string
+}
+
+// Site renders every page through handler into Out as a directory index,
+// so /blog becomes blog/index.html, and copies Files and the static files.
+// Links to the pages are rewritten to end in a slash so object storage
+// serves the index without a redirect. A page or file that doesn't answer
+// 200 is an error; the others are still written.
+func Site(handler http.Handler, opts Options) (Result, error) {
+	var result Result
+	if err := checkEmpty(opts.Out); err != nil {
+		return result, err
+	}
+
+	directories := map[string]bool{}
+	for _, page := range opts.Pages {
+		if page != "/" {
+			directories[page] = true
+		}
+	}
+	rewrite := func(body []byte) []byte {
+		return hrefPattern.ReplaceAllFunc(body, func(match []byte) []byte {
+			link := string(match[len(`href="`):])
+			if directories[link] {
+				return []byte(`href="` + link + "/")
+			}
+			return match
+		})
+	}
+
+	var errs []error
+	for _, page := range opts.Pages {
+		status, body := get(handler, page)
+		if status != http.StatusOK {
+			errs = append(errs, fmt.Errorf("%s: status %d", page, status))
+			continue
+		}
+		if err := write(opts.Out, path.Join(page, "index.html"), rewrite(body)); err != nil {
+			return result, err
+		}
+		result.Pages++
+	}
+
+	for _, file := range opts.Files {
+		status, body := get(handler, file)
+		if status != http.StatusOK {
+			errs = append(errs, fmt.Errorf("%s: status %d", file, status))
+			continue
+		}
+		if err := write(opts.Out, file, body); err != nil {
+			return result, err
+		}
+		result.Files++
+	}
+
+	status, body := get(handler, notFoundPath)
+	if status != http.StatusNotFound {
+		errs = append(errs, fmt.Errorf("not found page: status %d", status))
+	} else if err := write(opts.Out, NotFoundPage, rewrite(body)); err != nil {
+		return result, err
+	}
+
+	err := fs.WalkDir(opts.Static, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || assets.IsPrecompressed(name) {
+			return err
+		}
+
+		names := []string{name}
+		if hashed, ok := opts.Manifest.Hashed(name); ok {
+			names = append(names, hashed)
+		}
+		for _, name := range names {
+			url := assets.URLPrefix + name
+			status, body := get(handler, url)
+			if status != http.StatusOK {
+				result.Skipped = append(result.Skipped, url)
+				continue
+			}
+			if err := write(opts.Out, url, body); err != nil {
+				return err
+			}
+			result.Files++
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// checkEmpty fails unless dir is empty or doesn't exist
+func checkEmpty(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("%s is not empty; remove it or choose another directory", dir)
+	}
+	return nil
+}
+
+// get requests urlPath from handler as a plain GET from a browser would,
+// without conditional or compression headers
+func get(handler http.Handler, urlPath string) (int, []byte) {
+	req := httptest.NewRequest(http.MethodGet, urlPath, nil)
+	req.Header.Set("Accept", "text/html,*/*")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr.Code, rr.Body.Bytes()
+}
+
+// write writes data to the URL path name under out
+func write(out, name string, data []byte) error {
+	file := filepath.Join(out, filepath.FromSlash(strings.TrimPrefix(name, "/")))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
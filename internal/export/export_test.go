@@ -0,0 +1,160 @@
+package export
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/claykom/website/internal/assets"
+)
+
+// testHandler serves a small site: two pages linking to each other, a
+// broken page and static files with the .txt extension refused
+func testHandler(static fstest.MapFS, manifest *assets.Manifest) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<a href="/blog">Blog</a> <a href="/blog#top">Top</a> <a href="/">Home</a> <a href="/blogroll">Other</a>`))
+	})
+	mux.HandleFunc("/blog", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<a href="/blog/post?ref=list">Post</a>`))
+	})
+	mux.HandleFunc("/blog/post", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("post"))
+	})
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\n"))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/static/", func(w http.ResponseWriter, r *http.Request) {
+		name, _ := manifest.Resolve(strings.TrimPrefix(r.URL.Path, "/static/"))
+		if strings.HasSuffix(name, ".txt") {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		http.ServeFileFS(w, r, static, name)
+	})
+	return mux
+}
+
+func testStatic(t *testing.T) (fstest.MapFS, *assets.Manifest) {
+	t.Helper()
+	static := fstest.MapFS{
+		"css/style.css":    {Data: []byte("body {}")},
+		"css/style.css.gz": {Data: []byte("gzipped")},
+		"notes.txt":        {Data: []byte("private")},
+	}
+	manifest, err := assets.NewManifest(static)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return static, manifest
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("Expected %s to be written: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestSite(t *testing.T) {
+	static, manifest := testStatic(t)
+	out := filepath.Join(t.TempDir(), "dist")
+
+	result, err := Site(testHandler(static, manifest), Options{
+		Out:      out,
+		Pages:    []string{"/", "/blog", "/blog/post"},
+		Files:    []string{"/robots.txt"},
+		Static:   static,
+		Manifest: manifest,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Pages != 3 || result.Files != 3 {
+		t.Errorf("Expected 3 pages and 3 files, got %+v", result)
+	}
+	if len(result.Skipped) != 2 || result.Skipped[0] != "/static/notes.txt" {
+		t.Errorf("Expected notes.txt to be skipped under both names, got %v", result.Skipped)
+	}
+
+	home := readFile(t, out, "index.html")
+	expected := `<a href="/blog/">Blog</a> <a href="/blog/#top">Top</a> <a href="/">Home</a> <a href="/blogroll">Other</a>`
+	if home != expected {
+		t.Errorf("Expected links to pages to be rewritten:\n%s\ngot\n%s", expected, home)
+	}
+	if list := readFile(t, out, "blog/index.html"); list != `<a href="/blog/post/?ref=list">Post</a>` {
+		t.Errorf("Expected the query to follow the rewritten link, got %s", list)
+	}
+	readFile(t, out, "blog/post/index.html")
+	if robots := readFile(t, out, "robots.txt"); robots != "User-agent: *\n" {
+		t.Errorf("Expected robots.txt to be written as is, got %q", robots)
+	}
+	readFile(t, out, NotFoundPage)
+
+	hashed, _ := manifest.Hashed("css/style.css")
+	for _, name := range []string{"static/css/style.css", "static/" + hashed} {
+		if css := readFile(t, out, name); css != "body {}" {
+			t.Errorf("Expected %s to hold the stylesheet, got %q", name, css)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "static/css/style.css.gz")); err == nil {
+		t.Error("Expected precompressed files not to be exported")
+	}
+}
+
+func TestSiteReportsFailedPages(t *testing.T) {
+	static, manifest := testStatic(t)
+	out := t.TempDir()
+
+	result, err := Site(testHandler(static, manifest), Options{
+		Out:      out,
+		Pages:    []string{"/broken", "/blog", "/missing"},
+		Files:    []string{"/missing.txt"},
+		Static:   static,
+		Manifest: manifest,
+	})
+	if err == nil {
+		t.Fatal("Expected an error for pages that didn't answer 200")
+	}
+	for _, expected := range []string{"/broken: status 500", "/missing: status 404", "/missing.txt: status 404"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to contain %q, got %v", expected, err)
+		}
+	}
+	if result.Pages != 1 {
+		t.Errorf("Expected the working page to be written, got %d pages", result.Pages)
+	}
+}
+
+func TestSiteRefusesNonEmptyOut(t *testing.T) {
+	static, manifest := testStatic(t)
+	out := t.TempDir()
+	if err := os.WriteFile(filepath.Join(out, "old.html"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Site(testHandler(static, manifest), Options{Out: out, Pages: []string{"/"}, Static: static, Manifest: manifest})
+	if err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("Expected an error for a non-empty directory, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "index.html")); err == nil {
+		t.Error("Expected nothing to be written")
+	}
+}
+
+func BenchmarkSite(b *testing.B) {
+	static := fstest.MapFS{}
+	manifest, _ := assets.NewManifest(static)
+	handler := testHandler(static, manifest)
+	for i := 0; i < b.N; i++ {
+		Site(handler, Options{Out: b.TempDir(), Pages: []string{"/", "/blog"}, Static: static, Manifest: manifest})
+	}
+}
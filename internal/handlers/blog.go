@@ -1,17 +1,22 @@
 package handlers
 
 import (
-	"bufio"
-	"bytes"
+	"context"
+	"errors"
+	"io/fs"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/claykom/website/internal/content"
+	"github.com/claykom/website/internal/csp"
+	"github.com/claykom/website/internal/metrics"
 	"github.com/claykom/website/internal/models"
+	"github.com/claykom/website/internal/tracing"
 	"github.com/claykom/website/internal/views/pages"
 	"github.com/gomarkdown/markdown"
 	"github.com/gomarkdown/markdown/html"
@@ -19,120 +24,163 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// contentLoads counts content (re)loads by content type and outcome
+var contentLoads = metrics.DefaultRegistry.NewCounterVec(
+	"website_content_loads_total",
+	"Content loads by content type and result.",
+	"content", "result",
+)
+
 // BlogHandler handles blog-related requests
 type BlogHandler struct {
-	posts []models.BlogPost
+	content fs.FS
+
+	mu     sync.RWMutex
+	posts  []models.BlogPost
+	loaded bool
+	// digests holds each post's content digest and, under "", the list
+	// page's, so conditional requests don't hash content every time
+	digests map[string]string
 }
 
-// NewBlogHandler creates a new BlogHandler and loads markdown posts
-func NewBlogHandler() *BlogHandler {
+// NewBlogHandler creates a new BlogHandler and loads markdown posts from the
+// blog directory of content, which may be on disk or embedded
+func NewBlogHandler(content fs.FS) *BlogHandler {
 	handler := &BlogHandler{
-		posts: []models.BlogPost{},
+		content: content,
+		posts:   []models.BlogPost{},
 	}
 
 	// Load posts from markdown files
-	if err := handler.loadMarkdownPosts(); err != nil {
+	if err := handler.Reload(); err != nil {
 		log.Printf("Error loading markdown posts: %v", err)
 	}
 
 	return handler
 }
 
-// loadMarkdownPosts reads all markdown files from content/blog directory
-func (h *BlogHandler) loadMarkdownPosts() error {
-	blogDir := "content/blog"
+// Reload re-reads the posts and drops cached pages. If the blog directory
+// can't be read, the previously loaded posts are kept.
+func (h *BlogHandler) Reload() error {
+	if err := h.loadMarkdownPosts(h.content); err != nil {
+		contentLoads.WithLabelValues("blog", "error").Inc()
+		return err
+	}
+	contentLoads.WithLabelValues("blog", "success").Inc()
+	defaultPageCache.Load().Purge()
+	return nil
+}
+
+// CheckLoaded is a health check that fails until posts have been loaded
+// successfully at least once
+func (h *BlogHandler) CheckLoaded(ctx context.Context) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if !h.loaded {
+		return errors.New("blog posts not loaded")
+	}
+	return nil
+}
 
-	files, err := os.ReadDir(blogDir)
+// loadMarkdownPosts reads all markdown files from the blog directory
+func (h *BlogHandler) loadMarkdownPosts(content fs.FS) error {
+	blogDir := "blog"
+
+	ctx, span := tracing.Start(context.Background(), "content.load",
+		tracing.WithAttributes(tracing.String("content.dir", blogDir)),
+	)
+	defer span.End()
+
+	files, err := fs.ReadDir(content, blogDir)
 	if err != nil {
+		span.RecordError(err)
 		return err
 	}
 
+	posts := []models.BlogPost{}
 	for _, file := range files {
 		if file.IsDir() || !strings.HasSuffix(file.Name(), ".md") {
 			continue
 		}
 
-		filePath := filepath.Join(blogDir, file.Name())
-		post, err := h.parseMarkdownFile(filePath)
+		filePath := path.Join(blogDir, file.Name())
+		post, err := h.parseMarkdownFile(ctx, content, filePath)
 		if err != nil {
 			log.Printf("Error parsing %s: %v", filePath, err)
 			continue
 		}
 
-		h.posts = append(h.posts, post)
+		posts = append(posts, post)
 	}
 
 	// Sort posts by date (newest first)
-	sort.Slice(h.posts, func(i, j int) bool {
-		return h.posts[i].PublishedAt.After(h.posts[j].PublishedAt)
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].PublishedAt.After(posts[j].PublishedAt)
 	})
 
+	digests := make(map[string]string, len(posts)+1)
+	published := make([]models.BlogPost, 0, len(posts))
+	for _, post := range posts {
+		if post.Published {
+			digests[post.Slug] = postDigest(post)
+			published = append(published, post)
+		}
+	}
+	digests[""] = listDigest(published)
+
+	h.mu.Lock()
+	h.posts = posts
+	h.digests = digests
+	h.loaded = true
+	h.mu.Unlock()
+
 	return nil
 }
 
 // parseMarkdownFile parses a markdown file with frontmatter
-func (h *BlogHandler) parseMarkdownFile(filePath string) (models.BlogPost, error) {
-	content, err := os.ReadFile(filePath)
+func (h *BlogHandler) parseMarkdownFile(ctx context.Context, fsys fs.FS, filePath string) (models.BlogPost, error) {
+	data, err := fs.ReadFile(fsys, filePath)
 	if err != nil {
 		return models.BlogPost{}, err
 	}
 
-	// Split frontmatter and content
-	parts := bytes.SplitN(content, []byte("---"), 3)
-	if len(parts) < 3 {
+	fm, markdownContent, err := content.Split(data)
+	if err != nil {
 		return models.BlogPost{}, err
 	}
 
-	frontmatter := string(parts[1])
-	markdownContent := string(parts[2])
-
-	// Parse frontmatter
+	// Invalid dates are left zero; the check command reports them
 	post := models.BlogPost{
+		ID:        fm["slug"],
+		Title:     fm["title"],
+		Slug:      fm["slug"],
+		Author:    fm["author"],
+		Excerpt:   fm["excerpt"],
+		ImageURL:  fm["image"],
+		CSP:       fm["csp"],
+		Tags:      fm.List("tags"),
 		Published: true,
-		UpdatedAt: time.Now(),
 	}
-
-	scanner := bufio.NewScanner(strings.NewReader(frontmatter))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		switch key {
-		case "title":
-			post.Title = value
-		case "slug":
-			post.Slug = value
-			post.ID = value
-		case "author":
-			post.Author = value
-		case "date":
-			if t, err := time.Parse("2006-01-02", value); err == nil {
-				post.PublishedAt = t
-			}
-		case "excerpt":
-			post.Excerpt = value
-		case "tags":
-			// Parse tags: [go, programming, tutorial]
-			value = strings.Trim(value, "[]")
-			tags := strings.Split(value, ",")
-			for _, tag := range tags {
-				post.Tags = append(post.Tags, strings.TrimSpace(tag))
-			}
+	post.PublishedAt, _ = fm.Date("date")
+	post.UpdatedAt, _ = fm.Date("updated")
+
+	// Without an explicit updated date, fall back to the file's modification
+	// time (zero for embedded files) and then the publish date
+	if post.UpdatedAt.IsZero() {
+		if info, err := fs.Stat(fsys, filePath); err == nil {
+			post.UpdatedAt = info.ModTime()
 		}
 	}
+	if post.UpdatedAt.IsZero() {
+		post.UpdatedAt = post.PublishedAt
+	}
 
 	// Convert markdown to HTML
+	_, span := tracing.Start(ctx, "markdown.render",
+		tracing.WithAttributes(tracing.String("content.file", filePath)),
+	)
 	post.Content = h.markdownToHTML(markdownContent)
+	span.End()
 
 	return post, nil
 }
@@ -152,21 +200,77 @@ func (h *BlogHandler) markdownToHTML(md string) string {
 	return string(htmlBytes)
 }
 
-// ListPosts returns all published blog posts
-func (h *BlogHandler) ListPosts(w http.ResponseWriter, r *http.Request) {
-	// Filter only published posts
-	publishedPosts := make([]models.BlogPost, 0)
+// publishedPosts returns the published posts, newest first
+func (h *BlogHandler) publishedPosts() []models.BlogPost {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	published := make([]models.BlogPost, 0, len(h.posts))
 	for _, post := range h.posts {
 		if post.Published {
-			publishedPosts = append(publishedPosts, post)
+			published = append(published, post)
 		}
 	}
+	return published
+}
+
+// findPost returns the published post with slug
+func (h *BlogHandler) findPost(slug string) (models.BlogPost, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, post := range h.posts {
+		if post.Slug == slug && post.Published {
+			return post, true
+		}
+	}
+	return models.BlogPost{}, false
+}
 
-	component := pages.BlogList(publishedPosts)
-	if err := component.Render(r.Context(), w); err != nil {
-		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+// etag returns the ETag of the page for slug, or "" for the list page,
+// from the digest computed at load time, falling back to hashing the
+// content
+func (h *BlogHandler) etag(r *http.Request, slug string, compute func() string) string {
+	h.mu.RLock()
+	digest, ok := h.digests[slug]
+	h.mu.RUnlock()
+	if !ok {
+		digest = compute()
+	}
+	return contentETag(r, digest)
+}
+
+// postDigest identifies the content of a post page. CSP is not part of
+// the JSON encoding but changes the page.
+func postDigest(post models.BlogPost) string {
+	return contentDigest("BlogPost", post, post.CSP)
+}
+
+// listDigest identifies the content of the post list
+func listDigest(published []models.BlogPost) string {
+	return contentDigest("BlogList", published)
+}
+
+// ListPosts returns all published blog posts
+func (h *BlogHandler) ListPosts(w http.ResponseWriter, r *http.Request) {
+	publishedPosts := h.publishedPosts()
+
+	etag := h.etag(r, "", func() string { return listDigest(publishedPosts) })
+	if checkNotModified(w, r, etag, lastPostUpdate(publishedPosts)) {
 		return
 	}
+	render(w, r, "BlogList", pages.BlogList(publishedPosts, blogListMeta(r)))
+}
+
+// lastPostUpdate returns the most recent UpdatedAt among posts
+func lastPostUpdate(posts []models.BlogPost) time.Time {
+	var latest time.Time
+	for _, post := range posts {
+		if post.UpdatedAt.After(latest) {
+			latest = post.UpdatedAt
+		}
+	}
+	return latest
 }
 
 // GetPost returns a single blog post by slug
@@ -179,17 +283,48 @@ func (h *BlogHandler) GetPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Find post by slug
-	for _, post := range h.posts {
-		if post.Slug == slug && post.Published {
-			component := pages.BlogPost(post)
-			if err := component.Render(r.Context(), w); err != nil {
-				http.Error(w, "Error rendering page", http.StatusInternalServerError)
-				return
-			}
-			return
-		}
+	post, ok := h.findPost(slug)
+	if !ok {
+		http.Error(w, "Blog post not found", http.StatusNotFound)
+		return
+	}
+
+	etag := h.etag(r, post.Slug, func() string { return postDigest(post) })
+	if checkNotModified(w, r, etag, post.UpdatedAt) {
+		return
+	}
+	// Allow whatever the post embeds on top of the site policy
+	if post.CSP != "" {
+		csp.Extend(r.Context(), csp.Parse(post.CSP))
+	}
+	render(w, r, "BlogPost", pages.BlogPost(post, postMeta(r, post)))
+}
+
+// ListPostsAPI returns all published blog posts as JSON
+func (h *BlogHandler) ListPostsAPI(w http.ResponseWriter, r *http.Request) {
+	publishedPosts := h.publishedPosts()
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"posts": publishedPosts,
+		"count": len(publishedPosts),
+	})
+}
+
+// GetPostAPI returns a single blog post by slug as JSON
+func (h *BlogHandler) GetPostAPI(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	slug := vars["slug"]
+
+	if slug == "" {
+		respondWithError(w, http.StatusBadRequest, "Slug parameter is required")
+		return
+	}
+
+	post, ok := h.findPost(slug)
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Blog post not found")
+		return
 	}
 
-	http.Error(w, "Blog post not found", http.StatusNotFound)
+	respondWithJSON(w, http.StatusOK, post)
 }
@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
+	"github.com/claykom/website/internal/csp"
 	"github.com/claykom/website/internal/models"
 	"github.com/claykom/website/internal/testutils"
 	"github.com/gorilla/mux"
@@ -46,27 +49,9 @@ This is a test blog post content.
 		t.Fatalf("Failed to write test file: %v", err)
 	}
 
-	// Save original working directory
-	originalWd, _ := os.Getwd()
-	defer os.Chdir(originalWd)
-
-	// Change to temp directory so loadMarkdownPosts can find content/blog
-	if err := os.Chdir(tempDir); err != nil {
-		t.Fatalf("Failed to change directory: %v", err)
-	}
-
-	// Rename blog dir to content/blog structure
-	contentDir := filepath.Join(tempDir, "content")
-	if err := os.MkdirAll(contentDir, 0755); err != nil {
-		t.Fatalf("Failed to create content dir: %v", err)
-	}
-	if err := os.Rename(blogDir, filepath.Join(contentDir, "blog")); err != nil {
-		t.Fatalf("Failed to rename blog dir: %v", err)
-	}
-
 	// Test loading posts
 	handler := &BlogHandler{}
-	err = handler.loadMarkdownPosts()
+	err = handler.loadMarkdownPosts(os.DirFS(tempDir))
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -190,7 +175,7 @@ func TestBlogHandler_GetPost(t *testing.T) {
 }
 
 func TestPortfolioHandler_ListProjects(t *testing.T) {
-	handler := NewPortfolioHandler()
+	handler := NewPortfolioHandler(os.DirFS("../../content"))
 
 	req := testutils.NewTestRequest("GET", "/portfolio", "")
 	rr := testutils.NewTestResponseRecorder()
@@ -215,7 +200,7 @@ func TestPortfolioHandler_ListProjects(t *testing.T) {
 }
 
 func TestPortfolioHandler_GetProject(t *testing.T) {
-	handler := NewPortfolioHandler()
+	handler := NewPortfolioHandler(os.DirFS("../../content"))
 
 	tests := []struct {
 		name           string
@@ -263,7 +248,7 @@ func TestPortfolioHandler_GetProject(t *testing.T) {
 func TestNewBlogHandler(t *testing.T) {
 	// This test mainly ensures NewBlogHandler doesn't panic
 	// and handles missing blog directory gracefully
-	handler := NewBlogHandler()
+	handler := NewBlogHandler(fstest.MapFS{})
 
 	if handler == nil {
 		t.Error("Expected handler to be created")
@@ -275,8 +260,42 @@ func TestNewBlogHandler(t *testing.T) {
 	}
 }
 
+func TestNewBlogHandlerEmbedded(t *testing.T) {
+	// Content may come from an embed.FS rather than the working directory
+	content := fstest.MapFS{
+		"blog/hello.md":  {Data: []byte("---\ntitle: Hello\nslug: hello\ndate: 2024-02-01\n---\n\n# Hello\n")},
+		"blog/notes.txt": {Data: []byte("not markdown")},
+	}
+
+	handler := NewBlogHandler(content)
+	if len(handler.posts) != 1 {
+		t.Fatalf("Expected 1 post, got %d", len(handler.posts))
+	}
+	if handler.posts[0].Slug != "hello" {
+		t.Errorf("Expected slug 'hello', got '%s'", handler.posts[0].Slug)
+	}
+}
+
+func TestBlogHandler_GetPostCSP(t *testing.T) {
+	content := fstest.MapFS{
+		"blog/video.md": {Data: []byte("---\ntitle: Video\nslug: video\ndate: 2024-03-01\ncsp: frame-src https://www.youtube-nocookie.com\n---\n\nWatch this.\n")},
+	}
+	handler := NewBlogHandler(content)
+
+	ctx := csp.NewContext(context.Background(), csp.Default(), "n0nce")
+	req := testutils.NewTestRequest("GET", "/blog/video", "").WithContext(ctx)
+	req = mux.SetURLVars(req, map[string]string{"slug": "video"})
+	rr := testutils.NewTestResponseRecorder()
+	handler.GetPost(rr, req)
+
+	rr.AssertStatusCode(t, http.StatusOK)
+	if policy := csp.Header(ctx); !strings.Contains(policy, "frame-src https://www.youtube-nocookie.com") {
+		t.Errorf("Expected post's frame-src in CSP, got %q", policy)
+	}
+}
+
 func TestNewPortfolioHandler(t *testing.T) {
-	handler := NewPortfolioHandler()
+	handler := NewPortfolioHandler(os.DirFS("../../content"))
 
 	if handler == nil {
 		t.Error("Expected handler to be created")
@@ -316,7 +335,7 @@ func TestMuxURLVars(t *testing.T) {
 
 // Benchmark tests
 func BenchmarkBlogHandler_ListPosts(b *testing.B) {
-	handler := NewBlogHandler()
+	handler := NewBlogHandler(os.DirFS("../../content"))
 	req := testutils.NewTestRequest("GET", "/blog", "")
 
 	b.ResetTimer()
@@ -327,7 +346,7 @@ func BenchmarkBlogHandler_ListPosts(b *testing.B) {
 }
 
 func BenchmarkPortfolioHandler_ListProjects(b *testing.B) {
-	handler := NewPortfolioHandler()
+	handler := NewPortfolioHandler(os.DirFS("../../content"))
 	req := testutils.NewTestRequest("GET", "/portfolio", "")
 
 	b.ResetTimer()
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/claykom/website/internal/buildinfo"
+)
+
+// htmlCacheControl lets browsers keep rendered pages but revalidate them on
+// every use. "private" keeps shared caches from handing one visitor's CSP
+// nonce to everyone else.
+const htmlCacheControl = "private, no-cache"
+
+// etagSeed ties ETags to the templates compiled into this binary, so a
+// deploy that changes markup invalidates pages whose content did not change
+var etagSeed = buildSeed()
+
+// buildSeed identifies the build by its VCS revision, falling back to the
+// process start time for modified or untracked builds
+func buildSeed() string {
+	if info := buildinfo.Get(); info.Commit != "" && !info.Dirty {
+		return info.Commit
+	}
+	return strconv.FormatInt(startTime.UnixNano(), 36)
+}
+
+// contentETag returns a strong ETag for the page rendered from values for
+// r. Pages link to themselves by absolute URL, so the ETag also covers the
+// site URL settings and, without a base URL, the request's scheme and
+// host; changing them on reload invalidates copies clients hold.
+func contentETag(r *http.Request, values ...any) string {
+	urls := currentSiteURLs()
+	site := urls.BaseURL
+	if site == "" {
+		site = siteURL(r, "")
+	}
+	return `"` + contentDigest(append([]any{site, urls.AddSlash}, values...)...) + `"`
+}
+
+// contentDigest hashes values, so content can be hashed once and passed
+// to contentETag
+func contentDigest(values ...any) string {
+	h := sha256.New()
+	h.Write([]byte(etagSeed))
+	enc := json.NewEncoder(h)
+	for _, v := range values {
+		// Content models always marshal; an error would only weaken caching
+		_ = enc.Encode(v)
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:18])
+}
+
+// checkNotModified sets the validators and Cache-Control for a rendered page
+// and answers 304 Not Modified when the client's copy is current. Callers
+// skip rendering when it returns true.
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	h := w.Header()
+	h.Set("Cache-Control", htmlCacheControl)
+	h.Set("ETag", etag)
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	if !lastModified.IsZero() {
+		h.Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	// If-None-Match takes precedence; If-Modified-Since is only consulted
+	// when it is absent (RFC 9110 section 13.2.2)
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if !etagMatches(inm, etag) {
+			return false
+		}
+	} else {
+		ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if err != nil || lastModified.IsZero() || lastModified.After(ims) {
+			return false
+		}
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches compares an If-None-Match list against etag using the weak
+// comparison, so a W/ tag added by response compression still matches
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
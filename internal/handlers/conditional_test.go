@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/claykom/website/internal/models"
+	"github.com/claykom/website/internal/testutils"
+	"github.com/gorilla/mux"
+)
+
+func TestCheckNotModified(t *testing.T) {
+	modified := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	etag := `"abc"`
+
+	tests := []struct {
+		name           string
+		method         string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{"no validators", "GET", nil, http.StatusOK},
+		{"matching etag", "GET", map[string]string{"If-None-Match": `"abc"`}, http.StatusNotModified},
+		{"matching weak etag", "GET", map[string]string{"If-None-Match": `W/"abc"`}, http.StatusNotModified},
+		{"etag in list", "GET", map[string]string{"If-None-Match": `"old", "abc"`}, http.StatusNotModified},
+		{"wildcard etag", "GET", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"stale etag", "GET", map[string]string{"If-None-Match": `"old"`}, http.StatusOK},
+		{"head with matching etag", "HEAD", map[string]string{"If-None-Match": `"abc"`}, http.StatusNotModified},
+		{"post ignores validators", "POST", map[string]string{"If-None-Match": `"abc"`}, http.StatusOK},
+		{"not modified since", "GET", map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, http.StatusNotModified},
+		{"modified since", "GET", map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusOK},
+		{"invalid date", "GET", map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK},
+		{"etag takes precedence", "GET", map[string]string{
+			"If-None-Match":     `"old"`,
+			"If-Modified-Since": modified.Format(http.TimeFormat),
+		}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := testutils.NewTestRequestWithHeaders(tt.method, "/", tt.headers)
+			rr := testutils.NewTestResponseRecorder()
+
+			if checkNotModified(rr, req, etag, modified.Add(500*time.Millisecond)) {
+				if tt.expectedStatus != http.StatusNotModified {
+					t.Errorf("Expected %d, got 304", tt.expectedStatus)
+				}
+			} else if tt.expectedStatus == http.StatusNotModified {
+				t.Error("Expected 304 Not Modified")
+			}
+
+			rr.AssertHeader(t, "ETag", etag)
+			rr.AssertHeader(t, "Last-Modified", modified.Format(http.TimeFormat))
+			rr.AssertHeader(t, "Cache-Control", htmlCacheControl)
+		})
+	}
+}
+
+func TestContentETag(t *testing.T) {
+	req := testutils.NewTestRequest("GET", "/blog/a", "")
+	post := models.BlogPost{Slug: "a", Content: "one"}
+	first := contentETag(req, "BlogPost", post)
+
+	if first != contentETag(req, "BlogPost", post) {
+		t.Error("Expected ETag to be stable for the same content")
+	}
+	if first[0] != '"' || first[len(first)-1] != '"' {
+		t.Errorf("Expected a quoted strong ETag, got %s", first)
+	}
+
+	post.Content = "two"
+	if first == contentETag(req, "BlogPost", post) {
+		t.Error("Expected ETag to change with content")
+	}
+	if contentETag(req, "BlogList", post) == contentETag(req, "BlogPost", post) {
+		t.Error("Expected different pages to have different ETags")
+	}
+}
+
+func TestContentETagVariesWithSiteURLs(t *testing.T) {
+	defer SetSiteURLs(SiteURLs{})
+
+	etag := func(urls SiteURLs, host string) string {
+		SetSiteURLs(urls)
+		req := testutils.NewTestRequest("GET", "/", "")
+		req.Host = host
+		return contentETag(req, "Home")
+	}
+
+	base := etag(SiteURLs{BaseURL: "https://example.com"}, "example.com")
+	if base == etag(SiteURLs{BaseURL: "https://example.org"}, "example.com") {
+		t.Error("Expected ETag to change with the base URL")
+	}
+	if base == etag(SiteURLs{BaseURL: "https://example.com", AddSlash: true}, "example.com") {
+		t.Error("Expected ETag to change with the trailing slash policy")
+	}
+	if base != etag(SiteURLs{BaseURL: "https://example.com"}, "other.example.com") {
+		t.Error("Expected the host to be ignored with a base URL")
+	}
+	if etag(SiteURLs{}, "example.com") == etag(SiteURLs{}, "other.example.com") {
+		t.Error("Expected ETag to change with the host without a base URL")
+	}
+}
+
+func TestPostETagFollowsSiteURLs(t *testing.T) {
+	defer SetSiteURLs(SiteURLs{})
+
+	handler := &BlogHandler{}
+	post := models.BlogPost{Slug: "a", Title: "A", Published: true}
+	handler.posts = []models.BlogPost{post}
+	handler.digests = map[string]string{"a": postDigest(post)}
+
+	get := func() string {
+		req := mux.SetURLVars(testutils.NewTestRequest("GET", "/blog/a", ""), map[string]string{"slug": "a"})
+		rr := testutils.NewTestResponseRecorder()
+		handler.GetPost(rr, req)
+		return rr.Header().Get("ETag")
+	}
+
+	SetSiteURLs(SiteURLs{BaseURL: "https://example.com"})
+	before := get()
+	SetSiteURLs(SiteURLs{BaseURL: "https://example.com", AddSlash: true})
+	if after := get(); after == before {
+		t.Error("Expected the cached post ETag to change with the site URL settings")
+	}
+}
+
+func TestGetPostConditional(t *testing.T) {
+	updated := time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC)
+	handler := &BlogHandler{
+		posts: []models.BlogPost{
+			{Slug: "test-post", Title: "Test", Content: "<p>Body</p>", UpdatedAt: updated, Published: true},
+		},
+	}
+
+	get := func(headers map[string]string) *testutils.TestResponseRecorder {
+		req := testutils.NewTestRequestWithHeaders("GET", "/blog/test-post", headers)
+		req = mux.SetURLVars(req, map[string]string{"slug": "test-post"})
+		rr := testutils.NewTestResponseRecorder()
+		handler.GetPost(rr, req)
+		return rr
+	}
+
+	first := get(nil)
+	first.AssertStatusCode(t, http.StatusOK)
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag")
+	}
+	first.AssertHeader(t, "Last-Modified", updated.Format(http.TimeFormat))
+
+	revalidated := get(map[string]string{"If-None-Match": etag})
+	revalidated.AssertStatusCode(t, http.StatusNotModified)
+	if revalidated.Body.Len() != 0 {
+		t.Errorf("Expected empty 304 body, got %d bytes", revalidated.Body.Len())
+	}
+
+	get(map[string]string{"If-Modified-Since": updated.Format(http.TimeFormat)}).AssertStatusCode(t, http.StatusNotModified)
+
+	// Editing the post changes its ETag
+	handler.posts[0].Content = "<p>Edited</p>"
+	get(map[string]string{"If-None-Match": etag}).AssertStatusCode(t, http.StatusOK)
+}
+
+func TestListProjectsConditional(t *testing.T) {
+	handler := NewPortfolioHandler(os.DirFS("../../content"))
+
+	rr := testutils.NewTestResponseRecorder()
+	handler.ListProjects(rr, testutils.NewTestRequest("GET", "/portfolio", ""))
+	rr.AssertStatusCode(t, http.StatusOK)
+
+	req := testutils.NewTestRequestWithHeaders("GET", "/portfolio", map[string]string{"If-None-Match": rr.Header().Get("ETag")})
+	rr = testutils.NewTestResponseRecorder()
+	handler.ListProjects(rr, req)
+	rr.AssertStatusCode(t, http.StatusNotModified)
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/claykom/website/internal/metrics"
+	"github.com/claykom/website/internal/middleware"
+)
+
+const (
+	// maxReportSize bounds a report body; real reports are a few KB
+	maxReportSize = 64 * 1024
+	// maxReportField truncates logged values such as URLs and policies
+	maxReportField = 512
+	// maxTrackedViolations bounds the de-duplication table
+	maxTrackedViolations = 1000
+	// reportWindow is how long a repeated violation stays quiet in the logs
+	reportWindow = time.Hour
+)
+
+// reportContentTypes are the bodies browsers send: the legacy report-uri
+// format and the Reporting API format
+var reportContentTypes = []string{"application/csp-report", "application/reports+json", "application/json"}
+
+// cspDirectives are the directive names kept as metric labels; anything
+// else is counted as unknown so reports can't grow the label set
+var cspDirectives = map[string]bool{
+	"default-src":               true,
+	"script-src":                true,
+	"script-src-elem":           true,
+	"script-src-attr":           true,
+	"style-src":                 true,
+	"style-src-elem":            true,
+	"style-src-attr":            true,
+	"img-src":                   true,
+	"font-src":                  true,
+	"connect-src":               true,
+	"media-src":                 true,
+	"object-src":                true,
+	"frame-src":                 true,
+	"child-src":                 true,
+	"worker-src":                true,
+	"manifest-src":              true,
+	"base-uri":                  true,
+	"form-action":               true,
+	"frame-ancestors":           true,
+	"require-trusted-types-for": true,
+	"trusted-types":             true,
+}
+
+// cspViolations counts reported violations by directive and disposition
+var cspViolations = metrics.DefaultRegistry.NewCounterVec(
+	"website_csp_violations_total",
+	"Content-Security-Policy violation reports by directive and disposition.",
+	"directive", "disposition",
+)
+
+// Violation is a CSP violation report normalised from either report format
+type Violation struct {
+	DocumentURI string
+	BlockedURI  string
+	Directive   string
+	SourceFile  string
+	Line        int
+	Disposition string
+}
+
+// ViolationSummary aggregates identical violations
+type ViolationSummary struct {
+	Violation
+	Count     int
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// lastLogged is when the violation was last written to the log
+	lastLogged time.Time
+}
+
+// CSPReportHandler collects Content-Security-Policy violation reports
+type CSPReportHandler struct {
+	validator *middleware.ValidateInput
+	now       func() time.Time
+
+	mu         sync.Mutex
+	violations map[Violation]*ViolationSummary
+}
+
+// NewCSPReportHandler creates a CSPReportHandler
+func NewCSPReportHandler(validator *middleware.ValidateInput) *CSPReportHandler {
+	return &CSPReportHandler{
+		validator:  validator,
+		now:        time.Now,
+		violations: make(map[Violation]*ViolationSummary),
+	}
+}
+
+// legacyReport is the application/csp-report body sent for report-uri
+type legacyReport struct {
+	Body struct {
+		DocumentURI        string `json:"document-uri"`
+		BlockedURI         string `json:"blocked-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		Disposition        string `json:"disposition"`
+	} `json:"csp-report"`
+}
+
+// apiReport is one entry of an application/reports+json body sent for
+// report-to
+type apiReport struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		BlockedURL         string `json:"blockedURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Disposition        string `json:"disposition"`
+	} `json:"body"`
+}
+
+// ServeHTTP accepts a violation report and responds 204 No Content
+func (h *CSPReportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.validator.ValidateContentType(r.Header.Get("Content-Type"), reportContentTypes) {
+		respondWithError(w, http.StatusUnsupportedMediaType, "Unsupported report content type")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxReportSize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "Report too large")
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, "Invalid report")
+		return
+	}
+
+	violations, err := parseReports(body)
+	if err != nil || len(violations) == 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid report")
+		return
+	}
+
+	for _, v := range violations {
+		h.record(r, v)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseReports decodes either report format. Reporting API bodies may carry
+// other report types, which are ignored.
+func parseReports(body []byte) ([]Violation, error) {
+	// The Reporting API sends an array, the legacy format a single object
+	var reports []apiReport
+	if err := json.Unmarshal(body, &reports); err == nil {
+		var violations []Violation
+		for _, report := range reports {
+			if report.Type != "csp-violation" {
+				continue
+			}
+			violations = append(violations, Violation{
+				DocumentURI: report.Body.DocumentURL,
+				BlockedURI:  report.Body.BlockedURL,
+				Directive:   report.Body.EffectiveDirective,
+				SourceFile:  report.Body.SourceFile,
+				Line:        report.Body.LineNumber,
+				Disposition: report.Body.Disposition,
+			})
+		}
+		return violations, nil
+	}
+
+	var legacy legacyReport
+	if err := json.Unmarshal(body, &legacy); err != nil {
+		return nil, err
+	}
+	if legacy.Body.DocumentURI == "" {
+		return nil, errors.New("missing csp-report body")
+	}
+
+	directive := legacy.Body.EffectiveDirective
+	if directive == "" {
+		// Older browsers only send the full violated directive
+		directive, _, _ = strings.Cut(legacy.Body.ViolatedDirective, " ")
+	}
+	return []Violation{{
+		DocumentURI: legacy.Body.DocumentURI,
+		BlockedURI:  legacy.Body.BlockedURI,
+		Directive:   directive,
+		SourceFile:  legacy.Body.SourceFile,
+		Line:        legacy.Body.LineNumber,
+		Disposition: legacy.Body.Disposition,
+	}}, nil
+}
+
+// record aggregates a violation and logs it unless the same violation was
+// already logged within reportWindow
+func (h *CSPReportHandler) record(r *http.Request, v Violation) {
+	v = sanitizeViolation(v)
+	cspViolations.WithLabelValues(v.Directive, v.Disposition).Inc()
+
+	now := h.now()
+	h.mu.Lock()
+	summary, ok := h.violations[v]
+	if !ok {
+		if len(h.violations) >= maxTrackedViolations {
+			h.evict(now)
+		}
+		if len(h.violations) >= maxTrackedViolations {
+			// Table full of recent violations; count but don't track
+			h.mu.Unlock()
+			return
+		}
+		summary = &ViolationSummary{Violation: v, FirstSeen: now}
+		h.violations[v] = summary
+	}
+	summary.Count++
+	summary.LastSeen = now
+	shouldLog := now.Sub(summary.lastLogged) >= reportWindow
+	if shouldLog {
+		summary.lastLogged = now
+	}
+	count := summary.Count
+	h.mu.Unlock()
+
+	if shouldLog {
+		slog.WarnContext(r.Context(), "csp violation",
+			"directive", v.Directive,
+			"blocked_uri", v.BlockedURI,
+			"document_uri", v.DocumentURI,
+			"source_file", v.SourceFile,
+			"line", v.Line,
+			"disposition", v.Disposition,
+			"count", count,
+		)
+	}
+}
+
+// evict drops violations not seen within reportWindow. Callers hold h.mu.
+func (h *CSPReportHandler) evict(now time.Time) {
+	for key, summary := range h.violations {
+		if now.Sub(summary.LastSeen) >= reportWindow {
+			delete(h.violations, key)
+		}
+	}
+}
+
+// Violations returns a snapshot of the aggregated violations
+func (h *CSPReportHandler) Violations() []ViolationSummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	summaries := make([]ViolationSummary, 0, len(h.violations))
+	for _, summary := range h.violations {
+		summaries = append(summaries, *summary)
+	}
+	return summaries
+}
+
+// sanitizeViolation bounds attacker-controlled values before they reach
+// logs and metric labels
+func sanitizeViolation(v Violation) Violation {
+	v.DocumentURI = truncate(v.DocumentURI, maxReportField)
+	v.BlockedURI = truncate(v.BlockedURI, maxReportField)
+	v.SourceFile = truncate(v.SourceFile, maxReportField)
+	// A full directive such as "script-src-elem 'nonce-…'" is reduced to
+	// its name
+	v.Directive, _, _ = strings.Cut(v.Directive, " ")
+	if !cspDirectives[v.Directive] {
+		v.Directive = "unknown"
+	}
+	if v.Disposition != "enforce" && v.Disposition != "report" {
+		v.Disposition = "unknown"
+	}
+	return v
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claykom/website/internal/metrics"
+	"github.com/claykom/website/internal/middleware"
+	"github.com/claykom/website/internal/testutils"
+)
+
+const legacyReportBody = `{"csp-report": {
+	"document-uri": "https://example.com/blog/post",
+	"referrer": "",
+	"violated-directive": "script-src-elem 'self'",
+	"effective-directive": "script-src-elem",
+	"original-policy": "default-src 'self'",
+	"blocked-uri": "https://evil.example/x.js",
+	"source-file": "https://example.com/blog/post",
+	"line-number": 12,
+	"disposition": "enforce",
+	"status-code": 200
+}}`
+
+const apiReportBody = `[
+	{"type": "csp-violation", "age": 10, "url": "https://example.com/", "user_agent": "test",
+	 "body": {"documentURL": "https://example.com/", "blockedURL": "inline", "effectiveDirective": "style-src-elem",
+	          "originalPolicy": "default-src 'self'", "disposition": "report", "statusCode": 200, "lineNumber": 3}},
+	{"type": "deprecation", "age": 10, "url": "https://example.com/", "body": {"id": "x"}}
+]`
+
+func newReportRequest(contentType, body string) *http.Request {
+	req := testutils.NewTestRequest("POST", "/csp-report", body)
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+func TestCSPReportHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		expectedStatus int
+		directive      string
+		disposition    string
+	}{
+		{"legacy report", "application/csp-report", legacyReportBody, http.StatusNoContent, "script-src-elem", "enforce"},
+		{"reporting api", "application/reports+json", apiReportBody, http.StatusNoContent, "style-src-elem", "report"},
+		{"legacy without effective directive", "application/csp-report",
+			`{"csp-report": {"document-uri": "https://example.com/", "violated-directive": "img-src 'self'"}}`,
+			http.StatusNoContent, "img-src", "unknown"},
+		{"wrong content type", "text/plain", legacyReportBody, http.StatusUnsupportedMediaType, "", ""},
+		{"malformed json", "application/csp-report", `{"csp-report":`, http.StatusBadRequest, "", ""},
+		{"empty legacy report", "application/csp-report", `{}`, http.StatusBadRequest, "", ""},
+		{"no csp reports", "application/reports+json", `[{"type": "deprecation"}]`, http.StatusBadRequest, "", ""},
+		{"too large", "application/csp-report", `{"csp-report": {"document-uri": "` + strings.Repeat("a", maxReportSize) + `"}}`,
+			http.StatusRequestEntityTooLarge, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewCSPReportHandler(middleware.NewValidator())
+			rr := testutils.NewTestResponseRecorder()
+			handler.ServeHTTP(rr, newReportRequest(tt.contentType, tt.body))
+
+			rr.AssertStatusCode(t, tt.expectedStatus)
+
+			violations := handler.Violations()
+			if tt.directive == "" {
+				if len(violations) != 0 {
+					t.Errorf("Expected no violations, got %d", len(violations))
+				}
+				return
+			}
+			if len(violations) != 1 {
+				t.Fatalf("Expected 1 violation, got %d", len(violations))
+			}
+			if violations[0].Directive != tt.directive {
+				t.Errorf("Expected directive %q, got %q", tt.directive, violations[0].Directive)
+			}
+			if violations[0].Disposition != tt.disposition {
+				t.Errorf("Expected disposition %q, got %q", tt.disposition, violations[0].Disposition)
+			}
+		})
+	}
+}
+
+func TestCSPReportHandlerDeduplicates(t *testing.T) {
+	handler := NewCSPReportHandler(middleware.NewValidator())
+	before := cspViolations.WithLabelValues("script-src-elem", "enforce").Value()
+
+	for i := 0; i < 5; i++ {
+		rr := testutils.NewTestResponseRecorder()
+		handler.ServeHTTP(rr, newReportRequest("application/csp-report", legacyReportBody))
+		rr.AssertStatusCode(t, http.StatusNoContent)
+	}
+
+	violations := handler.Violations()
+	if len(violations) != 1 {
+		t.Fatalf("Expected identical reports to be aggregated, got %d entries", len(violations))
+	}
+	if violations[0].Count != 5 {
+		t.Errorf("Expected count 5, got %d", violations[0].Count)
+	}
+	if got := cspViolations.WithLabelValues("script-src-elem", "enforce").Value() - before; got != 5 {
+		t.Errorf("Expected 5 counted violations, got %v", got)
+	}
+}
+
+func TestCSPReportHandlerEviction(t *testing.T) {
+	handler := NewCSPReportHandler(middleware.NewValidator())
+	now := time.Now()
+	handler.now = func() time.Time { return now }
+
+	req := newReportRequest("application/csp-report", legacyReportBody)
+	for i := 0; i < maxTrackedViolations; i++ {
+		handler.record(req, Violation{DocumentURI: "https://example.com/", Line: i, Directive: "img-src"})
+	}
+
+	// Table is full of recent entries: new violations are not tracked
+	handler.record(req, Violation{DocumentURI: "https://example.com/new", Directive: "img-src"})
+	if len(handler.Violations()) != maxTrackedViolations {
+		t.Errorf("Expected table to stay at %d entries, got %d", maxTrackedViolations, len(handler.Violations()))
+	}
+
+	// Once the old entries expire they make room
+	now = now.Add(reportWindow)
+	handler.record(req, Violation{DocumentURI: "https://example.com/new", Directive: "img-src"})
+	if got := len(handler.Violations()); got != 1 {
+		t.Errorf("Expected expired entries to be evicted, got %d entries", got)
+	}
+}
+
+func TestSanitizeViolation(t *testing.T) {
+	v := sanitizeViolation(Violation{
+		DocumentURI: strings.Repeat("a", 2*maxReportField),
+		Directive:   "script-src\nINJECTED",
+		Disposition: "whatever",
+	})
+
+	if len(v.DocumentURI) != maxReportField {
+		t.Errorf("Expected document URI truncated to %d, got %d", maxReportField, len(v.DocumentURI))
+	}
+	if v.Directive != "unknown" {
+		t.Errorf("Expected invalid directive to become unknown, got %q", v.Directive)
+	}
+	if v.Disposition != "unknown" {
+		t.Errorf("Expected invalid disposition to become unknown, got %q", v.Disposition)
+	}
+}
+
+func TestSanitizeViolationDirective(t *testing.T) {
+	tests := []struct {
+		directive string
+		expected  string
+	}{
+		{"script-src-elem", "script-src-elem"},
+		{"script-src-elem 'nonce-abc123'", "script-src-elem"},
+		{"frame-ancestors 'none'", "frame-ancestors"},
+		{"not-a-directive", "unknown"},
+		{"SCRIPT-SRC", "unknown"},
+		{"", "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.directive, func(t *testing.T) {
+			if got := sanitizeViolation(Violation{Directive: tt.directive}).Directive; got != tt.expected {
+				t.Errorf("Expected directive %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCSPReportHandlerBoundsLabels(t *testing.T) {
+	handler := NewCSPReportHandler(middleware.NewValidator())
+	for i := 0; i < 200; i++ {
+		body := fmt.Sprintf(`{"csp-report": {"document-uri": "https://example.com/", "effective-directive": "junk-%d", "disposition": "x%d"}}`, i, i)
+		rr := testutils.NewTestResponseRecorder()
+		handler.ServeHTTP(rr, newReportRequest("application/csp-report", body))
+		rr.AssertStatusCode(t, http.StatusNoContent)
+	}
+
+	var buf bytes.Buffer
+	metrics.DefaultRegistry.Write(&buf)
+	series := 0
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if !strings.HasPrefix(line, "website_csp_violations_total{") {
+			continue
+		}
+		series++
+		if strings.Contains(line, "junk-") {
+			t.Errorf("Expected junk directives to be counted as unknown, got %s", line)
+		}
+	}
+	// Every known directive and unknown, by each disposition
+	if limit := (len(cspDirectives) + 1) * 3; series > limit {
+		t.Errorf("Expected at most %d label sets, got %d", limit, series)
+	}
+	if got := cspViolations.WithLabelValues("unknown", "unknown").Value(); got < 200 {
+		t.Errorf("Expected junk reports counted as unknown, got %v", got)
+	}
+}
+
+func BenchmarkCSPReportHandler(b *testing.B) {
+	handler := NewCSPReportHandler(middleware.NewValidator())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(testutils.NewTestResponseRecorder(), newReportRequest("application/csp-report", legacyReportBody))
+	}
+}
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/claykom/website/internal/buildinfo"
+	"github.com/claykom/website/internal/health"
 	"github.com/claykom/website/internal/testutils"
 )
 
@@ -26,6 +30,9 @@ func TestHome(t *testing.T) {
 	if !strings.Contains(body, "<html") || !strings.Contains(body, "</html>") {
 		t.Error("Expected response to contain HTML content")
 	}
+
+	// The footer shows the build
+	rr.AssertBodyContains(t, `class="build-info"`)
 }
 
 func TestHealth(t *testing.T) {
@@ -59,8 +66,8 @@ func TestHealth(t *testing.T) {
 		t.Errorf("Expected status 'ok', got %v", status)
 	}
 
-	if version, ok := response["version"]; !ok || version != "1.0.0" {
-		t.Errorf("Expected version '1.0.0', got %v", version)
+	if version, ok := response["version"]; !ok || version != buildinfo.Get().Version {
+		t.Errorf("Expected version '%s', got %v", buildinfo.Get().Version, version)
 	}
 
 	// Check timestamp format
@@ -119,6 +126,50 @@ func TestHealthDifferentMethods(t *testing.T) {
 	}
 }
 
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name           string
+		checkErr       error
+		drain          bool
+		expectedCode   int
+		expectedStatus string
+	}{
+		{"ready", nil, false, http.StatusOK, health.StatusOK},
+		{"failing check", errors.New("blog posts not loaded"), false, http.StatusServiceUnavailable, health.StatusFail},
+		{"draining", nil, true, http.StatusServiceUnavailable, health.StatusDraining},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := health.NewRegistry()
+			registry.Register("content", time.Second, func(ctx context.Context) error { return tt.checkErr })
+			if tt.drain {
+				registry.Drain()
+			}
+
+			rr := testutils.NewTestResponseRecorder()
+			Readiness(registry)(rr, testutils.NewTestRequest("GET", "/readyz", ""))
+
+			rr.AssertStatusCode(t, tt.expectedCode)
+			rr.AssertHeader(t, "Cache-Control", "no-store")
+
+			var response struct {
+				Status string          `json:"status"`
+				Checks []health.Result `json:"checks"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Error unmarshaling response: %v", err)
+			}
+			if response.Status != tt.expectedStatus {
+				t.Errorf("Expected status %s, got %s", tt.expectedStatus, response.Status)
+			}
+			if tt.checkErr != nil && (len(response.Checks) != 1 || response.Checks[0].Error != tt.checkErr.Error()) {
+				t.Errorf("Expected the check's error in the response, got %+v", response.Checks)
+			}
+		})
+	}
+}
+
 func TestNotFound(t *testing.T) {
 	req := testutils.NewTestRequest("GET", "/nonexistent", "")
 	rr := testutils.NewTestResponseRecorder()
@@ -320,12 +371,37 @@ func TestRespondWithError(t *testing.T) {
 	}
 }
 
-func TestGetVersion(t *testing.T) {
-	version := getVersion()
-	expectedVersion := "1.0.0"
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		withDeps bool
+	}{
+		{"public", false},
+		{"admin", true},
+	}
 
-	if version != expectedVersion {
-		t.Errorf("Expected version %s, got %s", expectedVersion, version)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := testutils.NewTestResponseRecorder()
+			Version(tt.withDeps)(rr, testutils.NewTestRequest("GET", "/version", ""))
+
+			rr.AssertStatusCode(t, http.StatusOK)
+
+			var info buildinfo.Info
+			if err := json.Unmarshal(rr.Body.Bytes(), &info); err != nil {
+				t.Fatalf("Error unmarshaling response: %v", err)
+			}
+			if info.Version != buildinfo.Get().Version || info.GoVersion == "" {
+				t.Errorf("Expected version %s and a Go version, got %+v", buildinfo.Get().Version, info)
+			}
+			expectedDeps := 0
+			if tt.withDeps {
+				expectedDeps = len(buildinfo.Get().Deps)
+			}
+			if len(info.Deps) != expectedDeps {
+				t.Errorf("Expected %d dependencies, got %d", expectedDeps, len(info.Deps))
+			}
+		})
 	}
 }
 
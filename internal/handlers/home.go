@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/claykom/website/internal/buildinfo"
+	"github.com/claykom/website/internal/health"
 	"github.com/claykom/website/internal/views/pages"
 )
 
@@ -12,33 +14,64 @@ var startTime = time.Now()
 
 // Home handles the home page
 func Home(w http.ResponseWriter, r *http.Request) {
-	component := pages.Home()
-	if err := component.Render(r.Context(), w); err != nil {
-		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	// The page has no content of its own, so it only changes between builds
+	if checkNotModified(w, r, contentETag(r, "Home"), time.Time{}) {
 		return
 	}
+	render(w, r, "Home", pages.Home(homeMeta(r)))
 }
 
-// Health handles health check requests
+// Health handles liveness probes. It only shows that the process can serve
+// requests; dependencies are checked by Readiness, so a failing dependency
+// takes the server out of rotation instead of getting it restarted.
 func Health(w http.ResponseWriter, r *http.Request) {
-	// You can add more health checks here (database, external services, etc.)
 	status := "ok"
 	httpStatus := http.StatusOK
 
 	response := map[string]interface{}{
 		"status":    status,
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
-		"version":   getVersion(),
+		"version":   buildinfo.Get().Version,
+		"build":     buildinfo.Get().Summary(),
 		"uptime":    getUptime(),
 	}
 
 	respondWithJSON(w, httpStatus, response)
 }
 
-// getVersion returns the application version (you can set this via build flags)
-func getVersion() string {
-	// This could be set at build time with -ldflags "-X main.version=1.0.0"
-	return "1.0.0"
+// Readiness handles readiness probes by running the registry's checks.
+// It answers 503 when any check fails and as soon as shutdown begins, so
+// load balancers drain the server before it stops accepting connections.
+func Readiness(registry *health.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := registry.Run(r.Context())
+		httpStatus := http.StatusOK
+		if report.Status != health.StatusOK {
+			httpStatus = http.StatusServiceUnavailable
+		}
+
+		response := map[string]interface{}{
+			"status":    report.Status,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+			"checks":    report.Checks,
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		respondWithJSON(w, httpStatus, response)
+	}
+}
+
+// Version reports the build information. The module list is only included
+// when withDeps is set, which the admin listener does; on the public
+// listener it would advertise exactly which library versions to attack.
+func Version(withDeps bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info := buildinfo.Get()
+		if !withDeps {
+			info = info.Summary()
+		}
+		respondWithJSON(w, http.StatusOK, info)
+	}
 }
 
 // getUptime returns the application uptime
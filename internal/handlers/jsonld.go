@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/claykom/website/internal/models"
+)
+
+const schemaContext = "https://schema.org"
+
+// ldPerson is a schema.org Person
+type ldPerson struct {
+	Context     string `json:"@context,omitempty"`
+	Type        string `json:"@type"`
+	Name        string `json:"name"`
+	URL         string `json:"url,omitempty"`
+	JobTitle    string `json:"jobTitle,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ldWebSite is a schema.org WebSite
+type ldWebSite struct {
+	Context     string    `json:"@context"`
+	Type        string    `json:"@type"`
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	Description string    `json:"description,omitempty"`
+	Author      *ldPerson `json:"author,omitempty"`
+}
+
+// ldBlogPosting is a schema.org BlogPosting
+type ldBlogPosting struct {
+	Context          string   `json:"@context"`
+	Type             string   `json:"@type"`
+	Headline         string   `json:"headline"`
+	Description      string   `json:"description,omitempty"`
+	URL              string   `json:"url"`
+	MainEntityOfPage string   `json:"mainEntityOfPage"`
+	Image            string   `json:"image,omitempty"`
+	DatePublished    string   `json:"datePublished,omitempty"`
+	DateModified     string   `json:"dateModified,omitempty"`
+	Author           ldPerson `json:"author"`
+	Keywords         []string `json:"keywords,omitempty"`
+}
+
+// ldCreativeWork is a schema.org CreativeWork, or SoftwareSourceCode when
+// it has a repository
+type ldCreativeWork struct {
+	Context        string   `json:"@context"`
+	Type           string   `json:"@type"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description,omitempty"`
+	URL            string   `json:"url"`
+	Image          string   `json:"image,omitempty"`
+	DateCreated    string   `json:"dateCreated,omitempty"`
+	DateModified   string   `json:"dateModified,omitempty"`
+	Author         ldPerson `json:"author"`
+	Keywords       []string `json:"keywords,omitempty"`
+	CodeRepository string   `json:"codeRepository,omitempty"`
+	SameAs         string   `json:"sameAs,omitempty"`
+}
+
+// ldBreadcrumbList is a schema.org BreadcrumbList
+type ldBreadcrumbList struct {
+	Context         string       `json:"@context"`
+	Type            string       `json:"@type"`
+	ItemListElement []ldListItem `json:"itemListElement"`
+}
+
+type ldListItem struct {
+	Type     string `json:"@type"`
+	Position int    `json:"position"`
+	Name     string `json:"name"`
+	Item     string `json:"item"`
+}
+
+// crumb is a step of a breadcrumb trail: a page's name and URL path
+type crumb struct {
+	name string
+	path string
+}
+
+// siteAuthor is the person behind the site, linked to the home page
+func siteAuthor(r *http.Request) ldPerson {
+	return ldPerson{Type: "Person", Name: models.SiteAuthor, URL: pageURL(r, "/")}
+}
+
+// homeStructuredData describes the person behind the site and the site
+func homeStructuredData(r *http.Request) []any {
+	person := siteAuthor(r)
+	person.JobTitle = "Software Engineer"
+	person.Description = homeDescription
+
+	website := ldWebSite{
+		Context:     schemaContext,
+		Type:        "WebSite",
+		Name:        models.SiteName,
+		URL:         person.URL,
+		Description: homeDescription,
+		Author:      &ldPerson{Type: "Person", Name: person.Name, URL: person.URL},
+	}
+
+	person.Context = schemaContext
+	return []any{person, website}
+}
+
+// postStructuredData describes a post, whose metadata is meta, and where
+// it sits in the site
+func postStructuredData(r *http.Request, post models.BlogPost, meta models.PageMeta) []any {
+	author := siteAuthor(r)
+	if post.Author != "" && post.Author != models.SiteAuthor {
+		author = ldPerson{Type: "Person", Name: post.Author}
+	}
+
+	posting := ldBlogPosting{
+		Context:          schemaContext,
+		Type:             "BlogPosting",
+		Headline:         post.Title,
+		Description:      post.Excerpt,
+		URL:              meta.Canonical,
+		MainEntityOfPage: meta.Canonical,
+		Image:            meta.Image,
+		DatePublished:    ldDate(post.PublishedAt),
+		DateModified:     ldDate(post.UpdatedAt),
+		Author:           author,
+		Keywords:         post.Tags,
+	}
+	return []any{posting, breadcrumbs(r, crumb{"Blog", "/blog"}, crumb{post.Title, "/blog/" + post.Slug})}
+}
+
+// projectStructuredData describes a project, whose metadata is meta, and
+// where it sits in the site. Projects with a GitHub repository are
+// SoftwareSourceCode.
+func projectStructuredData(r *http.Request, project models.Project, meta models.PageMeta) []any {
+	work := ldCreativeWork{
+		Context:        schemaContext,
+		Type:           "CreativeWork",
+		Name:           project.Title,
+		Description:    project.Description,
+		URL:            meta.Canonical,
+		Image:          meta.Image,
+		DateCreated:    ldDate(project.CreatedAt),
+		DateModified:   ldDate(project.UpdatedAt),
+		Author:         siteAuthor(r),
+		Keywords:       project.Technologies,
+		CodeRepository: project.GithubURL,
+		SameAs:         project.ProjectURL,
+	}
+	if project.GithubURL != "" {
+		work.Type = "SoftwareSourceCode"
+	}
+	return []any{work, breadcrumbs(r, crumb{"Portfolio", "/portfolio"}, crumb{project.Title, "/portfolio/" + project.Slug})}
+}
+
+// breadcrumbs lists the trail from the home page through crumbs
+func breadcrumbs(r *http.Request, crumbs ...crumb) ldBreadcrumbList {
+	crumbs = append([]crumb{{"Home", "/"}}, crumbs...)
+	list := ldBreadcrumbList{Context: schemaContext, Type: "BreadcrumbList"}
+	for i, c := range crumbs {
+		list.ItemListElement = append(list.ItemListElement, ldListItem{
+			Type:     "ListItem",
+			Position: i + 1,
+			Name:     c.name,
+			Item:     pageURL(r, c.path),
+		})
+	}
+	return list
+}
+
+// ldDate formats a date for schema.org; zero times are left out
+func ldDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
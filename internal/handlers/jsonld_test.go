@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/claykom/website/internal/models"
+	"github.com/claykom/website/internal/testutils"
+	"github.com/gorilla/mux"
+)
+
+func TestProjectStructuredData(t *testing.T) {
+	SetSiteURLs(SiteURLs{BaseURL: "https://example.com"})
+	defer SetSiteURLs(SiteURLs{})
+
+	tests := []struct {
+		name         string
+		githubURL    string
+		expectedType string
+	}{
+		{"with repository", "https://github.com/example/project", "SoftwareSourceCode"},
+		{"without repository", "", "CreativeWork"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := testutils.NewTestRequest("GET", "/portfolio/project", "")
+			meta := projectMeta(req, models.Project{Slug: "project", Title: "Project", GithubURL: tt.githubURL})
+
+			if len(meta.StructuredData) != 2 {
+				t.Fatalf("Expected 2 structured data values, got %d", len(meta.StructuredData))
+			}
+			work := meta.StructuredData[0].(ldCreativeWork)
+			if work.Type != tt.expectedType {
+				t.Errorf("Expected type %s, got %s", tt.expectedType, work.Type)
+			}
+			if work.CodeRepository != tt.githubURL {
+				t.Errorf("Expected codeRepository %q, got %q", tt.githubURL, work.CodeRepository)
+			}
+			if work.URL != "https://example.com/portfolio/project" {
+				t.Errorf("Expected url https://example.com/portfolio/project, got %s", work.URL)
+			}
+		})
+	}
+}
+
+func TestPostAuthor(t *testing.T) {
+	tests := []struct {
+		name        string
+		author      string
+		expectedURL string
+	}{
+		{"site author", "", "http://example.com/"},
+		{"site author by name", models.SiteAuthor, "http://example.com/"},
+		{"guest author", "Guest", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := testutils.NewTestRequest("GET", "/blog/post", "")
+			meta := postMeta(req, models.BlogPost{Slug: "post", Title: "Post", Author: tt.author})
+
+			posting := meta.StructuredData[0].(ldBlogPosting)
+			expectedName := tt.author
+			if expectedName == "" {
+				expectedName = models.SiteAuthor
+			}
+			if posting.Author.Name != expectedName {
+				t.Errorf("Expected author %s, got %s", expectedName, posting.Author.Name)
+			}
+			if posting.Author.URL != tt.expectedURL {
+				t.Errorf("Expected author url %q, got %q", tt.expectedURL, posting.Author.URL)
+			}
+		})
+	}
+}
+
+func TestBreadcrumbs(t *testing.T) {
+	SetSiteURLs(SiteURLs{BaseURL: "https://example.com", AddSlash: true})
+	defer SetSiteURLs(SiteURLs{})
+
+	list := breadcrumbs(testutils.NewTestRequest("GET", "/blog/post", ""), crumb{"Blog", "/blog"}, crumb{"Post", "/blog/post"})
+
+	expected := []ldListItem{
+		{Type: "ListItem", Position: 1, Name: "Home", Item: "https://example.com/"},
+		{Type: "ListItem", Position: 2, Name: "Blog", Item: "https://example.com/blog/"},
+		{Type: "ListItem", Position: 3, Name: "Post", Item: "https://example.com/blog/post/"},
+	}
+	if len(list.ItemListElement) != len(expected) {
+		t.Fatalf("Expected %d items, got %d", len(expected), len(list.ItemListElement))
+	}
+	for i, item := range list.ItemListElement {
+		if item != expected[i] {
+			t.Errorf("Expected item %d to be %+v, got %+v", i, expected[i], item)
+		}
+	}
+}
+
+func TestStructuredDataRendering(t *testing.T) {
+	handler := &BlogHandler{
+		posts: []models.BlogPost{{
+			Slug:        "test-post",
+			Title:       `</script><script>alert("x")</script>`,
+			Content:     "<p>Body</p>",
+			PublishedAt: time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC),
+			Tags:        []string{"go"},
+			Published:   true,
+		}},
+	}
+
+	req := testutils.NewTestRequest("GET", "/blog/test-post", "")
+	req = req.WithContext(templ.WithNonce(req.Context(), "test-nonce"))
+	req = mux.SetURLVars(req, map[string]string{"slug": "test-post"})
+	rr := testutils.NewTestResponseRecorder()
+	handler.GetPost(rr, req)
+	rr.AssertStatusCode(t, http.StatusOK)
+
+	body := rr.Body.String()
+	if count := strings.Count(body, `<script type="application/ld+json" nonce="test-nonce">`); count != 2 {
+		t.Errorf("Expected 2 JSON-LD scripts with the nonce, got %d", count)
+	}
+	if strings.Contains(body, `<script>alert`) {
+		t.Error("Expected the title to be escaped inside the JSON-LD script")
+	}
+
+	// Every script holds valid JSON
+	for _, part := range strings.Split(body, `<script type="application/ld+json" nonce="test-nonce">`)[1:] {
+		var data map[string]any
+		if err := json.Unmarshal([]byte(part[:strings.Index(part, "</script>")]), &data); err != nil {
+			t.Errorf("Expected valid JSON-LD, got error: %v", err)
+		}
+		if data["@context"] != "https://schema.org" {
+			t.Errorf("Expected @context https://schema.org, got %v", data["@context"])
+		}
+	}
+	rr.AssertBodyContains(t, `"headline":"\u003c/script\u003e\u003cscript\u003ealert(\"x\")\u003c/script\u003e"`)
+	rr.AssertBodyContains(t, `"datePublished":"2025-10-01T00:00:00Z"`)
+	rr.AssertBodyContains(t, `"keywords":["go"]`)
+}
+
+func TestHomeStructuredData(t *testing.T) {
+	rr := testutils.NewTestResponseRecorder()
+	Home(rr, testutils.NewTestRequest("GET", "/", ""))
+
+	rr.AssertBodyContains(t, `"@type":"Person"`)
+	rr.AssertBodyContains(t, `"@type":"WebSite"`)
+	if strings.Contains(rr.Body.String(), "BreadcrumbList") {
+		t.Error("Expected no breadcrumbs on the home page")
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/claykom/website/internal/models"
+)
+
+// SiteURLs says how pages build absolute links to themselves for their
+// metadata. BaseURL is the site's public URL; when empty, the request's
+// scheme and host are used. AddSlash appends a slash to page paths, for
+// the "add" trailing slash policy.
+type SiteURLs struct {
+	BaseURL  string
+	AddSlash bool
+}
+
+// siteURLs is the setting used by pageMeta
+var siteURLs atomic.Pointer[SiteURLs]
+
+// SetSiteURLs sets how pages build absolute links to themselves
+func SetSiteURLs(u SiteURLs) {
+	siteURLs.Store(&u)
+}
+
+// Descriptions of the pages without content of their own
+const (
+	homeDescription      = "Software engineer building robust web applications with Go and modern web technologies."
+	blogDescription      = "Thoughts on software development, Go, and web technologies"
+	portfolioDescription = "A collection of my recent projects and work"
+)
+
+// pageMeta fills in the absolute URLs of meta: the canonical URL of the
+// page at path, and the image if it is site-relative
+func pageMeta(r *http.Request, path string, meta models.PageMeta) models.PageMeta {
+	meta.Canonical = pageURL(r, path)
+	meta.AddSlash = currentSiteURLs().AddSlash
+	if strings.HasPrefix(meta.Image, "/") {
+		meta.Image = siteURL(r, currentSiteURLs().BaseURL) + meta.Image
+	}
+	return meta
+}
+
+// pageURL returns the absolute URL of the page at path
+func pageURL(r *http.Request, path string) string {
+	urls := currentSiteURLs()
+	if urls.AddSlash && path != "/" {
+		path += "/"
+	}
+	return siteURL(r, urls.BaseURL) + path
+}
+
+// currentSiteURLs returns the setting, or the zero value before it is set
+func currentSiteURLs() *SiteURLs {
+	if urls := siteURLs.Load(); urls != nil {
+		return urls
+	}
+	return &SiteURLs{}
+}
+
+// homeMeta describes the home page
+func homeMeta(r *http.Request) models.PageMeta {
+	return pageMeta(r, "/", models.PageMeta{
+		Description:    homeDescription,
+		StructuredData: homeStructuredData(r),
+	})
+}
+
+// blogListMeta describes the post list
+func blogListMeta(r *http.Request) models.PageMeta {
+	return pageMeta(r, "/blog", models.PageMeta{Title: "Blog", Description: blogDescription})
+}
+
+// postMeta describes a post as an article
+func postMeta(r *http.Request, post models.BlogPost) models.PageMeta {
+	meta := pageMeta(r, "/blog/"+post.Slug, models.PageMeta{
+		Title:       post.Title,
+		Description: post.Excerpt,
+		Image:       post.ImageURL,
+		Type:        models.PageTypeArticle,
+		PublishedAt: post.PublishedAt,
+		ModifiedAt:  post.UpdatedAt,
+		Tags:        post.Tags,
+	})
+	meta.StructuredData = postStructuredData(r, post, meta)
+	return meta
+}
+
+// portfolioListMeta describes the project list
+func portfolioListMeta(r *http.Request) models.PageMeta {
+	return pageMeta(r, "/portfolio", models.PageMeta{Title: "Portfolio", Description: portfolioDescription})
+}
+
+// projectMeta describes a project
+func projectMeta(r *http.Request, project models.Project) models.PageMeta {
+	meta := pageMeta(r, "/portfolio/"+project.Slug, models.PageMeta{
+		Title:       project.Title,
+		Description: project.Description,
+		Image:       project.ImageURL,
+		PublishedAt: project.CreatedAt,
+		ModifiedAt:  project.UpdatedAt,
+		Tags:        project.Technologies,
+	})
+	meta.StructuredData = projectStructuredData(r, project, meta)
+	return meta
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claykom/website/internal/models"
+	"github.com/claykom/website/internal/testutils"
+	"github.com/gorilla/mux"
+)
+
+func TestPageMeta(t *testing.T) {
+	defer SetSiteURLs(SiteURLs{})
+
+	tests := []struct {
+		name              string
+		urls              SiteURLs
+		headers           map[string]string
+		image             string
+		expectedCanonical string
+		expectedImage     string
+	}{
+		{
+			name:              "base URL",
+			urls:              SiteURLs{BaseURL: "https://example.com"},
+			image:             "/static/images/a.jpg",
+			expectedCanonical: "https://example.com/blog/post",
+			expectedImage:     "https://example.com/static/images/a.jpg",
+		},
+		{
+			name:              "base URL with path",
+			urls:              SiteURLs{BaseURL: "https://example.com/site"},
+			expectedCanonical: "https://example.com/site/blog/post",
+		},
+		{
+			name:              "from request",
+			headers:           map[string]string{"X-Forwarded-Proto": "https"},
+			expectedCanonical: "https://example.com/blog/post",
+		},
+		{
+			name:              "trailing slash",
+			urls:              SiteURLs{BaseURL: "https://example.com", AddSlash: true},
+			expectedCanonical: "https://example.com/blog/post/",
+		},
+		{
+			name:              "absolute image",
+			urls:              SiteURLs{BaseURL: "https://example.com"},
+			image:             "https://cdn.example.net/a.jpg",
+			expectedCanonical: "https://example.com/blog/post",
+			expectedImage:     "https://cdn.example.net/a.jpg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetSiteURLs(tt.urls)
+			req := testutils.NewTestRequestWithHeaders("GET", "/blog/post", tt.headers)
+
+			meta := pageMeta(req, "/blog/post", models.PageMeta{Image: tt.image})
+			if meta.Canonical != tt.expectedCanonical {
+				t.Errorf("Expected canonical %s, got %s", tt.expectedCanonical, meta.Canonical)
+			}
+			if meta.Image != tt.expectedImage {
+				t.Errorf("Expected image %q, got %q", tt.expectedImage, meta.Image)
+			}
+		})
+	}
+
+	// The root keeps a single slash
+	SetSiteURLs(SiteURLs{BaseURL: "https://example.com", AddSlash: true})
+	if meta := homeMeta(testutils.NewTestRequest("GET", "/", "")); meta.Canonical != "https://example.com/" {
+		t.Errorf("Expected home canonical https://example.com/, got %s", meta.Canonical)
+	}
+}
+
+func TestPostMetadata(t *testing.T) {
+	SetSiteURLs(SiteURLs{BaseURL: "https://example.com"})
+	defer SetSiteURLs(SiteURLs{})
+
+	handler := &BlogHandler{
+		posts: []models.BlogPost{{
+			Slug:        "test-post",
+			Title:       "Test <Post>",
+			Excerpt:     "What it's about",
+			ImageURL:    "/static/images/test.jpg",
+			Content:     "<p>Body</p>",
+			PublishedAt: time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC),
+			UpdatedAt:   time.Date(2025, 10, 2, 8, 30, 0, 0, time.UTC),
+			Tags:        []string{"go", "web"},
+			Published:   true,
+		}},
+	}
+
+	req := mux.SetURLVars(testutils.NewTestRequest("GET", "/blog/test-post", ""), map[string]string{"slug": "test-post"})
+	rr := testutils.NewTestResponseRecorder()
+	handler.GetPost(rr, req)
+	rr.AssertStatusCode(t, http.StatusOK)
+
+	for _, expected := range []string{
+		`<title>Test &lt;Post&gt; - Clay&#39;s Portfolio</title>`,
+		`<meta name="description" content="What it&#39;s about">`,
+		`<link rel="canonical" href="https://example.com/blog/test-post">`,
+		`<meta property="og:type" content="article">`,
+		`<meta property="og:title" content="Test &lt;Post&gt;">`,
+		`<meta property="og:url" content="https://example.com/blog/test-post">`,
+		`<meta property="og:image" content="https://example.com/static/images/test.jpg">`,
+		`<meta property="article:published_time" content="2025-10-01T00:00:00Z">`,
+		`<meta property="article:modified_time" content="2025-10-02T08:30:00Z">`,
+		`<meta property="article:tag" content="go">`,
+		`<meta property="article:tag" content="web">`,
+		`<meta name="twitter:card" content="summary_large_image">`,
+	} {
+		rr.AssertBodyContains(t, expected)
+	}
+}
+
+func TestListMetadata(t *testing.T) {
+	SetSiteURLs(SiteURLs{BaseURL: "https://example.com"})
+	defer SetSiteURLs(SiteURLs{})
+
+	rr := testutils.NewTestResponseRecorder()
+	Home(rr, testutils.NewTestRequest("GET", "/", ""))
+	rr.AssertBodyContains(t, `<title>Clay&#39;s Portfolio</title>`)
+	rr.AssertBodyContains(t, `<meta property="og:title" content="Clay&#39;s Portfolio">`)
+	rr.AssertBodyContains(t, `<meta property="og:type" content="website">`)
+	rr.AssertBodyContains(t, `<meta name="twitter:card" content="summary">`)
+	if strings.Contains(rr.Body.String(), "article:") {
+		t.Error("Expected no article tags on the home page")
+	}
+
+	rr = testutils.NewTestResponseRecorder()
+	(&BlogHandler{}).ListPosts(rr, testutils.NewTestRequest("GET", "/blog", ""))
+	rr.AssertBodyContains(t, `<link rel="canonical" href="https://example.com/blog">`)
+	rr.AssertBodyContains(t, `<meta name="description" content="`+blogDescription+`">`)
+}
+
+func BenchmarkPageMeta(b *testing.B) {
+	req := testutils.NewTestRequest("GET", "/blog/post", "")
+	post := models.BlogPost{Slug: "post", Title: "Post", ImageURL: "/static/images/a.jpg", Tags: []string{"go"}}
+	for i := 0; i < b.N; i++ {
+		postMeta(req, post)
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/claykom/website/internal/metrics"
+)
+
+// maxCachedPageSize keeps unusually large pages out of the cache
+const maxCachedPageSize = 1 << 20
+
+// pageCacheRequests counts page cache lookups by result
+var pageCacheRequests = metrics.DefaultRegistry.NewCounterVec(
+	"website_page_cache_requests_total",
+	"Rendered page cache lookups by result (hit, miss or bypass).",
+	"result",
+)
+
+// defaultPageCache is the cache used by render; nil disables caching
+var defaultPageCache atomic.Pointer[PageCache]
+
+func init() {
+	metrics.DefaultRegistry.NewGaugeFunc(
+		"website_page_cache_entries",
+		"Rendered pages held in the page cache.",
+		func() float64 { return float64(defaultPageCache.Load().Len()) },
+	)
+}
+
+// SetPageCache sets the cache used for rendered pages. A nil cache turns
+// caching off.
+func SetPageCache(c *PageCache) {
+	defaultPageCache.Store(c)
+}
+
+// PageCache is a bounded LRU of rendered pages. Pages are stored with the
+// CSP nonce they were rendered with, which is swapped for the current
+// request's nonce on a hit.
+type PageCache struct {
+	capacity int
+	hits     atomic.Uint64
+	misses   atomic.Uint64
+
+	mu      sync.Mutex
+	order   *list.List // front is the most recently used
+	entries map[string]*list.Element
+}
+
+// cachedPage is a rendered page body split around the CSP nonce it was
+// rendered with
+type cachedPage struct {
+	key   string
+	parts [][]byte
+	size  int
+}
+
+// newCachedPage copies body, splitting it wherever nonce occurs
+func newCachedPage(key string, body []byte, nonce string) *cachedPage {
+	body = bytes.Clone(body)
+	parts := [][]byte{body}
+	if nonce != "" {
+		parts = bytes.Split(body, []byte(nonce))
+	}
+	return &cachedPage{key: key, parts: parts, size: len(body)}
+}
+
+// NewPageCache creates a cache holding up to capacity pages
+func NewPageCache(capacity int) *PageCache {
+	return &PageCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// get returns the page for key and marks it recently used
+func (c *PageCache) get(key string) (*cachedPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cachedPage), true
+}
+
+// add stores a page, evicting the least recently used one when full
+func (c *PageCache) add(page *cachedPage) {
+	if c.capacity <= 0 || page.size > maxCachedPageSize {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[page.key]; ok {
+		elem.Value = page
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[page.key] = c.order.PushFront(page)
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedPage).key)
+	}
+}
+
+// Purge drops every cached page, for use after content changes
+func (c *PageCache) Purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	clear(c.entries)
+}
+
+// Len returns the number of cached pages
+func (c *PageCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Stats returns the number of cache hits and misses so far
+func (c *PageCache) Stats() (hits, misses uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// writeTo writes the page with nonce in place of the one it was rendered
+// with
+func (p *cachedPage) writeTo(w io.Writer, nonce string) error {
+	for i, part := range p.parts {
+		if i > 0 {
+			if _, err := io.WriteString(w, nonce); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pageCacheKey identifies a rendered page by component, URL and the request
+// headers the response varies on. Requests that are not GET or HEAD, or
+// that carry cookies or credentials and may be personalised, are not
+// cacheable.
+func pageCacheKey(w http.ResponseWriter, r *http.Request, name string) (string, bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return "", false
+	}
+	if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+		return "", false
+	}
+
+	// Pages link to themselves under the request's scheme and host when no
+	// base URL is set, so a page rendered for one host must not be served
+	// for another
+	var key strings.Builder
+	key.WriteString(name)
+	key.WriteByte(' ')
+	key.WriteString(siteURL(r, ""))
+	key.WriteString(r.URL.RequestURI())
+	for _, vary := range w.Header().Values("Vary") {
+		for _, header := range strings.Split(vary, ",") {
+			header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+			// Compression is applied to the rendered bytes downstream
+			if header == "" || header == "Accept-Encoding" {
+				continue
+			}
+			if header == "*" {
+				return "", false
+			}
+			key.WriteString("\n" + header + ": " + r.Header.Get(header))
+		}
+	}
+	return key.String(), true
+}
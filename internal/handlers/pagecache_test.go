@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/a-h/templ"
+	"github.com/claykom/website/internal/csp"
+	"github.com/claykom/website/internal/testutils"
+	"github.com/gorilla/mux"
+)
+
+// usePageCache installs a page cache for the duration of a test
+func usePageCache(tb testing.TB, capacity int) *PageCache {
+	cache := NewPageCache(capacity)
+	SetPageCache(cache)
+	tb.Cleanup(func() { SetPageCache(nil) })
+	return cache
+}
+
+// nonceComponent renders a counter and the request's CSP nonce
+func nonceComponent(renders *int) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		*renders++
+		_, err := io.WriteString(w, `<script nonce="`+templ.GetNonce(ctx)+`"></script>`)
+		return err
+	})
+}
+
+func requestWithNonce(method, path string, headers map[string]string) (*http.Request, string) {
+	req := testutils.NewTestRequestWithHeaders(method, path, headers)
+	nonce := csp.NewNonce()
+	return req.WithContext(csp.NewContext(req.Context(), csp.Default(), nonce)), nonce
+}
+
+func TestPageCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewPageCache(2)
+	cache.add(newCachedPage("a", []byte("a"), ""))
+	cache.add(newCachedPage("b", []byte("b"), ""))
+
+	// Using "a" makes "b" the eviction candidate
+	if _, ok := cache.get("a"); !ok {
+		t.Fatal("Expected a to be cached")
+	}
+	cache.add(newCachedPage("c", []byte("c"), ""))
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Error("Expected a to be kept")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", cache.Len())
+	}
+
+	hits, misses := cache.Stats()
+	if hits != 2 || misses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %d and %d", hits, misses)
+	}
+
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Errorf("Expected purge to empty the cache, got %d entries", cache.Len())
+	}
+}
+
+func TestPageCacheSkipsLargePages(t *testing.T) {
+	cache := NewPageCache(2)
+	cache.add(newCachedPage("big", make([]byte, maxCachedPageSize+1), ""))
+
+	if cache.Len() != 0 {
+		t.Error("Expected oversized page not to be cached")
+	}
+}
+
+func TestRenderServesCachedPageWithNewNonce(t *testing.T) {
+	usePageCache(t, 8)
+	renders := 0
+
+	first, firstNonce := requestWithNonce("GET", "/page", nil)
+	rr := testutils.NewTestResponseRecorder()
+	render(rr, first, "Test", nonceComponent(&renders))
+	rr.AssertBodyContains(t, firstNonce)
+
+	second, secondNonce := requestWithNonce("GET", "/page", nil)
+	rr = testutils.NewTestResponseRecorder()
+	render(rr, second, "Test", nonceComponent(&renders))
+
+	if renders != 1 {
+		t.Errorf("Expected 1 render, got %d", renders)
+	}
+	rr.AssertBodyContains(t, `nonce="`+secondNonce+`"`)
+	if strings.Contains(rr.Body.String(), firstNonce) {
+		t.Error("Expected the cached nonce to be replaced")
+	}
+}
+
+func TestRenderBypassesPageCache(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+	}{
+		{"post", "POST", nil},
+		{"cookie", "GET", map[string]string{"Cookie": "session=abc"}},
+		{"authorization", "GET", map[string]string{"Authorization": "Bearer token"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := usePageCache(t, 8)
+			renders := 0
+
+			for i := 0; i < 2; i++ {
+				req, _ := requestWithNonce(tt.method, "/page", tt.headers)
+				render(testutils.NewTestResponseRecorder(), req, "Test", nonceComponent(&renders))
+			}
+
+			if renders != 2 {
+				t.Errorf("Expected every request to render, got %d renders", renders)
+			}
+			if cache.Len() != 0 {
+				t.Errorf("Expected nothing cached, got %d entries", cache.Len())
+			}
+		})
+	}
+}
+
+func TestPageCacheKeyVaries(t *testing.T) {
+	key := func(vary, language string) string {
+		rr := testutils.NewTestResponseRecorder()
+		if vary != "" {
+			rr.Header().Set("Vary", vary)
+		}
+		req := testutils.NewTestRequestWithHeaders("GET", "/page?x=1", map[string]string{"Accept-Language": language})
+		k, ok := pageCacheKey(rr, req, "Test")
+		if !ok {
+			t.Fatal("Expected request to be cacheable")
+		}
+		return k
+	}
+
+	if key("", "en") != key("", "de") {
+		t.Error("Expected headers outside Vary to be ignored")
+	}
+	if key("Accept-Language", "en") == key("Accept-Language", "de") {
+		t.Error("Expected Vary headers to be part of the key")
+	}
+	if key("Accept-Encoding", "en") != key("", "en") {
+		t.Error("Expected Accept-Encoding to be ignored, since compression happens after rendering")
+	}
+}
+
+func TestPageCacheKeyIncludesHost(t *testing.T) {
+	key := func(host, proto string) string {
+		req := testutils.NewTestRequestWithHeaders("GET", "/page", map[string]string{"X-Forwarded-Proto": proto})
+		req.Host = host
+		k, _ := pageCacheKey(testutils.NewTestResponseRecorder(), req, "Test")
+		return k
+	}
+
+	// Pages link to themselves under the request's host, so a spoofed Host
+	// must not get its page served to others
+	if key("example.com", "") == key("evil.example", "") {
+		t.Error("Expected the host to be part of the key")
+	}
+	if key("example.com", "") == key("example.com", "https") {
+		t.Error("Expected the scheme to be part of the key")
+	}
+}
+
+func TestBlogReloadPurgesPageCache(t *testing.T) {
+	cache := usePageCache(t, 8)
+	content := fstest.MapFS{
+		"blog/one.md": {Data: []byte("---\ntitle: First Post\nslug: one\ndate: 2024-01-01\n---\n\nOne\n")},
+	}
+	handler := NewBlogHandler(content)
+
+	rr := testutils.NewTestResponseRecorder()
+	handler.ListPosts(rr, testutils.NewTestRequest("GET", "/blog", ""))
+	rr.AssertBodyContains(t, "First Post")
+	if cache.Len() != 1 {
+		t.Fatalf("Expected the list page to be cached, got %d entries", cache.Len())
+	}
+
+	content["blog/two.md"] = &fstest.MapFile{Data: []byte("---\ntitle: Second Post\nslug: two\ndate: 2024-02-01\n---\n\nTwo\n")}
+	if err := handler.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Expected reload to purge the cache, got %d entries", cache.Len())
+	}
+
+	rr = testutils.NewTestResponseRecorder()
+	handler.ListPosts(rr, testutils.NewTestRequest("GET", "/blog", ""))
+	rr.AssertBodyContains(t, "Second Post")
+}
+
+func TestBlogReloadKeepsPostsOnError(t *testing.T) {
+	content := fstest.MapFS{
+		"blog/one.md": {Data: []byte("---\ntitle: First Post\nslug: one\n---\n\nOne\n")},
+	}
+	handler := NewBlogHandler(content)
+	delete(content, "blog/one.md")
+	content["other/readme.md"] = &fstest.MapFile{Data: []byte("x")}
+
+	if err := handler.Reload(); err == nil {
+		t.Fatal("Expected an error without a blog directory")
+	}
+	if _, ok := handler.findPost("one"); !ok {
+		t.Error("Expected previously loaded posts to be kept")
+	}
+}
+
+func TestBlogCheckLoaded(t *testing.T) {
+	content := fstest.MapFS{"other/readme.md": {Data: []byte("x")}}
+	handler := NewBlogHandler(content)
+	if err := handler.CheckLoaded(context.Background()); err == nil {
+		t.Error("Expected the check to fail before posts are loaded")
+	}
+
+	content["blog/one.md"] = &fstest.MapFile{Data: []byte("---\ntitle: First Post\nslug: one\n---\n\nOne\n")}
+	if err := handler.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if err := handler.CheckLoaded(context.Background()); err != nil {
+		t.Errorf("Expected the check to pass after loading, got %v", err)
+	}
+}
+
+func BenchmarkGetPostPageCache(b *testing.B) {
+	handler := NewBlogHandler(os.DirFS("../../content"))
+	posts := handler.publishedPosts()
+	if len(posts) == 0 {
+		b.Skip("no posts in content/blog")
+	}
+	slug := posts[0].Slug
+
+	run := func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			req, _ := requestWithNonce("GET", "/blog/"+slug, nil)
+			req = mux.SetURLVars(req, map[string]string{"slug": slug})
+			handler.GetPost(testutils.NewTestResponseRecorder(), req)
+		}
+	}
+
+	b.Run("uncached", func(b *testing.B) {
+		SetPageCache(nil)
+		run(b)
+	})
+	b.Run("cached", func(b *testing.B) {
+		usePageCache(b, 64)
+		run(b)
+	})
+}
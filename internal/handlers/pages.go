@@ -0,0 +1,37 @@
+package handlers
+
+import "time"
+
+// Page is an HTML page the site serves, listed for the static export
+type Page struct {
+	Path     string
+	Modified time.Time
+}
+
+// HomePage is the home page, which has no content of its own
+var HomePage = Page{Path: "/"}
+
+// Pages returns the post list and every published post
+func (h *BlogHandler) Pages() []Page {
+	published := h.publishedPosts()
+
+	result := []Page{{Path: "/blog", Modified: lastPostUpdate(published)}}
+	for _, post := range published {
+		result = append(result, Page{Path: "/blog/" + post.Slug, Modified: post.UpdatedAt})
+	}
+	return result
+}
+
+// Pages returns the project list and every project
+func (h *PortfolioHandler) Pages() []Page {
+	projects := h.allProjects()
+
+	result := []Page{{Path: "/portfolio"}}
+	for _, project := range projects {
+		if project.UpdatedAt.After(result[0].Modified) {
+			result[0].Modified = project.UpdatedAt
+		}
+		result = append(result, Page{Path: "/portfolio/" + project.Slug, Modified: project.UpdatedAt})
+	}
+	return result
+}
@@ -1,9 +1,19 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
 	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/claykom/website/internal/content"
 	"github.com/claykom/website/internal/models"
 	"github.com/claykom/website/internal/views/pages"
 	"github.com/gorilla/mux"
@@ -11,40 +21,150 @@ import (
 
 // PortfolioHandler handles portfolio-related requests
 type PortfolioHandler struct {
-	// In a real application, this would be a database or repository
+	content fs.FS
+
+	mu       sync.RWMutex
 	projects []models.Project
+	loaded   bool
+}
+
+// NewPortfolioHandler creates a new PortfolioHandler and loads projects
+// from the portfolio directory of content
+func NewPortfolioHandler(content fs.FS) *PortfolioHandler {
+	handler := &PortfolioHandler{content: content}
+	if err := handler.Reload(); err != nil {
+		log.Printf("Error loading portfolio projects: %v", err)
+	}
+	return handler
+}
+
+// Reload re-reads the projects. If the portfolio directory can't be read,
+// the previously loaded projects are kept; a missing directory means no
+// projects.
+func (h *PortfolioHandler) Reload() error {
+	projects, err := loadProjects(h.content)
+	if err != nil {
+		contentLoads.WithLabelValues("portfolio", "error").Inc()
+		return err
+	}
+	contentLoads.WithLabelValues("portfolio", "success").Inc()
+
+	h.mu.Lock()
+	h.projects = projects
+	h.loaded = true
+	h.mu.Unlock()
+	defaultPageCache.Load().Purge()
+	return nil
+}
+
+// CheckLoaded is a health check that fails until projects have been
+// loaded successfully at least once
+func (h *PortfolioHandler) CheckLoaded(ctx context.Context) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if !h.loaded {
+		return errors.New("portfolio projects not loaded")
+	}
+	return nil
 }
 
-// NewPortfolioHandler creates a new PortfolioHandler
-func NewPortfolioHandler() *PortfolioHandler {
-	// Portfolio project showcasing this website
-	return &PortfolioHandler{
-		projects: []models.Project{
-			{
-				ID:           "1",
-				Title:        "Personal Website & Portfolio",
-				Slug:         "personal-website-portfolio",
-				Description:  "A modern, secure Go web application built with security-first design and production-ready deployment",
-				Content:      "This website itself serves as a portfolio piece, demonstrating modern Go web development practices. Built with Go 1.25, it features comprehensive security middleware including rate limiting, input validation, and security headers. The application uses Templ for type-safe HTML templating and follows clean architecture principles with a well-organized internal package structure. Container security is implemented through multi-stage Docker builds, non-root user execution, and read-only filesystems. The project includes automated health checks, structured logging, and supports both HTTP and HTTPS deployment with proper TLS configuration. Additional security measures include Content Security Policy headers, XSS protection, HSTS enforcement, and secure static file serving with path traversal protection. The codebase demonstrates Go best practices with comprehensive error handling, graceful shutdown procedures, and environment-based configuration management.",
-				ImageURL:     "/static/images/website-portfolio.jpg",
-				ProjectURL:   "https://claykom.dev",
-				GithubURL:    "https://github.com/claykom/website",
-				Technologies: []string{"Go", "Templ", "Docker", "Nginx", "Security"},
-				Featured:     true,
-				CreatedAt:    time.Now().AddDate(0, 0, -3),
-				UpdatedAt:    time.Now(),
-			},
-		},
+// loadProjects reads the markdown files in the portfolio directory,
+// featured projects first and then newest first. The body of each file is
+// the project's description as plain text.
+func loadProjects(fsys fs.FS) ([]models.Project, error) {
+	files, err := fs.ReadDir(fsys, content.PortfolioDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []models.Project{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	projects := []models.Project{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".md") {
+			continue
+		}
+
+		filePath := path.Join(content.PortfolioDir, file.Name())
+		project, err := parseProjectFile(fsys, filePath)
+		if err != nil {
+			log.Printf("Error parsing %s: %v", filePath, err)
+			continue
+		}
+		projects = append(projects, project)
+	}
+
+	sort.SliceStable(projects, func(i, j int) bool {
+		if projects[i].Featured != projects[j].Featured {
+			return projects[i].Featured
+		}
+		return projects[i].CreatedAt.After(projects[j].CreatedAt)
+	})
+	return projects, nil
+}
+
+// parseProjectFile parses a project markdown file with frontmatter
+func parseProjectFile(fsys fs.FS, filePath string) (models.Project, error) {
+	data, err := fs.ReadFile(fsys, filePath)
+	if err != nil {
+		return models.Project{}, err
+	}
+	fm, body, err := content.Split(data)
+	if err != nil {
+		return models.Project{}, err
+	}
+
+	// Invalid values are left zero; the check command reports them
+	project := models.Project{
+		ID:           fm["slug"],
+		Title:        fm["title"],
+		Slug:         fm["slug"],
+		Description:  fm["description"],
+		Content:      strings.TrimSpace(body),
+		ImageURL:     fm["image"],
+		ProjectURL:   fm["project_url"],
+		GithubURL:    fm["github_url"],
+		Technologies: fm.List("technologies"),
+	}
+	project.Featured, _ = strconv.ParseBool(fm["featured"])
+	project.CreatedAt, _ = fm.Date("date")
+	project.UpdatedAt, _ = fm.Date("updated")
+
+	// As for posts, fall back to the file's modification time and then
+	// the creation date
+	if project.UpdatedAt.IsZero() {
+		if info, err := fs.Stat(fsys, filePath); err == nil {
+			project.UpdatedAt = info.ModTime()
+		}
+	}
+	if project.UpdatedAt.IsZero() {
+		project.UpdatedAt = project.CreatedAt
 	}
+
+	return project, nil
+}
+
+// allProjects returns the loaded projects
+func (h *PortfolioHandler) allProjects() []models.Project {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.projects
 }
 
 // ListProjects returns all portfolio projects
 func (h *PortfolioHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
-	component := pages.PortfolioList(h.projects)
-	if err := component.Render(r.Context(), w); err != nil {
-		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	projects := h.allProjects()
+	var lastModified time.Time
+	for _, project := range projects {
+		if project.UpdatedAt.After(lastModified) {
+			lastModified = project.UpdatedAt
+		}
+	}
+	if checkNotModified(w, r, contentETag(r, "PortfolioList", projects), lastModified) {
 		return
 	}
+	render(w, r, "PortfolioList", pages.PortfolioList(projects, portfolioListMeta(r)))
 }
 
 // GetProject returns a single project by slug
@@ -58,13 +178,12 @@ func (h *PortfolioHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Find project by slug
-	for _, project := range h.projects {
+	for _, project := range h.allProjects() {
 		if project.Slug == slug {
-			component := pages.ProjectDetail(project)
-			if err := component.Render(r.Context(), w); err != nil {
-				http.Error(w, "Error rendering page", http.StatusInternalServerError)
+			if checkNotModified(w, r, contentETag(r, "ProjectDetail", project), project.UpdatedAt) {
 				return
 			}
+			render(w, r, "ProjectDetail", pages.ProjectDetail(project, projectMeta(r, project)))
 			return
 		}
 	}
@@ -72,43 +191,46 @@ func (h *PortfolioHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Project not found", http.StatusNotFound)
 }
 
-// API handlers (commented out - keeping for reference)
-// func (h *PortfolioHandler) ListProjectsAPI(w http.ResponseWriter, r *http.Request) {
-// 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-// 		"projects": h.projects,
-// 		"count":    len(h.projects),
-// 	})
-// }
-//
-// func (h *PortfolioHandler) GetProjectAPI(w http.ResponseWriter, r *http.Request) {
-// 	vars := mux.Vars(r)
-// 	slug := vars["slug"]
-//
-// 	if slug == "" {
-// 		respondWithError(w, http.StatusBadRequest, "Slug parameter is required")
-// 		return
-// 	}
-//
-// 	for _, project := range h.projects {
-// 		if project.Slug == slug {
-// 			respondWithJSON(w, http.StatusOK, project)
-// 			return
-// 		}
-// 	}
-//
-// 	respondWithError(w, http.StatusNotFound, "Project not found")
-// }
-//
-// func (h *PortfolioHandler) ListFeaturedProjectsAPI(w http.ResponseWriter, r *http.Request) {
-// 	featuredProjects := make([]models.Project, 0)
-// 	for _, project := range h.projects {
-// 		if project.Featured {
-// 			featuredProjects = append(featuredProjects, project)
-// 		}
-// 	}
-//
-// 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-// 		"projects": featuredProjects,
-// 		"count":    len(featuredProjects),
-// 	})
-// }
+// ListProjectsAPI returns all portfolio projects as JSON
+func (h *PortfolioHandler) ListProjectsAPI(w http.ResponseWriter, r *http.Request) {
+	projects := h.allProjects()
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"projects": projects,
+		"count":    len(projects),
+	})
+}
+
+// GetProjectAPI returns a single project by slug as JSON
+func (h *PortfolioHandler) GetProjectAPI(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	slug := vars["slug"]
+
+	if slug == "" {
+		respondWithError(w, http.StatusBadRequest, "Slug parameter is required")
+		return
+	}
+
+	for _, project := range h.allProjects() {
+		if project.Slug == slug {
+			respondWithJSON(w, http.StatusOK, project)
+			return
+		}
+	}
+
+	respondWithError(w, http.StatusNotFound, "Project not found")
+}
+
+// ListFeaturedProjectsAPI returns featured projects as JSON
+func (h *PortfolioHandler) ListFeaturedProjectsAPI(w http.ResponseWriter, r *http.Request) {
+	featuredProjects := make([]models.Project, 0)
+	for _, project := range h.allProjects() {
+		if project.Featured {
+			featuredProjects = append(featuredProjects, project)
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"projects": featuredProjects,
+		"count":    len(featuredProjects),
+	})
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/a-h/templ"
+	"github.com/claykom/website/internal/csp"
+	"github.com/claykom/website/internal/tracing"
+)
+
+// render renders a templ component inside a "templ.render" span. Output is
+// buffered so a failed render can still answer 500, and served from the
+// page cache when one is set.
+func render(w http.ResponseWriter, r *http.Request, name string, component templ.Component) {
+	ctx, span := tracing.Start(r.Context(), "templ.render",
+		tracing.WithAttributes(tracing.String("templ.component", name)),
+	)
+	defer span.End()
+
+	cache := defaultPageCache.Load()
+	key, cacheable := pageCacheKey(w, r, name)
+	if cache != nil && cacheable {
+		if page, ok := cache.get(key); ok {
+			pageCacheRequests.WithLabelValues("hit").Inc()
+			span.SetAttributes(tracing.Bool("page_cache.hit", true))
+			page.writeTo(w, csp.Nonce(ctx))
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := component.Render(ctx, &buf); err != nil {
+		span.RecordError(err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+		return
+	}
+
+	if cache != nil {
+		if cacheable {
+			pageCacheRequests.WithLabelValues("miss").Inc()
+			cache.add(newCachedPage(key, buf.Bytes(), csp.Nonce(ctx)))
+		} else {
+			pageCacheRequests.WithLabelValues("bypass").Inc()
+		}
+	}
+
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// sitemapMaxURLs is the most URLs the sitemap protocol allows in one file.
+// Past it, /sitemap.xml becomes an index of numbered sitemaps.
+var sitemapMaxURLs = 50000
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// sitemapEntry is a <url> in a sitemap or a <sitemap> in an index
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type urlSet struct {
+	XMLName xml.Name       `xml:"urlset"`
+	Xmlns   string         `xml:"xmlns,attr"`
+	URLs    []sitemapEntry `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	Xmlns    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// SitemapHandler serves the XML sitemap of the pages returned by pages
+type SitemapHandler struct {
+	pages   func() []Page
+	baseURL string
+}
+
+// NewSitemapHandler creates a sitemap of pages. Locations are absolute
+// URLs under baseURL, or under the request's scheme and host when baseURL
+// is empty.
+func NewSitemapHandler(pages func() []Page, baseURL string) *SitemapHandler {
+	return &SitemapHandler{pages: pages, baseURL: baseURL}
+}
+
+// Paths returns the sitemap's URL paths: /sitemap.xml, followed by the
+// numbered sitemaps when it is an index
+func (h *SitemapHandler) Paths() []string {
+	paths := []string{"/sitemap.xml"}
+	if parts := sitemapParts(len(h.pages())); parts > 1 {
+		for n := 1; n <= parts; n++ {
+			paths = append(paths, fmt.Sprintf("/sitemap-%d.xml", n))
+		}
+	}
+	return paths
+}
+
+// Sitemap serves /sitemap.xml: every page, or an index of the numbered
+// sitemaps when there are too many pages for one file
+func (h *SitemapHandler) Sitemap(w http.ResponseWriter, r *http.Request) {
+	pages := h.pages()
+	base := siteURL(r, h.baseURL)
+
+	parts := sitemapParts(len(pages))
+	if parts <= 1 {
+		h.write(w, r, "Sitemap", urlSet{Xmlns: sitemapNamespace, URLs: entries(base, pages)}, pages)
+		return
+	}
+
+	index := sitemapIndex{Xmlns: sitemapNamespace}
+	for n := 1; n <= parts; n++ {
+		part := pages[(n-1)*sitemapMaxURLs : min(n*sitemapMaxURLs, len(pages))]
+		index.Sitemaps = append(index.Sitemaps, sitemapEntry{
+			Loc:     fmt.Sprintf("%s/sitemap-%d.xml", base, n),
+			LastMod: lastMod(latest(part)),
+		})
+	}
+	h.write(w, r, "SitemapIndex", index, pages)
+}
+
+// SitemapPart serves /sitemap-{n}.xml, the nth file of a sitemap index
+func (h *SitemapHandler) SitemapPart(w http.ResponseWriter, r *http.Request) {
+	pages := h.pages()
+	n, err := strconv.Atoi(mux.Vars(r)["n"])
+	if parts := sitemapParts(len(pages)); err != nil || parts <= 1 || n < 1 || n > parts {
+		NotFound(w, r)
+		return
+	}
+
+	part := pages[(n-1)*sitemapMaxURLs : min(n*sitemapMaxURLs, len(pages))]
+	h.write(w, r, "Sitemap"+strconv.Itoa(n), urlSet{Xmlns: sitemapNamespace, URLs: entries(siteURL(r, h.baseURL), part)}, part)
+}
+
+// write encodes doc as XML, answering conditional requests from the pages
+// it lists
+func (h *SitemapHandler) write(w http.ResponseWriter, r *http.Request, name string, doc any, pages []Page) {
+	if checkNotModified(w, r, contentETag(r, name, doc), latest(pages)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		log.Printf("Error encoding sitemap: %v", err)
+		return
+	}
+	w.Write([]byte("\n"))
+}
+
+// sitemapParts returns how many sitemap files count pages need
+func sitemapParts(count int) int {
+	return (count + sitemapMaxURLs - 1) / sitemapMaxURLs
+}
+
+// entries turns pages into sitemap entries under base
+func entries(base string, pages []Page) []sitemapEntry {
+	result := make([]sitemapEntry, 0, len(pages))
+	for _, page := range pages {
+		result = append(result, sitemapEntry{Loc: base + page.Path, LastMod: lastMod(page.Modified)})
+	}
+	return result
+}
+
+// latest returns the most recent modification time of pages
+func latest(pages []Page) time.Time {
+	var result time.Time
+	for _, page := range pages {
+		if page.Modified.After(result) {
+			result = page.Modified
+		}
+	}
+	return result
+}
+
+// lastMod formats a <lastmod> date; zero times are left out
+func lastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.DateOnly)
+}
+
+// Robots serves /robots.txt, disallowing the path prefixes in disallow
+// and pointing crawlers at the sitemap
+func Robots(disallow []string, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var b strings.Builder
+		b.WriteString("User-agent: *\n")
+		if len(disallow) == 0 {
+			// An empty Disallow allows everything
+			b.WriteString("Disallow:\n")
+		}
+		for _, prefix := range disallow {
+			fmt.Fprintf(&b, "Disallow: %s\n", prefix)
+		}
+		fmt.Fprintf(&b, "\nSitemap: %s/sitemap.xml\n", siteURL(r, baseURL))
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", htmlCacheControl)
+		w.Write([]byte(b.String()))
+	}
+}
+
+// siteURL returns baseURL, or when it is empty the scheme and host the
+// request was made to
+func siteURL(r *http.Request, baseURL string) string {
+	if baseURL != "" {
+		return baseURL
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claykom/website/internal/testutils"
+	"github.com/gorilla/mux"
+)
+
+func testPages(count int) func() []Page {
+	return func() []Page {
+		pages := []Page{HomePage}
+		for i := 1; i < count; i++ {
+			pages = append(pages, Page{Path: fmt.Sprintf("/blog/post-%d", i), Modified: time.Date(2025, 1, i, 12, 0, 0, 0, time.UTC)})
+		}
+		return pages
+	}
+}
+
+func TestSitemap(t *testing.T) {
+	handler := NewSitemapHandler(testPages(3), "https://example.com")
+
+	rr := testutils.NewTestResponseRecorder()
+	handler.Sitemap(rr, testutils.NewTestRequest("GET", "/sitemap.xml", ""))
+	rr.AssertStatusCode(t, http.StatusOK)
+	rr.AssertContentType(t, "application/xml; charset=utf-8")
+
+	var set urlSet
+	if err := xml.Unmarshal(rr.Body.Bytes(), &set); err != nil {
+		t.Fatalf("Expected valid XML, got %v", err)
+	}
+	if set.Xmlns != sitemapNamespace {
+		t.Errorf("Expected the sitemap namespace, got %q", set.Xmlns)
+	}
+	expected := []sitemapEntry{
+		{Loc: "https://example.com/"},
+		{Loc: "https://example.com/blog/post-1", LastMod: "2025-01-01"},
+		{Loc: "https://example.com/blog/post-2", LastMod: "2025-01-02"},
+	}
+	if fmt.Sprint(set.URLs) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, set.URLs)
+	}
+
+	// The newest page dates the sitemap for conditional requests
+	rr.AssertHeader(t, "Last-Modified", "Thu, 02 Jan 2025 12:00:00 GMT")
+	req := testutils.NewTestRequestWithHeaders("GET", "/sitemap.xml", map[string]string{"If-None-Match": rr.Header().Get("ETag")})
+	rr = testutils.NewTestResponseRecorder()
+	handler.Sitemap(rr, req)
+	rr.AssertStatusCode(t, http.StatusNotModified)
+}
+
+func TestSitemapIndex(t *testing.T) {
+	defer func(max int) { sitemapMaxURLs = max }(sitemapMaxURLs)
+	sitemapMaxURLs = 2
+	handler := NewSitemapHandler(testPages(5), "https://example.com")
+
+	if paths := handler.Paths(); strings.Join(paths, " ") != "/sitemap.xml /sitemap-1.xml /sitemap-2.xml /sitemap-3.xml" {
+		t.Errorf("Expected the index and three sitemaps, got %v", paths)
+	}
+
+	rr := testutils.NewTestResponseRecorder()
+	handler.Sitemap(rr, testutils.NewTestRequest("GET", "/sitemap.xml", ""))
+	var index sitemapIndex
+	if err := xml.Unmarshal(rr.Body.Bytes(), &index); err != nil {
+		t.Fatalf("Expected a valid sitemap index, got %v", err)
+	}
+	if len(index.Sitemaps) != 3 || index.Sitemaps[2].Loc != "https://example.com/sitemap-3.xml" || index.Sitemaps[2].LastMod != "2025-01-04" {
+		t.Errorf("Expected three sitemaps dated by their pages, got %v", index.Sitemaps)
+	}
+
+	tests := []struct {
+		n              string
+		expectedStatus int
+		expectedURLs   int
+	}{
+		{"1", http.StatusOK, 2},
+		{"3", http.StatusOK, 1},
+		{"0", http.StatusNotFound, 0},
+		{"4", http.StatusNotFound, 0},
+	}
+	for _, tt := range tests {
+		t.Run("part "+tt.n, func(t *testing.T) {
+			req := mux.SetURLVars(testutils.NewTestRequest("GET", "/sitemap-"+tt.n+".xml", ""), map[string]string{"n": tt.n})
+			rr := testutils.NewTestResponseRecorder()
+			handler.SitemapPart(rr, req)
+			rr.AssertStatusCode(t, tt.expectedStatus)
+
+			if tt.expectedStatus == http.StatusOK {
+				var set urlSet
+				if err := xml.Unmarshal(rr.Body.Bytes(), &set); err != nil || len(set.URLs) != tt.expectedURLs {
+					t.Errorf("Expected %d URLs, got %v, %v", tt.expectedURLs, set.URLs, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSitemapPartWithoutIndex(t *testing.T) {
+	handler := NewSitemapHandler(testPages(3), "")
+	req := mux.SetURLVars(testutils.NewTestRequest("GET", "/sitemap-1.xml", ""), map[string]string{"n": "1"})
+	rr := testutils.NewTestResponseRecorder()
+	handler.SitemapPart(rr, req)
+	rr.AssertStatusCode(t, http.StatusNotFound)
+}
+
+func TestRobots(t *testing.T) {
+	tests := []struct {
+		name     string
+		disallow []string
+		baseURL  string
+		headers  map[string]string
+		expected string
+	}{
+		{
+			name:     "disallowed paths",
+			disallow: []string{"/admin/", "/preview/"},
+			baseURL:  "https://example.com",
+			expected: "User-agent: *\nDisallow: /admin/\nDisallow: /preview/\n\nSitemap: https://example.com/sitemap.xml\n",
+		},
+		{
+			name:     "allow everything",
+			baseURL:  "https://example.com/site",
+			expected: "User-agent: *\nDisallow:\n\nSitemap: https://example.com/site/sitemap.xml\n",
+		},
+		{
+			name:     "request host",
+			disallow: []string{"/"},
+			expected: "User-agent: *\nDisallow: /\n\nSitemap: http://example.com/sitemap.xml\n",
+		},
+		{
+			name:     "behind a TLS proxy",
+			disallow: []string{"/"},
+			headers:  map[string]string{"X-Forwarded-Proto": "https"},
+			expected: "User-agent: *\nDisallow: /\n\nSitemap: https://example.com/sitemap.xml\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := testutils.NewTestResponseRecorder()
+			Robots(tt.disallow, tt.baseURL)(rr, testutils.NewTestRequestWithHeaders("GET", "/robots.txt", tt.headers))
+			rr.AssertStatusCode(t, http.StatusOK)
+			rr.AssertContentType(t, "text/plain; charset=utf-8")
+			if rr.Body.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+func BenchmarkSitemap(b *testing.B) {
+	handler := NewSitemapHandler(testPages(1000), "https://example.com")
+	req := testutils.NewTestRequest("GET", "/sitemap.xml", "")
+	for i := 0; i < b.N; i++ {
+		handler.Sitemap(testutils.NewTestResponseRecorder(), req)
+	}
+}
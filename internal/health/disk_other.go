@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || freebsd)
+
+package health
+
+import "context"
+
+// DiskSpace always passes on platforms without statfs
+func DiskSpace(path string, minFree uint64) Check {
+	return func(ctx context.Context) error {
+		return nil
+	}
+}
@@ -0,0 +1,25 @@
+//go:build linux || darwin || freebsd
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+)
+
+// DiskSpace checks that the file system holding path has at least minFree
+// bytes available to unprivileged users
+func DiskSpace(path string, minFree uint64) Check {
+	return func(ctx context.Context) error {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(path, &st); err != nil {
+			return err
+		}
+		free := uint64(st.Bavail) * uint64(st.Bsize)
+		if free < minFree {
+			return fmt.Errorf("%d MB free in %s, want at least %d MB", free>>20, path, minFree>>20)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,174 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports whether something the site depends on is usable. It
+// should return promptly once ctx is done.
+type Check func(ctx context.Context) error
+
+// DefaultTimeout bounds checks registered without a timeout
+const DefaultTimeout = 2 * time.Second
+
+// Check statuses, and the report status while draining
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// Report is the outcome of all checks. Status is "ok" only if every check
+// passed and the registry isn't draining.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Registry holds the named checks that decide readiness
+type Registry struct {
+	mu       sync.RWMutex
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+type namedCheck struct {
+	name    string
+	timeout time.Duration
+	check   Check
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a check, replacing any check with the same name. A zero
+// timeout means DefaultTimeout.
+func (r *Registry) Register(name string, timeout time.Duration, check Check) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := namedCheck{name: name, timeout: timeout, check: check}
+	for i := range r.checks {
+		if r.checks[i].name == name {
+			r.checks[i] = c
+			return
+		}
+	}
+	r.checks = append(r.checks, c)
+}
+
+// Drain marks the server as shutting down. From then on the report fails
+// so load balancers stop sending new requests.
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+// Draining reports whether Drain has been called
+func (r *Registry) Draining() bool {
+	return r.draining.Load()
+}
+
+// Run runs all checks concurrently, each bounded by its timeout, and
+// returns their results in registration order. Checks are skipped while
+// draining.
+func (r *Registry) Run(ctx context.Context) Report {
+	if r.Draining() {
+		return Report{Status: StatusDraining, Checks: []Result{}}
+	}
+
+	r.mu.RLock()
+	checks := make([]namedCheck, len(r.checks))
+	copy(checks, r.checks)
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make([]Result, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// run runs one check. A check that ignores its context is abandoned when
+// the timeout passes rather than holding up the report.
+func run(ctx context.Context, c namedCheck) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", c.timeout)
+	}
+
+	result := Result{
+		Name:      c.name,
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// DirReadable checks that dir in fsys can be listed and isn't empty
+func DirReadable(fsys fs.FS, dir string) Check {
+	return func(ctx context.Context) error {
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return errors.New("directory is empty")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"math"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func ok(ctx context.Context) error { return nil }
+
+func TestRegistryRun(t *testing.T) {
+	tests := []struct {
+		name           string
+		check          Check
+		timeout        time.Duration
+		expectedStatus string
+		expectedError  string
+	}{
+		{"passing", ok, 0, StatusOK, ""},
+		{"failing", func(ctx context.Context) error { return errors.New("broken") }, 0, StatusFail, "broken"},
+		{"honours timeout", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, 10 * time.Millisecond, StatusFail, "timed out after 10ms"},
+		{"ignores timeout", func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}, 10 * time.Millisecond, StatusFail, "timed out after 10ms"},
+		{"panics", func(ctx context.Context) error { panic("boom") }, 0, StatusFail, "check panicked: boom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			registry.Register("first", 0, ok)
+			registry.Register("second", tt.timeout, tt.check)
+
+			start := time.Now()
+			report := registry.Run(context.Background())
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("Expected checks to be bounded by their timeout, took %s", elapsed)
+			}
+
+			if report.Status != tt.expectedStatus {
+				t.Errorf("Expected report status %s, got %s", tt.expectedStatus, report.Status)
+			}
+			if len(report.Checks) != 2 || report.Checks[0].Name != "first" || report.Checks[1].Name != "second" {
+				t.Fatalf("Expected results in registration order, got %+v", report.Checks)
+			}
+			result := report.Checks[1]
+			if result.Status != tt.expectedStatus {
+				t.Errorf("Expected check status %s, got %s", tt.expectedStatus, result.Status)
+			}
+			if !strings.Contains(result.Error, tt.expectedError) {
+				t.Errorf("Expected error containing %q, got %q", tt.expectedError, result.Error)
+			}
+			if result.LatencyMS < 0 {
+				t.Errorf("Expected a non-negative latency, got %v", result.LatencyMS)
+			}
+		})
+	}
+}
+
+func TestRegistryReplacesCheck(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("content", 0, func(ctx context.Context) error { return errors.New("not loaded") })
+	registry.Register("content", 0, ok)
+
+	report := registry.Run(context.Background())
+	if len(report.Checks) != 1 || report.Status != StatusOK {
+		t.Errorf("Expected the second registration to replace the first, got %+v", report)
+	}
+}
+
+func TestRegistryDrain(t *testing.T) {
+	registry := NewRegistry()
+	ran := false
+	registry.Register("content", 0, func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	registry.Drain()
+	report := registry.Run(context.Background())
+
+	if report.Status != StatusDraining {
+		t.Errorf("Expected status %s, got %s", StatusDraining, report.Status)
+	}
+	if ran {
+		t.Error("Expected checks to be skipped while draining")
+	}
+	if !registry.Draining() {
+		t.Error("Expected Draining to report true")
+	}
+}
+
+func TestDirReadable(t *testing.T) {
+	fsys := fstest.MapFS{
+		"static/css/style.css": {Data: []byte("body{}")},
+		"empty":                {Mode: fs.ModeDir | 0755},
+	}
+
+	tests := []struct {
+		dir         string
+		expectError bool
+	}{
+		{"static", false},
+		{"empty", true},
+		{"missing", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			err := DirReadable(fsys, tt.dir)(context.Background())
+			if (err != nil) != tt.expectError {
+				t.Errorf("Expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := DiskSpace(dir, 1)(context.Background()); err != nil {
+		t.Errorf("Expected a byte to be free, got %v", err)
+	}
+	if err := DiskSpace(dir, math.MaxUint64)(context.Background()); err == nil {
+		t.Error("Expected an error when asking for more space than exists")
+	}
+	if err := DiskSpace(dir+"/missing", 1)(context.Background()); err == nil {
+		t.Error("Expected an error for a missing path")
+	}
+}
+
+func BenchmarkRegistryRun(b *testing.B) {
+	registry := NewRegistry()
+	for _, name := range []string{"content", "static", "certificate", "disk"} {
+		registry.Register(name, 0, ok)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		registry.Run(context.Background())
+	}
+}
@@ -0,0 +1,292 @@
+package linkcheck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/claykom/website/internal/tracing"
+	"golang.org/x/net/html"
+)
+
+// maxRedirects bounds how many redirects are followed for one link
+const maxRedirects = 5
+
+// Problem is a broken link, missing anchor or image without alt text on a
+// page
+type Problem struct {
+	Page    string
+	Link    string
+	Message string
+}
+
+// String formats the problem as "page: link: message"
+func (p Problem) String() string {
+	if p.Link == "" {
+		return p.Page + ": " + p.Message
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Page, p.Link, p.Message)
+}
+
+// Options configures a check
+type Options struct {
+	// Pages are the paths of the pages to check. Links to other pages
+	// are followed to see that they resolve, but not checked themselves.
+	Pages []string
+	// ExternalHosts allows links to these hosts to be requested; "*"
+	// allows any host. Links to other hosts aren't checked.
+	ExternalHosts []string
+	// Client makes the external requests. If nil, a client that
+	// propagates the check's trace context is used.
+	Client *http.Client
+}
+
+// target is what a link resolved to
+type target struct {
+	status int
+	// ids holds the element IDs of an HTML response, for fragments
+	ids map[string]bool
+	err error
+}
+
+// checker caches link targets across pages
+type checker struct {
+	ctx      context.Context
+	handler  http.Handler
+	opts     Options
+	internal map[string]*target
+	external map[string]*target
+}
+
+// Check renders each page through handler and reports links to internal
+// pages and static files that don't answer 200, fragments with no
+// matching id on the target page, images without alt text, and failing
+// links to allowed external hosts. Internal links are followed through
+// redirects.
+func Check(handler http.Handler, opts Options) []Problem {
+	if opts.Client == nil {
+		opts.Client = &http.Client{Transport: tracing.NewTransport(nil)}
+	}
+	ctx, span := tracing.Start(context.Background(), "linkcheck")
+	defer span.End()
+
+	c := &checker{
+		ctx:      ctx,
+		handler:  handler,
+		opts:     opts,
+		internal: map[string]*target{},
+		external: map[string]*target{},
+	}
+
+	var problems []Problem
+	for _, page := range opts.Pages {
+		problems = append(problems, c.checkPage(page)...)
+	}
+	return problems
+}
+
+// checkPage checks the links and images on one page
+func (c *checker) checkPage(page string) []Problem {
+	status, body, _ := c.get(page)
+	if status != http.StatusOK {
+		return []Problem{{Page: page, Message: fmt.Sprintf("status %d", status)}}
+	}
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return []Problem{{Page: page, Message: err.Error()}}
+	}
+	c.internal[page] = &target{status: status, ids: ids(doc)}
+
+	base := &url.URL{Path: page}
+	var problems []Problem
+	for n := range doc.Descendants() {
+		if n.Type != html.ElementNode {
+			continue
+		}
+
+		if n.Data == "img" {
+			if alt, ok := attr(n, "alt"); !ok || strings.TrimSpace(alt) == "" {
+				src, _ := attr(n, "src")
+				problems = append(problems, Problem{Page: page, Link: src, Message: "image without alt text"})
+			}
+		}
+
+		link, ok := linkAttr(n)
+		if !ok {
+			continue
+		}
+		if message := c.checkLink(base, link); message != "" {
+			problems = append(problems, Problem{Page: page, Link: link, Message: message})
+		}
+	}
+	return problems
+}
+
+// checkLink returns what is wrong with link on the page at base, or ""
+func (c *checker) checkLink(base *url.URL, link string) string {
+	u, err := base.Parse(link)
+	if err != nil {
+		return "invalid URL"
+	}
+
+	switch {
+	case u.Scheme == "" && u.Host == "":
+		t := c.resolve(u.Path)
+		if t.status != http.StatusOK {
+			if strings.HasPrefix(u.Path, "/static/") {
+				return fmt.Sprintf("missing static file (status %d)", t.status)
+			}
+			return fmt.Sprintf("broken link (status %d)", t.status)
+		}
+		if u.Fragment != "" && u.Fragment != "top" && t.ids != nil && !t.ids[u.Fragment] {
+			return "missing anchor #" + u.Fragment
+		}
+	case u.Scheme == "http" || u.Scheme == "https":
+		if !c.externalAllowed(u.Hostname()) {
+			return ""
+		}
+		t := c.fetchExternal(u)
+		if t.err != nil {
+			return t.err.Error()
+		}
+		if t.status >= 400 {
+			return fmt.Sprintf("status %d", t.status)
+		}
+	}
+	// mailto:, tel: and other schemes aren't checked
+	return ""
+}
+
+// resolve requests an internal path, following redirects, and caches the
+// result
+func (c *checker) resolve(urlPath string) *target {
+	if t, ok := c.internal[urlPath]; ok {
+		return t
+	}
+
+	t := &target{}
+	current := urlPath
+	for range maxRedirects {
+		status, body, header := c.get(current)
+		t.status = status
+		location := header.Get("Location")
+		if status < 300 || status >= 400 || location == "" {
+			if status == http.StatusOK && strings.HasPrefix(header.Get("Content-Type"), "text/html") {
+				if doc, err := html.Parse(bytes.NewReader(body)); err == nil {
+					t.ids = ids(doc)
+				}
+			}
+			break
+		}
+
+		next, err := (&url.URL{Path: current}).Parse(location)
+		if err != nil || next.Host != "" {
+			// Redirects off the site, e.g. to the canonical host, count
+			// as resolved
+			t.status = http.StatusOK
+			break
+		}
+		current = next.Path
+	}
+
+	c.internal[urlPath] = t
+	return t
+}
+
+// get requests urlPath from the handler
+func (c *checker) get(urlPath string) (int, []byte, http.Header) {
+	req := httptest.NewRequest(http.MethodGet, urlPath, nil)
+	req.Header.Set("Accept", "text/html,*/*")
+	rr := httptest.NewRecorder()
+	c.handler.ServeHTTP(rr, req)
+	return rr.Code, rr.Body.Bytes(), rr.Header()
+}
+
+// externalAllowed reports whether host is on the allowlist
+func (c *checker) externalAllowed(host string) bool {
+	return slices.Contains(c.opts.ExternalHosts, "*") || slices.Contains(c.opts.ExternalHosts, host)
+}
+
+// fetchExternal requests u with HEAD, falling back to GET for servers
+// that don't support it, and caches the result per URL
+func (c *checker) fetchExternal(u *url.URL) *target {
+	key := u.String()
+	if u.Fragment != "" {
+		key = strings.TrimSuffix(key, "#"+u.EscapedFragment())
+	}
+	if t, ok := c.external[key]; ok {
+		return t
+	}
+
+	t := &target{}
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
+		req, err := http.NewRequestWithContext(ctx, method, key, nil)
+		if err != nil {
+			cancel()
+			t.err = err
+			break
+		}
+		resp, err := c.opts.Client.Do(req)
+		cancel()
+		if err != nil {
+			t.err = err
+			break
+		}
+		resp.Body.Close()
+		t.status = resp.StatusCode
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+			break
+		}
+	}
+
+	c.external[key] = t
+	return t
+}
+
+// linkAttr returns the URL an element links to or loads
+func linkAttr(n *html.Node) (string, bool) {
+	switch n.Data {
+	case "a", "link":
+		return attr(n, "href")
+	case "img", "script", "source", "iframe":
+		return attr(n, "src")
+	}
+	return "", false
+}
+
+// attr returns the value of an element's attribute
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// ids collects the targets a fragment can refer to: element IDs, and
+// the names of <a> elements
+func ids(doc *html.Node) map[string]bool {
+	found := map[string]bool{}
+	for n := range doc.Descendants() {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		if id, ok := attr(n, "id"); ok {
+			found[id] = true
+		}
+		if n.Data == "a" {
+			if name, ok := attr(n, "name"); ok {
+				found[name] = true
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,171 @@
+package linkcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testSite serves pages whose bodies are given by path, with HTML
+// content type, and a stylesheet under /static/
+func testSite(pages map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/static/style.css":
+			w.Header().Set("Content-Type", "text/css")
+			w.Write([]byte("body {}"))
+			return
+		case "/old":
+			http.Redirect(w, r, "/about", http.StatusMovedPermanently)
+			return
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+			return
+		}
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(body))
+	})
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		problems []string
+	}{
+		{
+			name: "valid links",
+			page: `<link rel="stylesheet" href="/static/style.css">
+				<a href="/about">About</a> <a href="about#team">Team</a> <a href="#intro">Intro</a>
+				<a href="#top">Top</a> <a href="/about?x=1">Query</a> <a href="/old">Old</a>
+				<a href="mailto:me@example.com">Mail</a> <a href="https://example.org/">External</a>
+				<h2 id="intro">Intro</h2> <img src="/static/style.css" alt="Logo">`,
+		},
+		{
+			name:     "missing page",
+			page:     `<a href="/blog/missing-post">Post</a>`,
+			problems: []string{"/: /blog/missing-post: broken link (status 404)"},
+		},
+		{
+			name:     "missing static file",
+			page:     `<img src="/static/images/missing.jpg" alt="Missing">`,
+			problems: []string{"/: /static/images/missing.jpg: missing static file (status 404)"},
+		},
+		{
+			name: "missing anchors",
+			page: `<a href="/about#history">History</a> <a href="#nowhere">Nowhere</a>`,
+			problems: []string{
+				"/: /about#history: missing anchor #history",
+				"/: #nowhere: missing anchor #nowhere",
+			},
+		},
+		{
+			name: "images without alt text",
+			page: `<img src="/static/style.css"> <img src="/static/style.css" alt=" ">`,
+			problems: []string{
+				"/: /static/style.css: image without alt text",
+				"/: /static/style.css: image without alt text",
+			},
+		},
+		{
+			name:     "redirect loop",
+			page:     `<a href="/loop">Loop</a>`,
+			problems: []string{"/: /loop: broken link (status 302)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := testSite(map[string]string{
+				"/":      tt.page,
+				"/about": `<section id="team"></section><a name="contact"></a>`,
+			})
+
+			problems := Check(handler, Options{Pages: []string{"/"}})
+			if len(problems) != len(tt.problems) {
+				t.Fatalf("Expected %d problems, got %v", len(tt.problems), problems)
+			}
+			for i, expected := range tt.problems {
+				if problems[i].String() != expected {
+					t.Errorf("Expected %q, got %q", expected, problems[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckPageStatus(t *testing.T) {
+	problems := Check(testSite(map[string]string{}), Options{Pages: []string{"/gone"}})
+	if len(problems) != 1 || problems[0].String() != "/gone: status 404" {
+		t.Errorf("Expected the missing page to be reported, got %v", problems)
+	}
+}
+
+func TestCheckExternal(t *testing.T) {
+	requests := 0
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case r.URL.Path == "/ok":
+		case r.URL.Path == "/head-not-allowed" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/head-not-allowed":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer external.Close()
+	host := strings.TrimPrefix(external.URL, "http://")
+	hostname := host[:strings.LastIndexByte(host, ':')]
+
+	page := `<a href="` + external.URL + `/ok">OK</a>
+		<a href="` + external.URL + `/ok#section">Again</a>
+		<a href="` + external.URL + `/head-not-allowed">GET only</a>
+		<a href="` + external.URL + `/missing">Missing</a>`
+	handler := testSite(map[string]string{"/": page})
+
+	tests := []struct {
+		name     string
+		hosts    []string
+		problems int
+		requests int
+	}{
+		{"not allowed", nil, 0, 0},
+		{"other host allowed", []string{"example.org"}, 0, 0},
+		{"host allowed", []string{hostname}, 1, 4},
+		{"any host", []string{"*"}, 1, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = 0
+			problems := Check(handler, Options{Pages: []string{"/"}, ExternalHosts: tt.hosts})
+			if len(problems) != tt.problems {
+				t.Errorf("Expected %d problems, got %v", tt.problems, problems)
+			}
+			if tt.problems > 0 && problems[0].Message != "status 404" {
+				t.Errorf("Expected status 404, got %s", problems[0].Message)
+			}
+			// The repeated URL is requested once; HEAD is retried as GET
+			if requests != tt.requests {
+				t.Errorf("Expected %d requests, got %d", tt.requests, requests)
+			}
+		})
+	}
+}
+
+func BenchmarkCheck(b *testing.B) {
+	handler := testSite(map[string]string{
+		"/":      strings.Repeat(`<p><a href="/about#team">Team</a> <img src="/static/style.css" alt="Logo"></p>`, 50),
+		"/about": `<section id="team"></section>`,
+	})
+	for i := 0; i < b.N; i++ {
+		Check(handler, Options{Pages: []string{"/", "/about"}})
+	}
+}
@@ -0,0 +1,355 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets are the default latency buckets in seconds, matching the
+// Prometheus client libraries
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultRegistry is the registry used by the package-level instruments in
+// middleware and handlers
+var DefaultRegistry = NewRegistry()
+
+// collector is implemented by every metric family the registry can export
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry holds metric families and renders them in the Prometheus text
+// exposition format
+type Registry struct {
+	mutex      sync.RWMutex
+	collectors []collector
+	names      map[string]bool
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		names: make(map[string]bool),
+	}
+}
+
+// register adds a collector, panicking on duplicate names like the
+// Prometheus MustRegister helpers do
+func (r *Registry) register(c collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.names[c.name()] {
+		panic(fmt.Sprintf("metrics: duplicate metric %q", c.name()))
+	}
+	r.names[c.name()] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// Write writes all registered metrics in the text exposition format
+func (r *Registry) Write(w io.Writer) {
+	r.mutex.RLock()
+	collectors := make([]collector, len(r.collectors))
+	copy(collectors, r.collectors)
+	r.mutex.RUnlock()
+
+	sort.Slice(collectors, func(i, j int) bool {
+		return collectors[i].name() < collectors[j].name()
+	})
+
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// Handler returns an http.Handler serving the registry's metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		r.Write(w)
+	})
+}
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	bits atomic.Uint64
+}
+
+// Inc increments the counter by one
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increments the counter by v, ignoring negative values
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	for {
+		old := c.bits.Load()
+		next := math.Float64bits(math.Float64frombits(old) + v)
+		if c.bits.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// Value returns the current counter value
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+// Histogram counts observations into cumulative buckets
+type Histogram struct {
+	upperBounds []float64
+	mutex       sync.Mutex
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		upperBounds: buckets,
+		counts:      make([]uint64, len(buckets)),
+	}
+}
+
+// Observe records a single observation
+func (h *Histogram) Observe(v float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for i, bound := range h.upperBounds {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// snapshot returns a consistent copy of the histogram state
+func (h *Histogram) snapshot() ([]uint64, float64, uint64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	counts := make([]uint64, len(h.counts))
+	copy(counts, h.counts)
+	return counts, h.sum, h.count
+}
+
+// family holds the children of a labelled metric
+type family[T any] struct {
+	metricName string
+	help       string
+	kind       string
+	labels     []string
+	newChild   func() *T
+	writeChild func(w io.Writer, name string, labels string, child *T)
+
+	mutex    sync.RWMutex
+	children map[string]*T
+	values   map[string][]string
+}
+
+func (f *family[T]) name() string {
+	return f.metricName
+}
+
+// with returns the child for the given label values, creating it if needed
+func (f *family[T]) with(values ...string) *T {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.metricName, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mutex.RLock()
+	child, ok := f.children[key]
+	f.mutex.RUnlock()
+	if ok {
+		return child
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if child, ok := f.children[key]; ok {
+		return child
+	}
+	child = f.newChild()
+	f.children[key] = child
+	f.values[key] = append([]string(nil), values...)
+	return child
+}
+
+func (f *family[T]) write(w io.Writer) {
+	f.mutex.RLock()
+	keys := make([]string, 0, len(f.children))
+	for key := range f.children {
+		keys = append(keys, key)
+	}
+	f.mutex.RUnlock()
+	sort.Strings(keys)
+
+	writeHeader(w, f.metricName, f.help, f.kind)
+	for _, key := range keys {
+		f.mutex.RLock()
+		child, values := f.children[key], f.values[key]
+		f.mutex.RUnlock()
+		f.writeChild(w, f.metricName, formatLabels(f.labels, values), child)
+	}
+}
+
+// CounterVec is a counter partitioned by label values
+type CounterVec struct {
+	*family[Counter]
+}
+
+// NewCounterVec creates and registers a labelled counter. With no label
+// names it behaves as a single counter available through WithLabelValues().
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	vec := &CounterVec{&family[Counter]{
+		metricName: name,
+		help:       help,
+		kind:       "counter",
+		labels:     labels,
+		newChild:   func() *Counter { return &Counter{} },
+		writeChild: func(w io.Writer, name, labels string, c *Counter) {
+			fmt.Fprintf(w, "%s%s %s\n", name, labels, formatFloat(c.Value()))
+		},
+		children: make(map[string]*Counter),
+		values:   make(map[string][]string),
+	}}
+	r.register(vec)
+	return vec
+}
+
+// NewCounter creates and registers an unlabelled counter
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.NewCounterVec(name, help).WithLabelValues()
+}
+
+// WithLabelValues returns the counter for the given label values
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	return v.with(values...)
+}
+
+// HistogramVec is a histogram partitioned by label values
+type HistogramVec struct {
+	*family[Histogram]
+}
+
+// NewHistogramVec creates and registers a labelled histogram. A nil buckets
+// slice selects DefBuckets.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+
+	vec := &HistogramVec{&family[Histogram]{
+		metricName: name,
+		help:       help,
+		kind:       "histogram",
+		labels:     labels,
+		newChild:   func() *Histogram { return newHistogram(bounds) },
+		writeChild: writeHistogram,
+		children:   make(map[string]*Histogram),
+		values:     make(map[string][]string),
+	}}
+	r.register(vec)
+	return vec
+}
+
+// WithLabelValues returns the histogram for the given label values
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return v.with(values...)
+}
+
+func writeHistogram(w io.Writer, name, labels string, h *Histogram) {
+	counts, sum, count := h.snapshot()
+	for i, bound := range h.upperBounds {
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", formatFloat(bound)), counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", "+Inf"), count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, formatFloat(sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, count)
+}
+
+// GaugeFunc is a gauge whose value is read at scrape time
+type GaugeFunc struct {
+	metricName string
+	help       string
+	kind       string
+	fn         func() float64
+}
+
+func (g *GaugeFunc) name() string {
+	return g.metricName
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	writeHeader(w, g.metricName, g.help, g.kind)
+	fmt.Fprintf(w, "%s %s\n", g.metricName, formatFloat(g.fn()))
+}
+
+// NewGaugeFunc creates and registers a gauge backed by fn
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&GaugeFunc{metricName: name, help: help, kind: "gauge", fn: fn})
+}
+
+// NewCounterFunc creates and registers a counter backed by fn, for values
+// that are already tracked elsewhere such as the runtime's GC count
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&GaugeFunc{metricName: name, help: help, kind: "counter", fn: fn})
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// labelEscaper escapes label values per the exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(values[i]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// withLabel appends one more label to an already formatted label set
+func withLabel(labels, name, value string) string {
+	pair := fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(value))
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/claykom/website/internal/testutils"
+)
+
+func TestCounterVec(t *testing.T) {
+	reg := NewRegistry()
+	vec := reg.NewCounterVec("test_requests_total", "Test requests.", "route", "status")
+
+	vec.WithLabelValues("/blog/{slug}", "2xx").Inc()
+	vec.WithLabelValues("/blog/{slug}", "2xx").Add(2)
+	vec.WithLabelValues("/", "4xx").Inc()
+
+	var buf bytes.Buffer
+	reg.Write(&buf)
+	out := buf.String()
+
+	expected := []string{
+		"# HELP test_requests_total Test requests.",
+		"# TYPE test_requests_total counter",
+		`test_requests_total{route="/",status="4xx"} 1`,
+		`test_requests_total{route="/blog/{slug}",status="2xx"} 3`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, out)
+		}
+	}
+}
+
+func TestCounterIgnoresNegative(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.NewCounter("test_total", "Test.")
+
+	c.Add(-5)
+	c.Inc()
+
+	if c.Value() != 1 {
+		t.Errorf("Expected counter value 1, got %v", c.Value())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	reg := NewRegistry()
+	vec := reg.NewHistogramVec("test_duration_seconds", "Test durations.", []float64{0.1, 1}, "route")
+
+	h := vec.WithLabelValues("/")
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	var buf bytes.Buffer
+	reg.Write(&buf)
+	out := buf.String()
+
+	expected := []string{
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{route="/",le="0.1"} 1`,
+		`test_duration_seconds_bucket{route="/",le="1"} 2`,
+		`test_duration_seconds_bucket{route="/",le="+Inf"} 3`,
+		`test_duration_seconds_sum{route="/"} 5.55`,
+		`test_duration_seconds_count{route="/"} 3`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, out)
+		}
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	reg := NewRegistry()
+	vec := reg.NewCounterVec("test_total", "Help with \\ and\nnewline.", "value")
+	vec.WithLabelValues("a\"b\\c\nd").Inc()
+
+	var buf bytes.Buffer
+	reg.Write(&buf)
+	out := buf.String()
+
+	if !strings.Contains(out, `# HELP test_total Help with \\ and\nnewline.`) {
+		t.Errorf("Help text not escaped, got:\n%s", out)
+	}
+	if !strings.Contains(out, `test_total{value="a\"b\\c\nd"} 1`) {
+		t.Errorf("Label value not escaped, got:\n%s", out)
+	}
+}
+
+func TestWrongLabelCountPanics(t *testing.T) {
+	reg := NewRegistry()
+	vec := reg.NewCounterVec("test_total", "Test.", "a", "b")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for wrong number of label values")
+		}
+	}()
+	vec.WithLabelValues("only-one")
+}
+
+func TestDuplicateRegistrationPanics(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounter("test_total", "Test.")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for duplicate metric name")
+		}
+	}()
+	reg.NewCounter("test_total", "Test.")
+}
+
+func TestRuntimeMetrics(t *testing.T) {
+	reg := NewRegistry()
+	reg.RegisterRuntimeMetrics()
+	reg.NewGaugeFunc("test_gauge", "Test gauge.", func() float64 { return 42 })
+
+	var buf bytes.Buffer
+	reg.Write(&buf)
+	out := buf.String()
+
+	for _, name := range []string{"go_goroutines ", "go_memstats_alloc_bytes ", "go_gc_cycles_total ", "go_info{version=", "process_start_time_seconds ", "test_gauge 42"} {
+		if !strings.Contains(out, name) {
+			t.Errorf("Expected output to contain %q", name)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounter("test_total", "Test.").Inc()
+
+	req := testutils.NewTestRequest("GET", "/metrics", "")
+	rr := testutils.NewTestResponseRecorder()
+
+	reg.Handler().ServeHTTP(rr, req)
+
+	rr.AssertStatusCode(t, 200)
+	rr.AssertHeaderContains(t, "Content-Type", "version=0.0.4")
+	rr.AssertBodyContains(t, "test_total 1")
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	reg := NewRegistry()
+	vec := reg.NewCounterVec("test_total", "Test.", "worker")
+	hist := reg.NewHistogramVec("test_seconds", "Test.", nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				vec.WithLabelValues("w").Inc()
+				hist.WithLabelValues().Observe(0.01)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := vec.WithLabelValues("w").Value(); got != 5000 {
+		t.Errorf("Expected 5000, got %v", got)
+	}
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// runtimeCollector exports Go runtime statistics, reading them once per
+// scrape so that the memstats values are consistent with each other
+type runtimeCollector struct {
+	startTime time.Time
+	mutex     sync.Mutex
+}
+
+// RegisterRuntimeMetrics adds the go_* and process_start_time_seconds
+// metrics to the registry
+func (r *Registry) RegisterRuntimeMetrics() {
+	r.register(&runtimeCollector{startTime: time.Now()})
+}
+
+func (c *runtimeCollector) name() string {
+	return "go_"
+}
+
+func (c *runtimeCollector) write(w io.Writer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	gauge := func(name, help string, value float64) {
+		writeHeader(w, name, help, "gauge")
+		fmt.Fprintf(w, "%s %s\n", name, formatFloat(value))
+	}
+	counter := func(name, help string, value float64) {
+		writeHeader(w, name, help, "counter")
+		fmt.Fprintf(w, "%s %s\n", name, formatFloat(value))
+	}
+
+	writeHeader(w, "go_info", "Information about the Go environment.", "gauge")
+	fmt.Fprintf(w, "go_info%s 1\n", formatLabels([]string{"version"}, []string{runtime.Version()}))
+
+	gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+	gauge("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", float64(ms.Alloc))
+	counter("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", float64(ms.TotalAlloc))
+	gauge("go_memstats_sys_bytes", "Number of bytes obtained from system.", float64(ms.Sys))
+	gauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", float64(ms.HeapInuse))
+	gauge("go_memstats_heap_objects", "Number of allocated objects.", float64(ms.HeapObjects))
+	counter("go_gc_cycles_total", "Number of completed GC cycles.", float64(ms.NumGC))
+	counter("go_gc_pause_seconds_total", "Total GC stop-the-world pause time.", float64(ms.PauseTotalNs)/1e9)
+	gauge("go_memstats_last_gc_time_seconds", "Number of seconds since 1970 of last garbage collection.", float64(ms.LastGC)/1e9)
+	gauge("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", float64(c.startTime.UnixNano())/1e9)
+}
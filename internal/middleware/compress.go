@@ -0,0 +1,312 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// encoder is the subset of gzip.Writer and brotli.Writer we rely on
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Writers are pooled because each one carries sizeable compression state
+var (
+	gzipPool = sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}}
+	brotliPool = sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, 5)
+	}}
+)
+
+// Compress compresses text responses of at least minSize bytes with brotli
+// or gzip, whichever the client prefers. Responses that already carry a
+// Content-Encoding (such as precompressed static files), range requests and
+// non-text types like images are passed through untouched. HEAD responses
+// get the headers GET would, without a body.
+func Compress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Range offsets refer to the identity encoding, which is still
+			// chosen by Accept-Encoding as far as caches are concerned
+			if r.Header.Get("Range") != "" {
+				addVary(w.Header(), "Accept-Encoding")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoding:       negotiateEncoding(r.Header.Get("Accept-Encoding")),
+				minSize:        minSize,
+				head:           r.Method == http.MethodHead,
+				status:         http.StatusOK,
+			}
+			defer cw.Close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks "br", "gzip" or "" from an Accept-Encoding value,
+// honouring q-values and preferring brotli on ties
+func negotiateEncoding(header string) string {
+	qBr, qGzip := encodingQualities(header)
+
+	switch {
+	case qBr > 0 && qBr >= qGzip:
+		return "br"
+	case qGzip > 0:
+		return "gzip"
+	}
+	return ""
+}
+
+// encodingQualities returns the q-values an Accept-Encoding header assigns
+// to brotli and gzip; zero or less means not acceptable
+func encodingQualities(header string) (qBr, qGzip float64) {
+	qBr, qGzip, qAny := -1.0, -1.0, -1.0
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "br":
+			qBr = q
+		case "gzip", "x-gzip":
+			qGzip = q
+		case "*":
+			qAny = q
+		}
+	}
+
+	// A wildcard covers codings that weren't listed explicitly
+	if qBr < 0 {
+		qBr = qAny
+	}
+	if qGzip < 0 {
+		qGzip = qAny
+	}
+	return qBr, qGzip
+}
+
+// compressibleType reports whether a Content-Type is worth compressing
+func compressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	if strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+
+	switch mediaType {
+	case "application/javascript", "application/json", "application/xml",
+		"application/wasm", "image/svg+xml", "image/x-icon":
+		return true
+	}
+	return false
+}
+
+// compressWriter buffers the first minSize bytes of a response, then decides
+// whether to compress it based on the final headers and size. For HEAD it
+// makes the same decision, also trusting a Content-Length set without a
+// body, and discards whatever would have been compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	head     bool
+	discard  bool
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         encoder
+}
+
+// WriteHeader records the status; it is sent once the encoding is decided
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	// Informational responses go straight through
+	if code >= 100 && code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = code
+
+	// Responses without a body need no buffering
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		cw.decide(false)
+	}
+}
+
+// Write buffers until minSize bytes have been seen
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.discard {
+			return len(b), nil
+		}
+		if cw.enc != nil {
+			return cw.enc.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// decide sets the response headers, sends the status and flushes the
+// buffer. bigEnough reports whether the body reached the size threshold.
+func (cw *compressWriter) decide(bigEnough bool) error {
+	cw.decided = true
+	h := cw.Header()
+
+	// Mirror net/http's sniffing so the type check sees what clients will
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	compressible := h.Get("Content-Encoding") == "" && compressibleType(h.Get("Content-Type"))
+	if compressible {
+		addVary(h, "Accept-Encoding")
+	}
+
+	// A HEAD handler may set Content-Length and write nothing
+	if cw.head && !bigEnough {
+		n, err := strconv.Atoi(h.Get("Content-Length"))
+		bigEnough = err == nil && n >= cw.minSize
+	}
+
+	if compressible && bigEnough && cw.encoding != "" &&
+		cw.status != http.StatusNoContent && cw.status != http.StatusNotModified {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		// The compressed bytes differ from the identity representation
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+
+		if cw.head {
+			cw.discard = true
+		} else {
+			if cw.encoding == "br" {
+				cw.enc = brotliPool.Get().(*brotli.Writer)
+			} else {
+				cw.enc = gzipPool.Get().(*gzip.Writer)
+			}
+			cw.enc.Reset(cw.ResponseWriter)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 || cw.discard {
+		return nil
+	}
+	if cw.enc != nil {
+		_, err := cw.enc.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// Flush sends buffered data; a streaming response is compressed even if it
+// hasn't reached minSize yet
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			cw.WriteHeader(http.StatusOK)
+		}
+		cw.decide(true)
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the response and returns the encoder to its pool
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			// Handler wrote nothing; leave the implicit 200 to net/http
+			return nil
+		}
+		if err := cw.decide(len(cw.buf) >= cw.minSize); err != nil {
+			return err
+		}
+	}
+	if cw.enc == nil {
+		return nil
+	}
+
+	err := cw.enc.Close()
+	cw.enc.Reset(io.Discard)
+	switch e := cw.enc.(type) {
+	case *brotli.Writer:
+		brotliPool.Put(e)
+	case *gzip.Writer:
+		gzipPool.Put(e)
+	}
+	cw.enc = nil
+	return err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// addVary adds a field to the Vary header unless it is already listed
+func addVary(h http.Header, field string) {
+	for _, value := range h.Values("Vary") {
+		for _, existing := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), field) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", field)
+}
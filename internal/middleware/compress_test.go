@@ -0,0 +1,337 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/claykom/website/internal/testutils"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"br", "br"},
+		{"gzip, deflate, br", "br"},
+		{"gzip;q=1.0, br;q=0.5", "gzip"},
+		{"br;q=0, gzip", "gzip"},
+		{"gzip;q=0", ""},
+		{"*", "br"},
+		{"*;q=0.5, br;q=0", "gzip"},
+		{"identity", ""},
+		{"deflate", ""},
+		{"GZIP", "gzip"},
+		{"gzip;q=abc", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := negotiateEncoding(tt.header); got != tt.expected {
+				t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompressibleType(t *testing.T) {
+	tests := map[string]bool{
+		"text/html; charset=utf-8":              true,
+		"text/css; charset=utf-8":               true,
+		"application/javascript; charset=utf-8": true,
+		"application/json":                      true,
+		"application/ld+json":                   true,
+		"image/svg+xml":                         true,
+		"image/png":                             false,
+		"image/jpeg":                            false,
+		"font/woff2":                            false,
+		"":                                      false,
+	}
+	for contentType, expected := range tests {
+		if got := compressibleType(contentType); got != expected {
+			t.Errorf("compressibleType(%q) = %v, want %v", contentType, got, expected)
+		}
+	}
+}
+
+// textHandler writes body with the given content type
+func textHandler(contentType, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("ETag", `"abc"`)
+		io.WriteString(w, body)
+	})
+}
+
+func TestCompressGzip(t *testing.T) {
+	body := strings.Repeat("<p>hello world</p>", 200)
+	handler := Compress(1024)(textHandler("text/html; charset=utf-8", body))
+
+	req := testutils.NewTestRequestWithHeaders("GET", "/", map[string]string{"Accept-Encoding": "gzip"})
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, req)
+
+	rr.AssertHeader(t, "Content-Encoding", "gzip")
+	rr.AssertHeader(t, "Vary", "Accept-Encoding")
+	rr.AssertHeader(t, "ETag", `W/"abc"`)
+
+	zr, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip body: %v", err)
+	}
+	decoded, _ := io.ReadAll(zr)
+	if string(decoded) != body {
+		t.Error("Decompressed body does not match original")
+	}
+	if rr.Body.Len() >= len(body) {
+		t.Error("Expected compressed body to be smaller")
+	}
+}
+
+func TestCompressBrotli(t *testing.T) {
+	body := strings.Repeat("body { color: red; }\n", 200)
+	handler := Compress(1024)(textHandler("text/css; charset=utf-8", body))
+
+	req := testutils.NewTestRequestWithHeaders("GET", "/", map[string]string{"Accept-Encoding": "gzip, br"})
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, req)
+
+	rr.AssertHeader(t, "Content-Encoding", "br")
+	decoded, err := io.ReadAll(brotli.NewReader(rr.Body))
+	if err != nil {
+		t.Fatalf("Invalid brotli body: %v", err)
+	}
+	if string(decoded) != body {
+		t.Error("Decompressed body does not match original")
+	}
+}
+
+func TestCompressSkips(t *testing.T) {
+	large := strings.Repeat("a", 4096)
+
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		headers  map[string]string
+		method   string
+		wantVary bool
+	}{
+		{
+			name:     "below threshold",
+			handler:  textHandler("text/html", "small"),
+			headers:  map[string]string{"Accept-Encoding": "gzip"},
+			wantVary: true,
+		},
+		{
+			name:    "image",
+			handler: textHandler("image/png", large),
+			headers: map[string]string{"Accept-Encoding": "gzip"},
+		},
+		{
+			name: "already encoded",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/css")
+				w.Header().Set("Content-Encoding", "br")
+				io.WriteString(w, large)
+			}),
+			headers: map[string]string{"Accept-Encoding": "gzip"},
+		},
+		{
+			name:     "range request",
+			handler:  textHandler("text/html", large),
+			headers:  map[string]string{"Accept-Encoding": "gzip", "Range": "bytes=0-10"},
+			wantVary: true,
+		},
+		{
+			name:     "client accepts nothing",
+			handler:  textHandler("text/html", large),
+			headers:  map[string]string{},
+			wantVary: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			req := testutils.NewTestRequestWithHeaders(method, "/", tt.headers)
+			rr := testutils.NewTestResponseRecorder()
+			Compress(1024)(tt.handler).ServeHTTP(rr, req)
+
+			if ce := rr.Header().Get("Content-Encoding"); ce == "gzip" {
+				t.Errorf("Expected response not to be gzipped")
+			}
+			if hasVary := rr.Header().Get("Vary") == "Accept-Encoding"; hasVary != tt.wantVary {
+				t.Errorf("Expected Vary set=%v, got %q", tt.wantVary, rr.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestCompressHeadMatchesGet(t *testing.T) {
+	large := strings.Repeat("<p>hello world</p>", 200)
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+	}{
+		{"large page", textHandler("text/html", large)},
+		{"small page", textHandler("text/html", "small")},
+		{"image", textHandler("image/png", large)},
+		{
+			// As http.ServeContent answers HEAD: a length and no body
+			name: "length without body",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/css")
+				w.Header().Set("ETag", `"abc"`)
+				if r.Method == http.MethodHead {
+					w.Header().Set("Content-Length", strconv.Itoa(len(large)))
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				io.WriteString(w, large)
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Compress(1024)(tt.handler)
+			headers := map[string]string{"Accept-Encoding": "gzip"}
+
+			get := testutils.NewTestResponseRecorder()
+			handler.ServeHTTP(get, testutils.NewTestRequestWithHeaders("GET", "/", headers))
+			head := testutils.NewTestResponseRecorder()
+			handler.ServeHTTP(head, testutils.NewTestRequestWithHeaders("HEAD", "/", headers))
+
+			for _, name := range []string{"Content-Type", "Content-Encoding", "Vary", "ETag", "Content-Length"} {
+				if got, expected := head.Header().Get(name), get.Header().Get(name); got != expected {
+					t.Errorf("Expected HEAD %s %q, got %q", name, expected, got)
+				}
+			}
+			if head.Body.Len() != 0 && head.Header().Get("Content-Encoding") != "" {
+				t.Errorf("Expected no compressed body for HEAD, got %d bytes", head.Body.Len())
+			}
+		})
+	}
+}
+
+func TestCompressPreservesStatus(t *testing.T) {
+	handler := Compress(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, strings.Repeat(`{"error":"x"}`, 10))
+	}))
+
+	req := testutils.NewTestRequestWithHeaders("GET", "/", map[string]string{"Accept-Encoding": "gzip"})
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, req)
+
+	rr.AssertStatusCode(t, http.StatusNotFound)
+	rr.AssertHeader(t, "Content-Encoding", "gzip")
+}
+
+func TestCompressNoBodyStatus(t *testing.T) {
+	handler := Compress(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+
+	req := testutils.NewTestRequestWithHeaders("GET", "/", map[string]string{"Accept-Encoding": "gzip"})
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, req)
+
+	rr.AssertStatusCode(t, http.StatusNotModified)
+	rr.AssertHeader(t, "Content-Encoding", "")
+}
+
+func TestCompressWithLoggerWrapper(t *testing.T) {
+	body := strings.Repeat("<li>item</li>", 500)
+	handler := Logger(Compress(1024)(textHandler("text/html", body)))
+
+	req := testutils.NewTestRequestWithHeaders("GET", "/", map[string]string{"Accept-Encoding": "gzip"})
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, req)
+
+	rr.AssertStatusCode(t, http.StatusOK)
+	rr.AssertHeader(t, "Content-Encoding", "gzip")
+
+	// Flush through the logging wrapper reaches the recorder
+	rr = testutils.NewTestResponseRecorder()
+	http.NewResponseController(newResponseWriter(rr)).Flush()
+	if !rr.Flushed {
+		t.Error("Expected Flush to reach the underlying writer")
+	}
+}
+
+func TestCompressStreamingFlush(t *testing.T) {
+	handler := Compress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "first chunk")
+		http.NewResponseController(w).Flush()
+		io.WriteString(w, "second chunk")
+	}))
+
+	req := testutils.NewTestRequestWithHeaders("GET", "/", map[string]string{"Accept-Encoding": "gzip"})
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if !rr.Flushed {
+		t.Error("Expected flush to reach the recorder")
+	}
+	rr.AssertHeader(t, "Content-Encoding", "gzip")
+	zr, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip body: %v", err)
+	}
+	decoded, _ := io.ReadAll(zr)
+	if string(decoded) != "first chunksecond chunk" {
+		t.Errorf("Unexpected body %q", decoded)
+	}
+}
+
+func TestCompressStaticFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	css := strings.Repeat(".a { margin: 0; }\n", 200)
+	if err := os.WriteFile(filepath.Join(tempDir, "style.css"), []byte(css), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "logo.png"), []byte(strings.Repeat("\x89PNG", 1000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := Compress(1024)(SecureStaticHandler(os.DirFS(tempDir)))
+
+	req := testutils.NewTestRequestWithHeaders("GET", "/style.css", map[string]string{"Accept-Encoding": "gzip"})
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, req)
+	rr.AssertHeader(t, "Content-Encoding", "gzip")
+	rr.AssertHeader(t, "Content-Length", "")
+
+	req = testutils.NewTestRequestWithHeaders("GET", "/logo.png", map[string]string{"Accept-Encoding": "gzip"})
+	rr = testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, req)
+	rr.AssertHeader(t, "Content-Encoding", "")
+}
+
+func BenchmarkCompressGzip(b *testing.B) {
+	body := strings.Repeat("<p>hello world</p>", 500)
+	handler := Compress(1024)(textHandler("text/html", body))
+	req := testutils.NewTestRequestWithHeaders("GET", "/", map[string]string{"Accept-Encoding": "gzip"})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(testutils.NewTestResponseRecorder(), req)
+	}
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures the CORS middleware
+type CORSOptions struct {
+	// AllowedOrigins lists exact origins ("https://app.example.com"),
+	// wildcard subdomains ("https://*.example.com") or "*" for any origin
+	AllowedOrigins []string
+	// AllowedMethods defaults to GET and HEAD
+	AllowedMethods []string
+	// AllowedHeaders lists request headers a cross-origin caller may send
+	AllowedHeaders []string
+	// AllowCredentials lets browsers send cookies and HTTP auth. A "*"
+	// origin is ignored when it is set, since browsers reject that pair.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight result
+	MaxAge time.Duration
+}
+
+// CORS adds Cross-Origin Resource Sharing headers for allowed origins and
+// answers preflight requests. Routes must accept OPTIONS so that preflights
+// reach this middleware instead of the MethodNotAllowed handler.
+func CORS(opts CORSOptions) func(http.Handler) http.Handler {
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead}
+	}
+	methods = upperAll(methods)
+	allowedMethods := strings.Join(methods, ", ")
+
+	allowedHeaders := make([]string, len(opts.AllowedHeaders))
+	for i, h := range opts.AllowedHeaders {
+		allowedHeaders[i] = http.CanonicalHeaderKey(strings.TrimSpace(h))
+	}
+
+	maxAge := ""
+	if opts.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			// Responses differ by origin, so shared caches must key on it
+			addVary(w.Header(), "Origin")
+			if preflight {
+				addVary(w.Header(), "Access-Control-Request-Method")
+				addVary(w.Header(), "Access-Control-Request-Headers")
+			}
+
+			// A plain OPTIONS request only asks what the resource supports
+			if r.Method == http.MethodOptions && !preflight {
+				w.Header().Set("Allow", allowedMethods+", OPTIONS")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowOrigin, ok := matchOrigin(opts.AllowedOrigins, origin, opts.AllowCredentials)
+			if !ok {
+				if preflight {
+					// No CORS headers: the browser blocks the real request
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("Access-Control-Allow-Origin", allowOrigin)
+			if opts.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !slices.Contains(methods, r.Header.Get("Access-Control-Request-Method")) {
+				h.Del("Access-Control-Allow-Origin")
+				h.Del("Access-Control-Allow-Credentials")
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			requested := parseHeaderList(r.Header.Get("Access-Control-Request-Headers"))
+			for _, name := range requested {
+				if !slices.Contains(allowedHeaders, name) {
+					h.Del("Access-Control-Allow-Origin")
+					h.Del("Access-Control-Allow-Credentials")
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+			}
+
+			h.Set("Access-Control-Allow-Methods", allowedMethods)
+			if len(requested) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+			}
+			if maxAge != "" {
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// matchOrigin returns the Access-Control-Allow-Origin value for origin, if
+// it is allowed
+func matchOrigin(allowed []string, origin string, credentials bool) (string, bool) {
+	for _, pattern := range allowed {
+		switch {
+		case pattern == "*":
+			if credentials {
+				// Browsers reject "*" with credentials; config forbids this
+				// combination, so never echo arbitrary origins here
+				continue
+			}
+			return "*", true
+		case strings.EqualFold(pattern, origin):
+			return origin, true
+		case strings.Contains(pattern, "://*."):
+			if matchWildcardOrigin(pattern, origin) {
+				return origin, true
+			}
+		}
+	}
+	return "", false
+}
+
+// matchWildcardOrigin matches "https://*.example.com" against subdomains
+// of example.com with the same scheme and port. The bare domain is not
+// matched.
+func matchWildcardOrigin(pattern, origin string) bool {
+	p, err := url.Parse(pattern)
+	if err != nil {
+		return false
+	}
+	o, err := url.Parse(origin)
+	if err != nil || o.Host == "" || o.Path != "" {
+		return false
+	}
+	if !strings.EqualFold(p.Scheme, o.Scheme) || p.Port() != o.Port() {
+		return false
+	}
+
+	suffix := strings.ToLower(strings.TrimPrefix(p.Hostname(), "*"))
+	host := strings.ToLower(o.Hostname())
+	return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+}
+
+// parseHeaderList splits a comma-separated header list into canonical names
+func parseHeaderList(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, http.CanonicalHeaderKey(name))
+		}
+	}
+	return names
+}
+
+func upperAll(values []string) []string {
+	upper := make([]string, len(values))
+	for i, v := range values {
+		upper[i] = strings.ToUpper(strings.TrimSpace(v))
+	}
+	return upper
+}
@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/claykom/website/internal/testutils"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	allowed := []string{"https://app.example.com", "https://*.example.org", "http://*.local.test:3000"}
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"http://app.example.com", false},
+		{"https://app.example.com.evil.com", false},
+		{"https://api.example.org", true},
+		{"https://deep.api.example.org", true},
+		{"https://example.org", false},
+		{"https://evilexample.org", false},
+		{"https://api.example.org:8443", false},
+		{"http://web.local.test:3000", true},
+		{"http://web.local.test", false},
+		{"null", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			value, ok := matchOrigin(allowed, tt.origin, false)
+			if ok != tt.allowed {
+				t.Errorf("matchOrigin(%q) = %v, want %v", tt.origin, ok, tt.allowed)
+			}
+			if ok && value != tt.origin {
+				t.Errorf("Expected origin to be echoed, got %q", value)
+			}
+		})
+	}
+
+	if value, ok := matchOrigin([]string{"*"}, "https://any.example", false); !ok || value != "*" {
+		t.Errorf("Expected * to allow any origin, got %q, %v", value, ok)
+	}
+	if _, ok := matchOrigin([]string{"*"}, "https://any.example", true); ok {
+		t.Error("Expected * to be ignored with credentials")
+	}
+}
+
+func TestCORS(t *testing.T) {
+	opts := CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"get", "POST"},
+		AllowedHeaders:   []string{"content-type", "X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	reached := false
+	handler := CORS(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		method         string
+		headers        map[string]string
+		expectedStatus int
+		expectReached  bool
+		expected       map[string]string
+	}{
+		{
+			name:           "same-origin request",
+			method:         "GET",
+			headers:        map[string]string{},
+			expectedStatus: http.StatusOK,
+			expectReached:  true,
+			expected:       map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+		},
+		{
+			name:           "allowed origin",
+			method:         "GET",
+			headers:        map[string]string{"Origin": "https://app.example.com"},
+			expectedStatus: http.StatusOK,
+			expectReached:  true,
+			expected: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:           "disallowed origin",
+			method:         "GET",
+			headers:        map[string]string{"Origin": "https://evil.example.com"},
+			expectedStatus: http.StatusOK,
+			expectReached:  true,
+			expected:       map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Credentials": ""},
+		},
+		{
+			name:   "preflight",
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "content-type, x-request-id",
+			},
+			expectedStatus: http.StatusNoContent,
+			expected: map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Methods": "GET, POST",
+				"Access-Control-Allow-Headers": "Content-Type, X-Request-Id",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			name:   "preflight with disallowed method",
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                        "https://app.example.com",
+				"Access-Control-Request-Method": "DELETE",
+			},
+			expectedStatus: http.StatusForbidden,
+			expected:       map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""},
+		},
+		{
+			name:   "preflight with disallowed header",
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "Authorization",
+			},
+			expectedStatus: http.StatusForbidden,
+			expected:       map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:   "preflight from disallowed origin",
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                        "https://evil.example.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			expectedStatus: http.StatusForbidden,
+			expected:       map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:           "plain options",
+			method:         "OPTIONS",
+			headers:        map[string]string{},
+			expectedStatus: http.StatusNoContent,
+			expected:       map[string]string{"Allow": "GET, POST, OPTIONS"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached = false
+			req := testutils.NewTestRequestWithHeaders(tt.method, "/api/blog", tt.headers)
+			rr := testutils.NewTestResponseRecorder()
+			handler.ServeHTTP(rr, req)
+
+			rr.AssertStatusCode(t, tt.expectedStatus)
+			if reached != tt.expectReached {
+				t.Errorf("Expected handler reached=%v, got %v", tt.expectReached, reached)
+			}
+			for header, expected := range tt.expected {
+				rr.AssertHeader(t, header, expected)
+			}
+		})
+	}
+}
+
+func TestCORSPreflightVary(t *testing.T) {
+	handler := CORS(CORSOptions{AllowedOrigins: []string{"*"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := testutils.NewTestRequestWithHeaders("OPTIONS", "/api/blog", map[string]string{
+		"Origin":                        "https://anywhere.example",
+		"Access-Control-Request-Method": "GET",
+	})
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, req)
+
+	rr.AssertStatusCode(t, http.StatusNoContent)
+	rr.AssertHeader(t, "Access-Control-Allow-Origin", "*")
+	rr.AssertHeader(t, "Access-Control-Allow-Methods", "GET, HEAD")
+	vary := rr.Header().Values("Vary")
+	if len(vary) != 3 {
+		t.Errorf("Expected Vary on Origin and both request headers, got %v", vary)
+	}
+}
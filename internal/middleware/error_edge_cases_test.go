@@ -125,7 +125,7 @@ func TestErrorHandlingEdgeCases(t *testing.T) {
 // TestStaticHandlerErrorCases tests error conditions in static file handling
 func TestStaticHandlerErrorCases(t *testing.T) {
 	tempDir := t.TempDir()
-	handler := SecureStaticHandler(http.Dir(tempDir))
+	handler := SecureStaticHandler(os.DirFS(tempDir))
 
 	t.Run("malicious file access attempts", func(t *testing.T) {
 		maliciousPaths := []string{
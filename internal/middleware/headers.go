@@ -1,66 +1,160 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"os"
+	"slices"
+	"strings"
+
+	"github.com/claykom/website/internal/csp"
 )
 
+// cspReportGroup names the Reporting-Endpoints entry used by report-to
+const cspReportGroup = "csp-endpoint"
+
+// HeaderOptions configures SecureHeadersWith
+type HeaderOptions struct {
+	// Profile selects the header values; nil means DefaultProfile
+	Profile *HeaderProfile
+	// Routes override headers below a path prefix; longer prefixes win
+	Routes []RouteHeaders
+	// CSPReportURI receives violation reports through both the legacy
+	// report-uri directive and the Reporting API; "" disables reporting
+	CSPReportURI string
+	// CSPReportOnly sends the policy as Content-Security-Policy-Report-Only,
+	// so violations are reported but nothing is blocked
+	CSPReportOnly bool
+}
+
+func (opts HeaderOptions) profile() HeaderProfile {
+	if opts.Profile != nil {
+		return *opts.Profile
+	}
+	return DefaultProfile()
+}
+
 // SecureHeaders adds security headers to responses
 func SecureHeaders(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Basic security headers
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.Header().Set("X-Frame-Options", "DENY")
-		w.Header().Set("X-XSS-Protection", "1; mode=block")
-		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
-
-		// HSTS (HTTP Strict Transport Security)
-		// Only add HSTS header if serving over HTTPS
-		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
-			w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains; preload")
-		}
-
-		// Content Security Policy - more restrictive and configurable
-		csp := getContentSecurityPolicy()
-		w.Header().Set("Content-Security-Policy", csp)
-
-		// Additional security headers
-		w.Header().Set("X-Permitted-Cross-Domain-Policies", "none")
-		w.Header().Set("Cross-Origin-Embedder-Policy", "require-corp")
-		w.Header().Set("Cross-Origin-Opener-Policy", "same-origin")
-		w.Header().Set("Cross-Origin-Resource-Policy", "same-origin")
-
-		// Remove server information
-		w.Header().Del("Server")
-		w.Header().Del("X-Powered-By")
-
-		next.ServeHTTP(w, r)
+	return SecureHeadersWith(HeaderOptions{})(next)
+}
+
+// SecureHeadersWith is SecureHeaders with a header profile, per-route
+// overrides and CSP reporting options
+func SecureHeadersWith(opts HeaderOptions) func(http.Handler) http.Handler {
+	profile := opts.profile()
+	cspHeader := "Content-Security-Policy"
+	if opts.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+
+	// Shortest prefix first, so longer prefixes are applied last and win
+	routes := slices.Clone(opts.Routes)
+	slices.SortStableFunc(routes, func(a, b RouteHeaders) int {
+		return len(a.PathPrefix) - len(b.PathPrefix)
 	})
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// HSTS only over HTTPS
+			profile.apply(w.Header(), isHTTPS(r))
+
+			// Remove server information
+			w.Header().Del("Server")
+			w.Header().Del("X-Powered-By")
+
+			policy := getContentSecurityPolicy(profile)
+			for _, route := range routes {
+				if !strings.HasPrefix(r.URL.Path, route.PathPrefix) {
+					continue
+				}
+				for name, value := range route.Headers {
+					switch name = http.CanonicalHeaderKey(name); {
+					case name == "Content-Security-Policy":
+						policy = csp.Parse(value)
+					case value == "":
+						w.Header().Del(name)
+					default:
+						w.Header().Set(name, value)
+					}
+				}
+			}
+
+			// Content Security Policy with a fresh nonce for inline scripts and
+			// styles. Handlers may add sources until the header is written.
+			nonce := csp.NewNonce()
+			policy.Add("script-src", "'nonce-"+nonce+"'")
+			policy.Add("style-src", "'nonce-"+nonce+"'")
+			if opts.CSPReportURI != "" {
+				// report-uri for browsers without Reporting API support
+				policy.Set("report-uri", opts.CSPReportURI)
+				policy.Set("report-to", cspReportGroup)
+				w.Header().Set("Reporting-Endpoints", cspReportGroup+`="`+opts.CSPReportURI+`"`)
+			}
+			ctx := csp.NewContext(r.Context(), policy, nonce)
+
+			cw := &cspWriter{ResponseWriter: w, ctx: ctx, header: cspHeader}
+			next.ServeHTTP(cw, r.WithContext(ctx))
+			// Handler wrote nothing; net/http sends the implicit 200 after this
+			cw.setHeader()
+		})
+	}
 }
 
-// getContentSecurityPolicy returns a CSP string, allowing environment override
-func getContentSecurityPolicy() string {
-	// Default CSP - very restrictive
-	defaultCSP := "default-src 'self'; " +
-		"script-src 'self'; " +
-		"style-src 'self' 'unsafe-inline'; " +
-		"img-src 'self' data: https:; " +
-		"font-src 'self'; " +
-		"connect-src 'self'; " +
-		"media-src 'self'; " +
-		"object-src 'none'; " +
-		"child-src 'none'; " +
-		"frame-src 'none'; " +
-		"worker-src 'none'; " +
-		"frame-ancestors 'none'; " +
-		"form-action 'self'; " +
-		"base-uri 'self'; " +
-		"manifest-src 'self'"
-
-	// Allow override via environment variable for development
-	if envCSP := os.Getenv("CSP_POLICY"); envCSP != "" {
-		return envCSP
+// cspWriter sets the Content-Security-Policy header just before the
+// response headers are sent, so it includes sources added by the handler
+type cspWriter struct {
+	http.ResponseWriter
+	ctx    context.Context
+	header string
+	done   bool
+}
+
+func (cw *cspWriter) setHeader() {
+	if cw.done {
+		return
+	}
+	cw.done = true
+	cw.Header().Set(cw.header, csp.Header(cw.ctx))
+}
+
+func (cw *cspWriter) WriteHeader(code int) {
+	switch {
+	case code == http.StatusNotModified:
+		// The client reuses its cached body, whose nonces belong to the
+		// policy it already has; a fresh nonce here would block them
+		cw.done = true
+	case code >= 200:
+		cw.setHeader()
 	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cspWriter) Write(b []byte) (int, error) {
+	cw.setHeader()
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends the headers first if the handler hasn't yet
+func (cw *cspWriter) Flush() {
+	cw.setHeader()
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *cspWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
 
-	return defaultCSP
+// getContentSecurityPolicy returns the base policy for a response. The
+// CSP_POLICY environment variable replaces the profile's policy; nonces and
+// per-page sources are still added to it.
+func getContentSecurityPolicy(profile HeaderProfile) *csp.Policy {
+	if envCSP := os.Getenv("CSP_POLICY"); envCSP != "" {
+		return csp.Parse(envCSP)
+	}
+	if profile.CSP == nil {
+		return csp.New()
+	}
+	return profile.CSP.Clone()
 }
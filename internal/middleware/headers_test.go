@@ -3,8 +3,11 @@ package middleware
 import (
 	"net/http"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/a-h/templ"
+	"github.com/claykom/website/internal/csp"
 	"github.com/claykom/website/internal/testutils"
 )
 
@@ -67,10 +70,8 @@ func TestSecureHeadersComplete(t *testing.T) {
 	expectedHeaders := map[string]string{
 		"X-Content-Type-Options":            "nosniff",
 		"X-Frame-Options":                   "DENY",
-		"X-XSS-Protection":                  "1; mode=block",
 		"Referrer-Policy":                   "strict-origin-when-cross-origin",
 		"X-Permitted-Cross-Domain-Policies": "none",
-		"Cross-Origin-Embedder-Policy":      "require-corp",
 		"Cross-Origin-Opener-Policy":        "same-origin",
 		"Cross-Origin-Resource-Policy":      "same-origin",
 	}
@@ -82,10 +83,20 @@ func TestSecureHeadersComplete(t *testing.T) {
 		}
 	}
 
-	// CSP should be present
+	// CSP and Permissions-Policy should be present
 	if rr.Header().Get("Content-Security-Policy") == "" {
 		t.Error("Content-Security-Policy header missing")
 	}
+	if !strings.Contains(rr.Header().Get("Permissions-Policy"), "camera=()") {
+		t.Errorf("Expected Permissions-Policy to disable camera, got '%s'", rr.Header().Get("Permissions-Policy"))
+	}
+
+	// Deprecated, and require-corp would block cross-origin images CSP allows
+	for _, header := range []string{"X-XSS-Protection", "Cross-Origin-Embedder-Policy"} {
+		if rr.Header().Get(header) != "" {
+			t.Errorf("Header %s should not be sent by the default profile", header)
+		}
+	}
 }
 
 func TestSecureHeadersRemovesServerHeaders(t *testing.T) {
@@ -202,9 +213,180 @@ func TestContentSecurityPolicyEnvironmentOverride(t *testing.T) {
 
 	handler.ServeHTTP(rr, req)
 
-	// Should use custom CSP from environment
+	// Should use custom CSP from environment, with the request nonce added
 	actualCSP := rr.Header().Get("Content-Security-Policy")
-	if actualCSP != customCSP {
-		t.Errorf("Expected CSP '%s', got '%s'", customCSP, actualCSP)
+	if !strings.HasPrefix(actualCSP, "default-src 'none'; script-src 'self' 'nonce-") {
+		t.Errorf("Expected CSP based on '%s', got '%s'", customCSP, actualCSP)
+	}
+}
+
+func TestContentSecurityPolicyNonce(t *testing.T) {
+	var nonce, templNonce string
+	handler := SecureHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce = csp.Nonce(r.Context())
+		templNonce = templ.GetNonce(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, testutils.NewTestRequest("GET", "/", ""))
+
+	if nonce == "" {
+		t.Fatal("Expected a nonce in the request context")
+	}
+	if templNonce != nonce {
+		t.Errorf("Expected templ nonce %q, got %q", nonce, templNonce)
+	}
+
+	policy := rr.Header().Get("Content-Security-Policy")
+	if strings.Contains(policy, "unsafe-inline") {
+		t.Errorf("CSP must not allow unsafe-inline: %s", policy)
+	}
+	for _, directive := range []string{"script-src 'self' 'nonce-" + nonce + "'", "style-src 'self' 'nonce-" + nonce + "'"} {
+		if !strings.Contains(policy, directive) {
+			t.Errorf("Expected CSP to contain %q, got %q", directive, policy)
+		}
+	}
+
+	// Each response gets a fresh nonce
+	rr2 := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr2, testutils.NewTestRequest("GET", "/", ""))
+	if rr2.Header().Get("Content-Security-Policy") == policy {
+		t.Error("Expected a different nonce per request")
+	}
+}
+
+func TestContentSecurityPolicyPageSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "before WriteHeader",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				csp.AddSources(r.Context(), "frame-src", "https://www.youtube-nocookie.com")
+				w.WriteHeader(http.StatusOK)
+			},
+		},
+		{
+			name: "before Write",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				csp.AddSources(r.Context(), "frame-src", "https://www.youtube-nocookie.com")
+				w.Write([]byte("ok"))
+			},
+		},
+		{
+			name: "no write",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				csp.AddSources(r.Context(), "frame-src", "https://www.youtube-nocookie.com")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := testutils.NewTestResponseRecorder()
+			SecureHeaders(tt.handler).ServeHTTP(rr, testutils.NewTestRequest("GET", "/", ""))
+
+			policy := rr.Header().Get("Content-Security-Policy")
+			if !strings.Contains(policy, "frame-src https://www.youtube-nocookie.com") {
+				t.Errorf("Expected page source in CSP, got %q", policy)
+			}
+			if !strings.Contains(policy, "default-src 'self'") {
+				t.Errorf("Expected rest of the policy to be kept, got %q", policy)
+			}
+		})
+	}
+}
+
+func TestContentSecurityPolicyThroughCompress(t *testing.T) {
+	body := strings.Repeat("<p>embedded video</p>", 100)
+	handler := Compress(64)(SecureHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		csp.AddSources(r.Context(), "frame-src", "https://player.example.com")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(body))
+	})))
+
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, testutils.NewTestRequestWithHeaders("GET", "/", map[string]string{"Accept-Encoding": "gzip"}))
+
+	rr.AssertHeader(t, "Content-Encoding", "gzip")
+	if !strings.Contains(rr.Header().Get("Content-Security-Policy"), "frame-src https://player.example.com") {
+		t.Errorf("Expected page source in CSP, got %q", rr.Header().Get("Content-Security-Policy"))
+	}
+}
+
+func TestContentSecurityPolicyNotModified(t *testing.T) {
+	handler := SecureHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, testutils.NewTestRequest("GET", "/", ""))
+
+	rr.AssertStatusCode(t, http.StatusNotModified)
+	// A new nonce would not match the one in the client's cached page
+	rr.AssertHeader(t, "Content-Security-Policy", "")
+	rr.AssertHeader(t, "X-Content-Type-Options", "nosniff")
+}
+
+func TestSecureHeadersCSPReporting(t *testing.T) {
+	tests := []struct {
+		name              string
+		opts              HeaderOptions
+		expectedHeader    string
+		absentHeader      string
+		expectedReporting string
+		expectedInPolicy  []string
+	}{
+		{
+			name:              "enforced with reporting",
+			opts:              HeaderOptions{CSPReportURI: "/csp-report"},
+			expectedHeader:    "Content-Security-Policy",
+			absentHeader:      "Content-Security-Policy-Report-Only",
+			expectedReporting: `csp-endpoint="/csp-report"`,
+			expectedInPolicy:  []string{"report-uri /csp-report", "report-to csp-endpoint"},
+		},
+		{
+			name:              "report-only",
+			opts:              HeaderOptions{CSPReportURI: "https://reports.example.com/csp", CSPReportOnly: true},
+			expectedHeader:    "Content-Security-Policy-Report-Only",
+			absentHeader:      "Content-Security-Policy",
+			expectedReporting: `csp-endpoint="https://reports.example.com/csp"`,
+			expectedInPolicy:  []string{"report-uri https://reports.example.com/csp"},
+		},
+		{
+			name:           "reporting disabled",
+			opts:           HeaderOptions{},
+			expectedHeader: "Content-Security-Policy",
+			absentHeader:   "Content-Security-Policy-Report-Only",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := SecureHeadersWith(tt.opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			rr := testutils.NewTestResponseRecorder()
+			handler.ServeHTTP(rr, testutils.NewTestRequest("GET", "/", ""))
+
+			policy := rr.Header().Get(tt.expectedHeader)
+			if policy == "" {
+				t.Fatalf("Expected %s header", tt.expectedHeader)
+			}
+			if rr.Header().Get(tt.absentHeader) != "" {
+				t.Errorf("Expected no %s header", tt.absentHeader)
+			}
+			rr.AssertHeader(t, "Reporting-Endpoints", tt.expectedReporting)
+			for _, directive := range tt.expectedInPolicy {
+				if !strings.Contains(policy, directive) {
+					t.Errorf("Expected policy to contain %q, got %q", directive, policy)
+				}
+			}
+			if tt.opts.CSPReportURI == "" && strings.Contains(policy, "report-") {
+				t.Errorf("Expected no reporting directives, got %q", policy)
+			}
+		})
 	}
 }
@@ -1,7 +1,7 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -31,7 +31,21 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
-// Logger logs HTTP requests with method, path, status, and duration
+// Flush passes through to the underlying writer so streaming and
+// compressed responses aren't held back by the wrapper
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Logger logs HTTP requests with method, path, status, and duration.
+// Records are logged with the request context so trace IDs are attached.
 func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -41,13 +55,12 @@ func Logger(next http.Handler) http.Handler {
 
 		duration := time.Since(start)
 
-		log.Printf(
-			"%s %s %d %s %s",
-			r.Method,
-			r.RequestURI,
-			wrapped.statusCode,
-			duration,
-			r.RemoteAddr,
+		slog.InfoContext(r.Context(), "request",
+			"method", r.Method,
+			"uri", r.RequestURI,
+			"status", wrapped.statusCode,
+			"duration", duration,
+			"remote_addr", r.RemoteAddr,
 		)
 	})
 }
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/claykom/website/internal/metrics"
+	"github.com/gorilla/mux"
+)
+
+var (
+	httpRequestsTotal = metrics.DefaultRegistry.NewCounterVec(
+		"website_http_requests_total",
+		"Total HTTP requests by route template, method and status class.",
+		"route", "method", "status",
+	)
+	httpRequestDuration = metrics.DefaultRegistry.NewHistogramVec(
+		"website_http_request_duration_seconds",
+		"HTTP request latency by route template and method.",
+		nil,
+		"route", "method",
+	)
+	rateLimitRejections = metrics.DefaultRegistry.NewCounter(
+		"website_rate_limit_rejections_total",
+		"Requests rejected by the rate limiter.",
+	)
+	panicsRecovered = metrics.DefaultRegistry.NewCounter(
+		"website_panics_recovered_total",
+		"Panics caught by the Recovery middleware.",
+	)
+)
+
+// Metrics records request counts and latency labelled by the matched route
+// template rather than the raw path, so slugs don't explode cardinality
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		wrapped := newResponseWriter(w)
+		next.ServeHTTP(wrapped, r)
+
+		route := routeTemplate(r)
+		method := normalizeMethod(r.Method)
+		httpRequestsTotal.WithLabelValues(route, method, statusClass(wrapped.statusCode)).Inc()
+		httpRequestDuration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routeTemplate returns the mux path template for the request, or
+// "unmatched" for requests that reached the NotFound/MethodNotAllowed handlers
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "unmatched"
+	}
+	if tpl, err := route.GetPathTemplate(); err == nil {
+		return tpl
+	}
+	return "unmatched"
+}
+
+// normalizeMethod folds non-standard methods into one label value
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
+
+// statusClass maps a status code to "2xx", "4xx" and so on
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claykom/website/internal/metrics"
+	"github.com/claykom/website/internal/testutils"
+	"github.com/gorilla/mux"
+)
+
+func TestMetricsUsesRouteTemplate(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(Metrics)
+	r.HandleFunc("/metrics-test/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	before := httpRequestsTotal.WithLabelValues("/metrics-test/{slug}", "GET", "4xx").Value()
+
+	for _, slug := range []string{"one", "two", "three"} {
+		req := testutils.NewTestRequest("GET", "/metrics-test/"+slug, "")
+		rr := testutils.NewTestResponseRecorder()
+		r.ServeHTTP(rr, req)
+	}
+
+	after := httpRequestsTotal.WithLabelValues("/metrics-test/{slug}", "GET", "4xx").Value()
+	if after-before != 3 {
+		t.Errorf("Expected 3 requests recorded under the route template, got %v", after-before)
+	}
+
+	var buf bytes.Buffer
+	metrics.DefaultRegistry.Write(&buf)
+	if strings.Contains(buf.String(), "/metrics-test/one") {
+		t.Error("Raw request path leaked into metric labels")
+	}
+}
+
+func TestMetricsCountsRateLimitRejections(t *testing.T) {
+	store := NewRateLimitStore(time.Hour)
+	handler := RateLimit(store, 1, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	before := rateLimitRejections.Value()
+	for i := 0; i < 3; i++ {
+		req := testutils.NewTestRequest("GET", "/", "")
+		req.RemoteAddr = "203.0.113.50:1234"
+		handler.ServeHTTP(testutils.NewTestResponseRecorder(), req)
+	}
+
+	if got := rateLimitRejections.Value() - before; got != 2 {
+		t.Errorf("Expected 2 rejections, got %v", got)
+	}
+}
+
+func TestMetricsCountsRecoveredPanics(t *testing.T) {
+	handler := Metrics(Recovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+
+	before := panicsRecovered.Value()
+	req := testutils.NewTestRequest("GET", "/", "")
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, req)
+
+	rr.AssertStatusCode(t, http.StatusInternalServerError)
+	if got := panicsRecovered.Value() - before; got != 1 {
+		t.Errorf("Expected 1 recovered panic, got %v", got)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	tests := map[int]string{
+		200: "2xx",
+		304: "3xx",
+		404: "4xx",
+		503: "5xx",
+		42:  "unknown",
+	}
+	for code, expected := range tests {
+		if got := statusClass(code); got != expected {
+			t.Errorf("statusClass(%d) = %q, want %q", code, got, expected)
+		}
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	handler := BearerAuth("secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		header         string
+		expectedStatus int
+	}{
+		{"valid token", "Bearer secret", http.StatusOK},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"missing header", "", http.StatusUnauthorized},
+		{"basic scheme", "Basic secret", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := testutils.NewTestRequest("GET", "/metrics", "")
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := testutils.NewTestResponseRecorder()
+			handler.ServeHTTP(rr, req)
+			rr.AssertStatusCode(t, tt.expectedStatus)
+		})
+	}
+}
+
+func TestBearerAuthEmptyTokenDenies(t *testing.T) {
+	handler := BearerAuth("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := testutils.NewTestRequest("GET", "/metrics", "")
+	req.Header.Set("Authorization", "Bearer ")
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, req)
+
+	rr.AssertStatusCode(t, http.StatusUnauthorized)
+}
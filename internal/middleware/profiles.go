@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/claykom/website/internal/csp"
+)
+
+// HeaderProfile is a named set of security header values. An empty value
+// means the header is not sent.
+type HeaderProfile struct {
+	Name                         string
+	FrameOptions                 string
+	ReferrerPolicy               string
+	StrictTransportSecurity      string
+	PermissionsPolicy            string
+	CrossOriginEmbedderPolicy    string
+	CrossOriginOpenerPolicy      string
+	CrossOriginResourcePolicy    string
+	PermittedCrossDomainPolicies string
+	// CSP is the base Content-Security-Policy, cloned for every response
+	CSP *csp.Policy
+}
+
+// defaultPermissionsPolicy turns off powerful features the site never uses
+const defaultPermissionsPolicy = "accelerometer=(), browsing-topics=(), camera=(), display-capture=(), " +
+	"geolocation=(), gyroscope=(), magnetometer=(), microphone=(), midi=(), payment=(), usb=()"
+
+// DefaultProfile suits a public site that links to cross-origin images:
+// no Cross-Origin-Embedder-Policy, since require-corp would block every
+// image host that doesn't send Cross-Origin-Resource-Policy.
+func DefaultProfile() HeaderProfile {
+	return HeaderProfile{
+		Name:                         "default",
+		FrameOptions:                 "DENY",
+		ReferrerPolicy:               "strict-origin-when-cross-origin",
+		StrictTransportSecurity:      "max-age=31536000; includeSubDomains; preload",
+		PermissionsPolicy:            defaultPermissionsPolicy,
+		CrossOriginOpenerPolicy:      "same-origin",
+		CrossOriginResourcePolicy:    "same-origin",
+		PermittedCrossDomainPolicies: "none",
+		CSP:                          csp.Default(),
+	}
+}
+
+// StrictProfile enables cross-origin isolation. Images, media and fonts
+// must be same-origin to be compatible with require-corp.
+func StrictProfile() HeaderProfile {
+	p := DefaultProfile()
+	p.Name = "strict"
+	p.ReferrerPolicy = "no-referrer"
+	p.StrictTransportSecurity = "max-age=63072000; includeSubDomains; preload"
+	p.PermissionsPolicy = defaultPermissionsPolicy + ", autoplay=(), fullscreen=(), picture-in-picture=()"
+	p.CrossOriginEmbedderPolicy = "require-corp"
+	p.CSP = csp.Default().Set("img-src", "'self'", "data:")
+	return p
+}
+
+// DevelopmentProfile is for local work over plain HTTP: no HSTS, which
+// would pin localhost to HTTPS in the browser
+func DevelopmentProfile() HeaderProfile {
+	p := DefaultProfile()
+	p.Name = "development"
+	p.StrictTransportSecurity = ""
+	return p
+}
+
+// Profile returns the named header profile
+func Profile(name string) (HeaderProfile, error) {
+	switch name {
+	case "", "default":
+		return DefaultProfile(), nil
+	case "strict":
+		return StrictProfile(), nil
+	case "development":
+		return DevelopmentProfile(), nil
+	}
+	return HeaderProfile{}, fmt.Errorf("unknown security header profile %q", name)
+}
+
+// apply sets the profile's headers; HSTS only over HTTPS
+func (p HeaderProfile) apply(h http.Header, https bool) {
+	set := func(name, value string) {
+		if value != "" {
+			h.Set(name, value)
+		}
+	}
+	set("X-Content-Type-Options", "nosniff")
+	set("X-Frame-Options", p.FrameOptions)
+	set("Referrer-Policy", p.ReferrerPolicy)
+	set("Permissions-Policy", p.PermissionsPolicy)
+	set("Cross-Origin-Embedder-Policy", p.CrossOriginEmbedderPolicy)
+	set("Cross-Origin-Opener-Policy", p.CrossOriginOpenerPolicy)
+	set("Cross-Origin-Resource-Policy", p.CrossOriginResourcePolicy)
+	set("X-Permitted-Cross-Domain-Policies", p.PermittedCrossDomainPolicies)
+	if https {
+		set("Strict-Transport-Security", p.StrictTransportSecurity)
+	}
+}
+
+// RouteHeaders overrides headers for requests under PathPrefix. An empty
+// value removes the header. A Content-Security-Policy value replaces the
+// base policy; nonces and reporting are still added to it.
+type RouteHeaders struct {
+	PathPrefix string
+	Headers    map[string]string
+}
+
+// Check reports contradictory or ineffective header combinations. It is
+// meant to be logged at startup.
+func (opts HeaderOptions) Check() []string {
+	p := opts.profile()
+	policy := getContentSecurityPolicy(p)
+	var warnings []string
+
+	if p.CrossOriginEmbedderPolicy == "require-corp" {
+		for _, directive := range []string{"img-src", "media-src", "font-src", "default-src"} {
+			if allowsCrossOrigin(policy.Sources(directive)) {
+				warnings = append(warnings, fmt.Sprintf(
+					"Cross-Origin-Embedder-Policy: require-corp blocks cross-origin resources that CSP %s allows, unless they send Cross-Origin-Resource-Policy", directive))
+			}
+		}
+	}
+
+	ancestors := policy.Sources("frame-ancestors")
+	switch {
+	case p.FrameOptions == "DENY" && ancestors != nil && !slices.Equal(ancestors, []string{"'none'"}):
+		warnings = append(warnings, "X-Frame-Options: DENY contradicts CSP frame-ancestors "+strings.Join(ancestors, " "))
+	case p.FrameOptions == "SAMEORIGIN" && slices.Equal(ancestors, []string{"'none'"}):
+		warnings = append(warnings, "X-Frame-Options: SAMEORIGIN contradicts CSP frame-ancestors 'none'")
+	}
+
+	if hsts := p.StrictTransportSecurity; strings.Contains(hsts, "preload") &&
+		(!strings.Contains(hsts, "includeSubDomains") || hstsMaxAge(hsts) < 31536000) {
+		warnings = append(warnings, "Strict-Transport-Security preload requires includeSubDomains and max-age of at least 31536000")
+	}
+
+	if p.CrossOriginEmbedderPolicy == "require-corp" && p.CrossOriginOpenerPolicy != "same-origin" {
+		warnings = append(warnings, "Cross-Origin-Embedder-Policy: require-corp without Cross-Origin-Opener-Policy: same-origin does not enable cross-origin isolation")
+	}
+
+	if opts.CSPReportOnly && opts.CSPReportURI == "" {
+		warnings = append(warnings, "Content-Security-Policy-Report-Only without a report URI neither blocks nor reports anything")
+	}
+
+	for _, route := range opts.Routes {
+		for name, value := range route.Headers {
+			switch http.CanonicalHeaderKey(name) {
+			case "X-Frame-Options":
+				if value == "" && slices.Equal(policy.Sources("frame-ancestors"), []string{"'none'"}) {
+					warnings = append(warnings, fmt.Sprintf(
+						"removing X-Frame-Options under %s has no effect while CSP frame-ancestors is 'none'", route.PathPrefix))
+				}
+			case "Cross-Origin-Embedder-Policy":
+				if value == "require-corp" && p.CrossOriginEmbedderPolicy != "require-corp" && allowsCrossOrigin(policy.Sources("img-src")) {
+					warnings = append(warnings, fmt.Sprintf(
+						"Cross-Origin-Embedder-Policy: require-corp under %s blocks cross-origin images that CSP img-src allows", route.PathPrefix))
+				}
+			}
+		}
+	}
+
+	return warnings
+}
+
+// allowsCrossOrigin reports whether a source list admits other origins
+func allowsCrossOrigin(sources []string) bool {
+	for _, source := range sources {
+		switch source {
+		case "'self'", "'none'", "data:", "blob:":
+			continue
+		}
+		if strings.HasPrefix(source, "'nonce-") || strings.HasPrefix(source, "'sha") {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// hstsMaxAge extracts max-age from a Strict-Transport-Security value
+func hstsMaxAge(value string) int {
+	for _, part := range strings.Split(value, ";") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(part), "max-age="); ok {
+			age, _ := strconv.Atoi(v)
+			return age
+		}
+	}
+	return 0
+}
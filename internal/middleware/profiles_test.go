@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/claykom/website/internal/csp"
+	"github.com/claykom/website/internal/testutils"
+)
+
+func TestProfiles(t *testing.T) {
+	tests := []struct {
+		profile  string
+		https    bool
+		expected map[string]string
+	}{
+		{
+			profile: "default",
+			https:   true,
+			expected: map[string]string{
+				"Cross-Origin-Embedder-Policy": "",
+				"Strict-Transport-Security":    "max-age=31536000; includeSubDomains; preload",
+				"Referrer-Policy":              "strict-origin-when-cross-origin",
+			},
+		},
+		{
+			profile: "strict",
+			https:   true,
+			expected: map[string]string{
+				"Cross-Origin-Embedder-Policy": "require-corp",
+				"Cross-Origin-Opener-Policy":   "same-origin",
+				"Strict-Transport-Security":    "max-age=63072000; includeSubDomains; preload",
+				"Referrer-Policy":              "no-referrer",
+			},
+		},
+		{
+			profile: "development",
+			https:   true,
+			expected: map[string]string{
+				"Strict-Transport-Security": "",
+				"X-Frame-Options":           "DENY",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			profile, err := Profile(tt.profile)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			handler := SecureHeadersWith(HeaderOptions{Profile: &profile})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			req := testutils.NewTestRequest("GET", "/", "")
+			if tt.https {
+				req.Header.Set("X-Forwarded-Proto", "https")
+			}
+			rr := testutils.NewTestResponseRecorder()
+			handler.ServeHTTP(rr, req)
+
+			for header, expected := range tt.expected {
+				rr.AssertHeader(t, header, expected)
+			}
+			if rr.Header().Get("X-XSS-Protection") != "" {
+				t.Error("X-XSS-Protection should not be sent")
+			}
+		})
+	}
+
+	if _, err := Profile("paranoid"); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+}
+
+func TestStrictProfileIsConsistent(t *testing.T) {
+	profile := StrictProfile()
+	if warnings := (HeaderOptions{Profile: &profile, CSPReportURI: "/csp-report"}).Check(); len(warnings) != 0 {
+		t.Errorf("Expected strict profile to pass its own check, got %v", warnings)
+	}
+
+	// Cross-origin images would be blocked by require-corp
+	if slices.Contains(profile.CSP.Sources("img-src"), "https:") {
+		t.Error("Strict profile must not allow cross-origin images in CSP")
+	}
+}
+
+func TestRouteHeaderOverrides(t *testing.T) {
+	opts := HeaderOptions{
+		Routes: []RouteHeaders{
+			{PathPrefix: "/embed/", Headers: map[string]string{
+				"X-Frame-Options":         "SAMEORIGIN",
+				"content-security-policy": "default-src 'self'; frame-ancestors 'self'",
+			}},
+			{PathPrefix: "/embed/video/", Headers: map[string]string{"X-Frame-Options": ""}},
+			{PathPrefix: "/", Headers: map[string]string{"Referrer-Policy": "no-referrer"}},
+		},
+	}
+	handler := SecureHeadersWith(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		path          string
+		frameOptions  string
+		cspContains   string
+		referrer      string
+		cspNotContain string
+	}{
+		{"/blog", "DENY", "frame-ancestors 'none'", "no-referrer", ""},
+		{"/embed/widget", "SAMEORIGIN", "frame-ancestors 'self'", "no-referrer", "object-src"},
+		{"/embed/video/1", "", "frame-ancestors 'self'", "no-referrer", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := testutils.NewTestResponseRecorder()
+			handler.ServeHTTP(rr, testutils.NewTestRequest("GET", tt.path, ""))
+
+			rr.AssertHeader(t, "X-Frame-Options", tt.frameOptions)
+			rr.AssertHeader(t, "Referrer-Policy", tt.referrer)
+			policy := rr.Header().Get("Content-Security-Policy")
+			if !strings.Contains(policy, tt.cspContains) {
+				t.Errorf("Expected CSP to contain %q, got %q", tt.cspContains, policy)
+			}
+			if !strings.Contains(policy, "'nonce-") {
+				t.Errorf("Expected nonce in overridden CSP, got %q", policy)
+			}
+			if tt.cspNotContain != "" && strings.Contains(policy, tt.cspNotContain) {
+				t.Errorf("Expected CSP not to contain %q, got %q", tt.cspNotContain, policy)
+			}
+		})
+	}
+}
+
+func TestHeaderOptionsCheck(t *testing.T) {
+	coepWithImages := DefaultProfile()
+	coepWithImages.CrossOriginEmbedderPolicy = "require-corp"
+
+	frameMismatch := DefaultProfile()
+	frameMismatch.FrameOptions = "SAMEORIGIN"
+
+	weakPreload := DefaultProfile()
+	weakPreload.StrictTransportSecurity = "max-age=300; preload"
+
+	lonelyCOEP := StrictProfile()
+	lonelyCOEP.CrossOriginOpenerPolicy = ""
+
+	ancestorsSelf := DefaultProfile()
+	ancestorsSelf.CSP = csp.Default().Set("frame-ancestors", "'self'")
+
+	tests := []struct {
+		name     string
+		opts     HeaderOptions
+		expected string
+	}{
+		{"default is clean", HeaderOptions{CSPReportURI: "/csp-report"}, ""},
+		{"coep blocks images", HeaderOptions{Profile: &coepWithImages}, "require-corp blocks cross-origin resources that CSP img-src allows"},
+		{"frame options vs frame-ancestors", HeaderOptions{Profile: &frameMismatch}, "SAMEORIGIN contradicts CSP frame-ancestors 'none'"},
+		{"deny vs frame-ancestors self", HeaderOptions{Profile: &ancestorsSelf}, "DENY contradicts CSP frame-ancestors 'self'"},
+		{"weak hsts preload", HeaderOptions{Profile: &weakPreload}, "preload requires includeSubDomains"},
+		{"coep without coop", HeaderOptions{Profile: &lonelyCOEP}, "does not enable cross-origin isolation"},
+		{"report-only without reporting", HeaderOptions{CSPReportOnly: true}, "neither blocks nor reports"},
+		{"route removes frame options", HeaderOptions{Routes: []RouteHeaders{{PathPrefix: "/embed/", Headers: map[string]string{"X-Frame-Options": ""}}}}, "has no effect while CSP frame-ancestors is 'none'"},
+		{"route adds coep", HeaderOptions{Routes: []RouteHeaders{{PathPrefix: "/lab/", Headers: map[string]string{"Cross-Origin-Embedder-Policy": "require-corp"}}}}, "under /lab/ blocks cross-origin images"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := tt.opts.Check()
+			if tt.expected == "" {
+				if len(warnings) != 0 {
+					t.Errorf("Expected no warnings, got %v", warnings)
+				}
+				return
+			}
+			found := false
+			for _, w := range warnings {
+				if strings.Contains(w, tt.expected) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected warning containing %q, got %v", tt.expected, warnings)
+			}
+		})
+	}
+}
@@ -53,6 +53,13 @@ func (r *RateLimitStore) Allow(ip string, maxRequests int, window time.Duration)
 	limiter.mutex.Lock()
 	defer limiter.mutex.Unlock()
 
+	// Limits changed since the limiter was created, e.g. by a config reload
+	if refillRate := window / time.Duration(maxRequests); limiter.maxTokens != maxRequests || limiter.refillRate != refillRate {
+		limiter.maxTokens = maxRequests
+		limiter.refillRate = refillRate
+		limiter.tokens = min(limiter.tokens, maxRequests)
+	}
+
 	// Refill tokens based on elapsed time
 	now := time.Now()
 	elapsed := now.Sub(limiter.lastRefill)
@@ -135,6 +142,7 @@ func RateLimit(store *RateLimitStore, maxRequests int, window time.Duration) fun
 			ip := getClientIP(r)
 
 			if !store.Allow(ip, maxRequests, window) {
+				rateLimitRejections.Inc()
 				w.Header().Set("Retry-After", "60")
 				http.Error(w, "Rate limit exceeded. Too many requests.", http.StatusTooManyRequests)
 				return
@@ -67,6 +67,26 @@ func TestRateLimiter(t *testing.T) {
 	}
 }
 
+func TestRateLimitChangedLimits(t *testing.T) {
+	store := NewRateLimitStore(time.Hour)
+	ip := "192.168.1.1"
+
+	for i := 0; i < 5; i++ {
+		store.Allow(ip, 10, time.Minute)
+	}
+
+	// Lowering the limit caps the remaining tokens
+	passed := 0
+	for i := 0; i < 5; i++ {
+		if store.Allow(ip, 2, time.Minute) {
+			passed++
+		}
+	}
+	if passed != 2 {
+		t.Errorf("Expected 2 requests to pass under the new limit, got %d", passed)
+	}
+}
+
 func TestRateLimitMiddleware(t *testing.T) {
 	tests := []struct {
 		name            string
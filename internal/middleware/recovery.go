@@ -13,6 +13,7 @@ func Recovery(next http.Handler) http.Handler {
 			if err := recover(); err != nil {
 				// Log the panic and stack trace
 				log.Printf("PANIC: %v\n%s", err, debug.Stack())
+				panicsRecovered.Inc()
 
 				// Return a 500 Internal Server Error
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Trailing slash modes for TrailingSlash
+const (
+	TrailingSlashStrip = "strip"
+	TrailingSlashAdd   = "add"
+)
+
+// HTTPSRedirect answers every request on a plain HTTP listener with a
+// redirect to the same URL over HTTPS. host replaces the request's host
+// when set, so www and apex requests land on the canonical host in one hop;
+// port is the public HTTPS port and is omitted when it is 443.
+func HTTPSRedirect(host string, port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := host
+		if target == "" {
+			target = hostname(r.Host)
+		}
+		if !validHost(target) {
+			http.Error(w, "Invalid host", http.StatusBadRequest)
+			return
+		}
+		if port != 443 {
+			target = net.JoinHostPort(target, strconv.Itoa(port))
+		} else if strings.Contains(target, ":") {
+			// IPv6 literals need brackets even without a port
+			target = "[" + target + "]"
+		}
+
+		u := url.URL{Scheme: "https", Host: target, Path: r.URL.Path, RawPath: r.URL.RawPath, RawQuery: r.URL.RawQuery}
+		redirect(w, r, u.String())
+	})
+}
+
+// CanonicalHost redirects requests for any other host name, such as www,
+// to host, keeping the scheme, port, path and query. Requests addressed to
+// an IP literal or localhost pass through, so health checks and probes that
+// connect directly keep working.
+func CanonicalHost(host string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if host == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := hostname(r.Host)
+			if name == "" || strings.EqualFold(name, host) || name == "localhost" || net.ParseIP(name) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			target := host
+			if _, port, err := net.SplitHostPort(r.Host); err == nil {
+				target = net.JoinHostPort(host, port)
+			}
+			scheme := "http"
+			if isHTTPS(r) {
+				scheme = "https"
+			}
+
+			u := url.URL{Scheme: scheme, Host: target, Path: r.URL.Path, RawPath: r.URL.RawPath, RawQuery: r.URL.RawQuery}
+			redirect(w, r, u.String())
+		})
+	}
+}
+
+// TrailingSlash normalises the paths of pages so each has one URL. In
+// strip mode "/blog/" redirects to "/blog". In add mode "/blog" redirects
+// to "/blog/" and the slash is removed again before routing, so routes are
+// registered without it either way. Only paths under pages, such as
+// "/blog" and "/blog/post", are affected: probes, APIs and files answer
+// where they are. Paths whose last segment has a file extension are left
+// alone, and only GET and HEAD requests are redirected.
+func TrailingSlash(mode string, pages []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if mode != TrailingSlashStrip && mode != TrailingSlashAdd {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := r.URL.Path
+			if !underPrefix(strings.TrimRight(p, "/"), pages) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			hasSlash := strings.HasSuffix(p, "/")
+			redirectable := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+			switch {
+			case mode == TrailingSlashStrip && hasSlash && redirectable:
+				redirectPath(w, r, strings.TrimRight(p, "/"))
+				return
+			case mode == TrailingSlashAdd && !hasSlash && redirectable && !hasExtension(p):
+				redirectPath(w, r, p+"/")
+				return
+			case mode == TrailingSlashAdd && hasSlash:
+				r2 := r.Clone(r.Context())
+				r2.URL.Path = strings.TrimRight(p, "/")
+				r2.URL.RawPath = ""
+				r = r2
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isHTTPS reports whether the client connected over HTTPS, directly or
+// through a proxy that sets X-Forwarded-Proto
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// redirect sends a permanent redirect: 301 for GET and HEAD, 308 for other
+// methods so clients repeat the request body
+func redirect(w http.ResponseWriter, r *http.Request, target string) {
+	code := http.StatusMovedPermanently
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+	http.Redirect(w, r, target, code)
+}
+
+// redirectPath redirects to path on the same host, keeping the query
+func redirectPath(w http.ResponseWriter, r *http.Request, path string) {
+	if path == "" {
+		path = "/"
+	}
+	// A leading "//" would be read as a host by the client
+	path = "/" + strings.TrimLeft(path, "/")
+	u := url.URL{Path: path, RawQuery: r.URL.RawQuery}
+	redirect(w, r, u.String())
+}
+
+// hostname returns the host part of a Host header, without the port
+func hostname(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return strings.Trim(hostport, "[]")
+}
+
+// underPrefix reports whether p is one of prefixes or a path below one
+func underPrefix(p string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasExtension reports whether the last path segment looks like a file
+func hasExtension(p string) bool {
+	return strings.Contains(p[strings.LastIndex(p, "/")+1:], ".")
+}
+
+// validHost reports whether host is a plausible DNS name or IP literal, so
+// a forged Host header can't inject anything into a redirect URL
+func validHost(host string) bool {
+	if host == "" || len(host) > 253 {
+		return false
+	}
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	for _, c := range host {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"github.com/claykom/website/internal/testutils"
+)
+
+func TestHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		name             string
+		canonical        string
+		port             int
+		method           string
+		host             string
+		path             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{"default port", "", 443, "GET", "example.com", "/blog?page=2", http.StatusMovedPermanently, "https://example.com/blog?page=2"},
+		{"request port dropped", "", 443, "GET", "example.com:80", "/", http.StatusMovedPermanently, "https://example.com/"},
+		{"custom https port", "", 8443, "GET", "example.com:8080", "/blog", http.StatusMovedPermanently, "https://example.com:8443/blog"},
+		{"canonical host", "example.com", 443, "GET", "www.example.com", "/blog", http.StatusMovedPermanently, "https://example.com/blog"},
+		{"ipv6 literal", "", 443, "GET", "[::1]:80", "/", http.StatusMovedPermanently, "https://[::1]/"},
+		{"post keeps method", "", 443, "POST", "example.com", "/csp-report", http.StatusPermanentRedirect, "https://example.com/csp-report"},
+		{"forged host", "", 443, "GET", "evil.com/@example.com", "/", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := testutils.NewTestRequest(tt.method, tt.path, "")
+			req.Host = tt.host
+			rr := testutils.NewTestResponseRecorder()
+
+			HTTPSRedirect(tt.canonical, tt.port).ServeHTTP(rr, req)
+
+			rr.AssertStatusCode(t, tt.expectedStatus)
+			rr.AssertHeader(t, "Location", tt.expectedLocation)
+		})
+	}
+}
+
+func TestCanonicalHost(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name             string
+		host             string
+		https            bool
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{"canonical", "example.com", false, http.StatusOK, ""},
+		{"canonical with different case", "Example.COM", false, http.StatusOK, ""},
+		{"www over https", "www.example.com", true, http.StatusMovedPermanently, "https://example.com/blog?x=1"},
+		{"www over http keeps port", "www.example.com:8080", false, http.StatusMovedPermanently, "http://example.com:8080/blog?x=1"},
+		{"ip literal", "10.0.0.5:8080", false, http.StatusOK, ""},
+		{"localhost", "localhost:8080", false, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := testutils.NewTestRequest("GET", "/blog?x=1", "")
+			req.Host = tt.host
+			if tt.https {
+				req.TLS = &tls.ConnectionState{}
+			}
+			rr := testutils.NewTestResponseRecorder()
+
+			CanonicalHost("example.com")(next).ServeHTTP(rr, req)
+
+			rr.AssertStatusCode(t, tt.expectedStatus)
+			rr.AssertHeader(t, "Location", tt.expectedLocation)
+		})
+	}
+}
+
+func TestTrailingSlash(t *testing.T) {
+	var seenPath string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name             string
+		mode             string
+		method           string
+		path             string
+		expectedStatus   int
+		expectedLocation string
+		expectedPath     string
+	}{
+		{"off", "", "GET", "/blog/", http.StatusOK, "", "/blog/"},
+		{"strip", TrailingSlashStrip, "GET", "/blog/?page=2", http.StatusMovedPermanently, "/blog?page=2", ""},
+		{"strip keeps root", TrailingSlashStrip, "GET", "/", http.StatusOK, "", "/"},
+		{"strip no slash", TrailingSlashStrip, "GET", "/blog", http.StatusOK, "", "/blog"},
+		{"strip leaves post alone", TrailingSlashStrip, "POST", "/blog/", http.StatusOK, "", "/blog/"},
+		{"strip repeated slashes", TrailingSlashStrip, "GET", "/blog//", http.StatusMovedPermanently, "/blog", ""},
+		{"add", TrailingSlashAdd, "GET", "/blog", http.StatusMovedPermanently, "/blog/", ""},
+		{"add routes without slash", TrailingSlashAdd, "GET", "/blog/", http.StatusOK, "", "/blog"},
+		{"add skips files", TrailingSlashAdd, "GET", "/robots.txt", http.StatusOK, "", "/robots.txt"},
+		{"add skips static", TrailingSlashAdd, "GET", "/static/css/style", http.StatusOK, "", "/static/css/style"},
+		{"add skips root", TrailingSlashAdd, "GET", "/", http.StatusOK, "", "/"},
+		{"add nested page", TrailingSlashAdd, "GET", "/blog/post", http.StatusMovedPermanently, "/blog/post/", ""},
+		{"add skips probes", TrailingSlashAdd, "GET", "/healthz", http.StatusOK, "", "/healthz"},
+		{"add skips api", TrailingSlashAdd, "GET", "/api/blog", http.StatusOK, "", "/api/blog"},
+		{"add skips prefix lookalikes", TrailingSlashAdd, "GET", "/blogroll", http.StatusOK, "", "/blogroll"},
+		{"strip skips api", TrailingSlashStrip, "GET", "/api/blog/", http.StatusOK, "", "/api/blog/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seenPath = ""
+			rr := testutils.NewTestResponseRecorder()
+
+			TrailingSlash(tt.mode, []string{"/blog"})(next).ServeHTTP(rr, testutils.NewTestRequest(tt.method, tt.path, ""))
+
+			rr.AssertStatusCode(t, tt.expectedStatus)
+			rr.AssertHeader(t, "Location", tt.expectedLocation)
+			if seenPath != tt.expectedPath {
+				t.Errorf("Expected handler to see path %q, got %q", tt.expectedPath, seenPath)
+			}
+		})
+	}
+}
@@ -1,14 +1,40 @@
 package middleware
 
 import (
+	"io"
+	"io/fs"
 	"net/http"
 	"path/filepath"
 	"strings"
+
+	"github.com/claykom/website/internal/assets"
 )
 
-// SecureStaticHandler creates a secure static file handler that prevents directory traversal
-func SecureStaticHandler(root http.Dir) http.Handler {
-	fileServer := http.FileServer(root)
+// StaticOption configures SecureStaticHandler
+type StaticOption func(*staticOptions)
+
+type staticOptions struct {
+	manifest *assets.Manifest
+}
+
+// WithManifest serves content-hashed file names from the manifest. Only
+// those names get immutable caching; plain names must be revalidated.
+func WithManifest(m *assets.Manifest) StaticOption {
+	return func(o *staticOptions) {
+		o.manifest = m
+	}
+}
+
+// SecureStaticHandler creates a secure static file handler that prevents
+// directory traversal. root may be a directory (os.DirFS) or an embed.FS
+// subtree.
+func SecureStaticHandler(root fs.FS, opts ...StaticOption) http.Handler {
+	options := &staticOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	fileServer := http.FileServerFS(root)
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Enhanced path traversal protection
@@ -30,8 +56,11 @@ func SecureStaticHandler(root http.Dir) http.Handler {
 			return
 		}
 
+		// Map fingerprinted names such as css/style.3f9a1c2b.css to the file on disk
+		name, fingerprinted := options.manifest.Resolve(strings.TrimPrefix(cleanPath, "/"))
+
 		// Restrict to allowed file extensions for security
-		ext := strings.ToLower(filepath.Ext(path))
+		ext := strings.ToLower(filepath.Ext(name))
 		allowedExtensions := map[string]bool{
 			".css":   true,
 			".js":    true,
@@ -68,11 +97,13 @@ func SecureStaticHandler(root http.Dir) http.Handler {
 		}
 
 		// Set cache headers for static assets
-		switch ext {
-		case ".css", ".js", ".png", ".jpg", ".jpeg", ".gif", ".ico", ".svg", ".woff", ".woff2", ".webp", ".avif":
-			// Cache static assets for 1 year
+		switch {
+		case fingerprinted:
+			// The name changes whenever the content does, so cache for 1 year
 			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
-			w.Header().Set("Expires", "Thu, 31 Dec 2026 23:59:59 GMT")
+		case allowedExtensions[ext]:
+			// Plain names may change content in place; revalidate every time
+			w.Header().Set("Cache-Control", "public, no-cache")
 		default:
 			// No cache for other files
 			w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
@@ -80,7 +111,63 @@ func SecureStaticHandler(root http.Dir) http.Handler {
 			w.Header().Set("Expires", "0")
 		}
 
+		// Prefer a precompressed .br/.gz sibling if the client accepts it
+		if ext != "" && servePrecompressed(w, r, root, name) {
+			return
+		}
+
 		// Serve the file
+		if name != strings.TrimPrefix(cleanPath, "/") {
+			r = r.Clone(r.Context())
+			r.URL.Path = "/" + name
+			r.URL.RawPath = ""
+		}
 		fileServer.ServeHTTP(w, r)
 	})
 }
+
+// servePrecompressed serves name.br or name.gz when present and acceptable
+// to the client. It reports whether a response was written.
+func servePrecompressed(w http.ResponseWriter, r *http.Request, root fs.FS, name string) bool {
+	qBr, qGzip := encodingQualities(r.Header.Get("Accept-Encoding"))
+
+	candidates := []struct {
+		encoding string
+		suffix   string
+		q        float64
+	}{
+		{"br", ".br", qBr},
+		{"gzip", ".gz", qGzip},
+	}
+	if qGzip > qBr {
+		candidates[0], candidates[1] = candidates[1], candidates[0]
+	}
+
+	for _, c := range candidates {
+		f, err := root.Open(name + c.suffix)
+		if err != nil {
+			continue
+		}
+		stat, err := f.Stat()
+		rs, seekable := f.(io.ReadSeeker)
+		if err != nil || stat.IsDir() || !seekable {
+			f.Close()
+			continue
+		}
+
+		// The representation depends on Accept-Encoding once a sibling exists
+		addVary(w.Header(), "Accept-Encoding")
+		if c.q <= 0 {
+			f.Close()
+			continue
+		}
+		defer f.Close()
+
+		w.Header().Set("Content-Encoding", c.encoding)
+		// Named after the original so ServeContent picks its Content-Type
+		http.ServeContent(w, r, name, stat.ModTime(), rs)
+		return true
+	}
+
+	return false
+}
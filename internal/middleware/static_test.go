@@ -6,7 +6,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 
+	"github.com/claykom/website/internal/assets"
 	"github.com/claykom/website/internal/testutils"
 )
 
@@ -43,7 +45,7 @@ func TestSecureStaticHandler(t *testing.T) {
 	outsideFile := filepath.Join(outsideDir, "secret.txt")
 	os.WriteFile(outsideFile, []byte("secret content"), 0644)
 
-	handler := SecureStaticHandler(http.Dir(tempDir))
+	handler := SecureStaticHandler(os.DirFS(tempDir))
 
 	tests := []struct {
 		name           string
@@ -62,7 +64,7 @@ func TestSecureStaticHandler(t *testing.T) {
 				"Content-Type":           "text/css; charset=utf-8",
 				"X-Content-Type-Options": "nosniff",
 				"X-Frame-Options":        "DENY",
-				"Cache-Control":          "public, max-age=31536000, immutable",
+				"Cache-Control":          "public, no-cache",
 			},
 			checkBody: ".body { color: red; }",
 		},
@@ -72,7 +74,7 @@ func TestSecureStaticHandler(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			checkHeaders: map[string]string{
 				"Content-Type":  "application/javascript; charset=utf-8",
-				"Cache-Control": "public, max-age=31536000, immutable",
+				"Cache-Control": "public, no-cache",
 			},
 			checkBody: "console.log('test');",
 		},
@@ -206,7 +208,7 @@ func TestSecureStaticHandlerMethods(t *testing.T) {
 	testFile := filepath.Join(tempDir, "test.css")
 	os.WriteFile(testFile, []byte(".test {}"), 0644)
 
-	handler := SecureStaticHandler(http.Dir(tempDir))
+	handler := SecureStaticHandler(os.DirFS(tempDir))
 
 	methods := []struct {
 		method         string
@@ -242,7 +244,7 @@ func TestSecureStaticHandlerMethods(t *testing.T) {
 
 func TestContentTypeDetection(t *testing.T) {
 	tempDir := t.TempDir()
-	handler := SecureStaticHandler(http.Dir(tempDir))
+	handler := SecureStaticHandler(os.DirFS(tempDir))
 
 	files := map[string]struct {
 		content     string
@@ -291,7 +293,7 @@ func TestSecurityHeaders(t *testing.T) {
 	testFile := filepath.Join(tempDir, "test.css")
 	os.WriteFile(testFile, []byte(".test {}"), 0644)
 
-	handler := SecureStaticHandler(http.Dir(tempDir))
+	handler := SecureStaticHandler(os.DirFS(tempDir))
 
 	req := testutils.NewTestRequest("GET", "/test.css", "")
 	rr := testutils.NewTestResponseRecorder()
@@ -302,7 +304,7 @@ func TestSecurityHeaders(t *testing.T) {
 		"X-Content-Type-Options": "nosniff",
 		"X-Frame-Options":        "DENY",
 		"Referrer-Policy":        "strict-origin-when-cross-origin",
-		"Cache-Control":          "public, max-age=31536000, immutable",
+		"Cache-Control":          "public, no-cache",
 	}
 
 	for headerName, expectedValue := range expectedHeaders {
@@ -322,7 +324,7 @@ func TestSecurityHeaders(t *testing.T) {
 func TestStaticHandlerEdgeCases(t *testing.T) {
 	t.Run("empty directory", func(t *testing.T) {
 		emptyDir := t.TempDir()
-		handler := SecureStaticHandler(http.Dir(emptyDir))
+		handler := SecureStaticHandler(os.DirFS(emptyDir))
 
 		req := testutils.NewTestRequest("GET", "/nonexistent.css", "")
 		rr := testutils.NewTestResponseRecorder()
@@ -336,7 +338,7 @@ func TestStaticHandlerEdgeCases(t *testing.T) {
 
 	t.Run("invalid directory", func(t *testing.T) {
 		// Test with non-existent directory
-		handler := SecureStaticHandler(http.Dir("/nonexistent/directory"))
+		handler := SecureStaticHandler(os.DirFS("/nonexistent/directory"))
 
 		req := testutils.NewTestRequest("GET", "/test.css", "")
 		rr := testutils.NewTestResponseRecorder()
@@ -363,7 +365,7 @@ func TestStaticHandlerEdgeCases(t *testing.T) {
 			t.Skip("Symbolic links not supported on this system")
 		}
 
-		handler := SecureStaticHandler(http.Dir(tempDir))
+		handler := SecureStaticHandler(os.DirFS(tempDir))
 
 		req := testutils.NewTestRequest("GET", "/link.css", "")
 		rr := testutils.NewTestResponseRecorder()
@@ -379,7 +381,7 @@ func TestStaticHandlerEdgeCases(t *testing.T) {
 
 	t.Run("very long paths", func(t *testing.T) {
 		tempDir := t.TempDir()
-		handler := SecureStaticHandler(http.Dir(tempDir))
+		handler := SecureStaticHandler(os.DirFS(tempDir))
 
 		// Create a very long path
 		longPath := "/" + strings.Repeat("a", 1000) + ".css"
@@ -404,6 +406,132 @@ func TestStaticHandlerEdgeCases(t *testing.T) {
 	})
 }
 
+func TestStaticFingerprintedAssets(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "css"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "css", "style.css"), []byte(".a { color: red; }"), 0644)
+
+	manifest, err := assets.NewManifest(os.DirFS(tempDir))
+	if err != nil {
+		t.Fatalf("Failed to build manifest: %v", err)
+	}
+	hashed, ok := manifest.Hashed("css/style.css")
+	if !ok {
+		t.Fatal("Expected css/style.css in manifest")
+	}
+
+	handler := SecureStaticHandler(os.DirFS(tempDir), WithManifest(manifest))
+
+	tests := []struct {
+		name          string
+		path          string
+		expectedCache string
+	}{
+		{"hashed name is immutable", "/" + hashed, "public, max-age=31536000, immutable"},
+		{"plain name revalidates", "/css/style.css", "public, no-cache"},
+		{"stale hash serves current file", "/css/style.0badc0de.css", "public, no-cache"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := testutils.NewTestRequest("GET", tt.path, "")
+			rr := testutils.NewTestResponseRecorder()
+			handler.ServeHTTP(rr, req)
+
+			rr.AssertStatusCode(t, http.StatusOK)
+			rr.AssertHeader(t, "Cache-Control", tt.expectedCache)
+			rr.AssertContentType(t, "text/css; charset=utf-8")
+			rr.AssertBodyContains(t, ".a { color: red; }")
+		})
+	}
+}
+
+func TestStaticPrecompressedSiblings(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "app.js"), []byte("console.log('plain');"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "app.js.br"), []byte("brotli-bytes"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "app.js.gz"), []byte("gzip-bytes"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "only.css"), []byte(".plain {}"), 0644)
+
+	handler := SecureStaticHandler(os.DirFS(tempDir))
+
+	tests := []struct {
+		name             string
+		path             string
+		acceptEncoding   string
+		expectedEncoding string
+		expectedBody     string
+		expectedVary     string
+	}{
+		{"brotli preferred", "/app.js", "gzip, br", "br", "brotli-bytes", "Accept-Encoding"},
+		{"gzip by q-value", "/app.js", "br;q=0.5, gzip", "gzip", "gzip-bytes", "Accept-Encoding"},
+		{"gzip only", "/app.js", "gzip", "gzip", "gzip-bytes", "Accept-Encoding"},
+		{"identity", "/app.js", "", "", "console.log('plain');", "Accept-Encoding"},
+		{"no sibling", "/only.css", "br", "", ".plain {}", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := testutils.NewTestRequestWithHeaders("GET", tt.path, map[string]string{"Accept-Encoding": tt.acceptEncoding})
+			rr := testutils.NewTestResponseRecorder()
+			handler.ServeHTTP(rr, req)
+
+			rr.AssertStatusCode(t, http.StatusOK)
+			rr.AssertHeader(t, "Content-Encoding", tt.expectedEncoding)
+			rr.AssertHeader(t, "Vary", tt.expectedVary)
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, rr.Body.String())
+			}
+			if tt.path == "/app.js" {
+				rr.AssertContentType(t, "application/javascript; charset=utf-8")
+			}
+		})
+	}
+
+	// Siblings are not directly addressable
+	req := testutils.NewTestRequest("GET", "/app.js.br", "")
+	rr := testutils.NewTestResponseRecorder()
+	handler.ServeHTTP(rr, req)
+	rr.AssertStatusCode(t, http.StatusForbidden)
+}
+
+func TestSecureStaticHandlerFS(t *testing.T) {
+	// Static files may come from an embed.FS instead of a directory
+	root := fstest.MapFS{
+		"css/site.css":    {Data: []byte(".embedded {}")},
+		"css/site.css.gz": {Data: []byte("gzip-bytes")},
+		"secret.txt":      {Data: []byte("secret")},
+	}
+	handler := SecureStaticHandler(root)
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"embedded file", "/css/site.css", "", http.StatusOK, ".embedded {}"},
+		{"embedded precompressed", "/css/site.css", "gzip", http.StatusOK, "gzip-bytes"},
+		{"disallowed extension", "/secret.txt", "", http.StatusForbidden, ""},
+		{"missing file", "/css/missing.css", "", http.StatusNotFound, ""},
+		{"traversal", "/../secret.txt", "", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := testutils.NewTestRequestWithHeaders("GET", tt.path, map[string]string{"Accept-Encoding": tt.acceptEncoding})
+			rr := testutils.NewTestResponseRecorder()
+			handler.ServeHTTP(rr, req)
+
+			rr.AssertStatusCode(t, tt.expectedStatus)
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
 // Benchmark tests
 func BenchmarkSecureStaticHandler(b *testing.B) {
 	tempDir := b.TempDir()
@@ -412,7 +540,7 @@ func BenchmarkSecureStaticHandler(b *testing.B) {
 	testFile := filepath.Join(tempDir, "test.css")
 	os.WriteFile(testFile, []byte(".benchmark { color: blue; }"), 0644)
 
-	handler := SecureStaticHandler(http.Dir(tempDir))
+	handler := SecureStaticHandler(os.DirFS(tempDir))
 	req := testutils.NewTestRequest("GET", "/test.css", "")
 
 	b.ResetTimer()
@@ -424,7 +552,7 @@ func BenchmarkSecureStaticHandler(b *testing.B) {
 
 func BenchmarkPathTraversalCheck(b *testing.B) {
 	tempDir := b.TempDir()
-	handler := SecureStaticHandler(http.Dir(tempDir))
+	handler := SecureStaticHandler(os.DirFS(tempDir))
 
 	maliciousPaths := []string{
 		"/../../../etc/passwd",
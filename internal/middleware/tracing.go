@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/claykom/website/internal/tracing"
+)
+
+// Tracing starts a server span for each request, continuing the trace from
+// an incoming W3C traceparent/tracestate when one is present. Handlers and
+// loggers further down find the span in the request context.
+func Tracing(tracer *tracing.Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if remote := tracing.Extract(r.Header); remote.IsValid() {
+				ctx = tracing.ContextWithRemoteSpanContext(ctx, remote)
+			}
+
+			route := routeTemplate(r)
+			ctx, span := tracer.Start(ctx, r.Method+" "+route,
+				tracing.WithKind(tracing.SpanKindServer),
+				tracing.WithAttributes(
+					tracing.String("http.request.method", r.Method),
+					tracing.String("http.route", route),
+					tracing.String("url.path", r.URL.Path),
+					tracing.String("user_agent.original", r.UserAgent()),
+				),
+			)
+			defer span.End()
+
+			wrapped := newResponseWriter(w)
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+			span.SetAttributes(tracing.Int("http.response.status_code", wrapped.statusCode))
+			if wrapped.statusCode >= 500 {
+				span.SetStatus(tracing.StatusError, http.StatusText(wrapped.statusCode))
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/claykom/website/internal/testutils"
+	"github.com/claykom/website/internal/tracing"
+	"github.com/gorilla/mux"
+)
+
+func TestTracingContinuesIncomingTrace(t *testing.T) {
+	var spans bytes.Buffer
+	tracer := tracing.NewTracer(tracing.NewWriterExporter("test", &spans))
+
+	var seen tracing.SpanContext
+	r := mux.NewRouter()
+	r.Use(Tracing(tracer))
+	r.HandleFunc("/blog/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		seen = tracing.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := testutils.NewTestRequestWithHeaders("GET", "/blog/hello", map[string]string{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"tracestate":  "vendor=value",
+	})
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, req)
+
+	if seen.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected handler to see the incoming trace ID, got %s", seen.TraceID)
+	}
+	if seen.TraceState != "vendor=value" {
+		t.Errorf("Expected tracestate to be propagated, got %q", seen.TraceState)
+	}
+
+	out := spans.String()
+	if !strings.Contains(out, `"name":"GET /blog/{slug}"`) {
+		t.Errorf("Expected server span named after the route template, got %s", out)
+	}
+	if !strings.Contains(out, `"parentSpanId":"00f067aa0ba902b7"`) {
+		t.Errorf("Expected server span to be a child of the remote span, got %s", out)
+	}
+}
+
+func TestTracingMarksServerErrors(t *testing.T) {
+	var spans bytes.Buffer
+	tracer := tracing.NewTracer(tracing.NewWriterExporter("test", &spans))
+
+	handler := Tracing(tracer)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	handler.ServeHTTP(testutils.NewTestResponseRecorder(), testutils.NewTestRequest("GET", "/", ""))
+
+	var payload struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					Status struct {
+						Code int `json:"code"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(spans.Bytes(), &payload); err != nil {
+		t.Fatalf("Invalid span output: %v", err)
+	}
+	if code := payload.ResourceSpans[0].ScopeSpans[0].Spans[0].Status.Code; code != int(tracing.StatusError) {
+		t.Errorf("Expected error status for 503, got %d", code)
+	}
+}
+
+func TestLoggerIncludesTraceID(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(tracing.NewLogHandler(slog.NewTextHandler(&logs, nil))))
+	defer slog.SetDefault(previous)
+
+	handler := Tracing(tracing.NewTracer(nil))(Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req := testutils.NewTestRequestWithHeaders("GET", "/", map[string]string{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+	handler.ServeHTTP(testutils.NewTestResponseRecorder(), req)
+
+	if !strings.Contains(logs.String(), "trace_id=4bf92f3577b34da6a3ce929d0e0e4736") {
+		t.Errorf("Expected request log to include trace ID, got %s", logs.String())
+	}
+}
@@ -11,9 +11,13 @@ type BlogPost struct {
 	Slug        string    `json:"slug"`
 	Content     string    `json:"content"`
 	Excerpt     string    `json:"excerpt"`
+	ImageURL    string    `json:"image_url"`
 	Author      string    `json:"author"`
 	PublishedAt time.Time `json:"published_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Tags        []string  `json:"tags"`
 	Published   bool      `json:"published"`
+	// CSP lists extra Content-Security-Policy sources the post needs, such
+	// as "frame-src https://www.youtube-nocookie.com"
+	CSP string `json:"-"`
 }
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+)
+
+// SiteName is appended to page titles and sent as og:site_name
+const SiteName = "Clay's Portfolio"
+
+// SiteAuthor is the person behind the site, and the author of posts that
+// don't name one
+const SiteAuthor = "Clay"
+
+// Page types for og:type
+const (
+	PageTypeWebsite = "website"
+	PageTypeArticle = "article"
+)
+
+// PageMeta describes a page for the <head>: its title, the description
+// and preview used when the page is shared, and where it canonically
+// lives. Canonical and Image are absolute URLs. PublishedAt, ModifiedAt
+// and Tags are only sent for articles. StructuredData holds schema.org
+// values, each rendered as a JSON-LD script. AddSlash is set when the site
+// serves pages with a trailing slash.
+type PageMeta struct {
+	Title       string
+	Description string
+	Canonical   string
+	Image       string
+	Type        string
+	PublishedAt time.Time
+	ModifiedAt  time.Time
+	Tags        []string
+
+	StructuredData []any
+	AddSlash       bool
+}
+
+// Href returns the link to the site page at path, in the form the site
+// serves it so following it doesn't redirect
+func (m PageMeta) Href(path string) string {
+	if m.AddSlash && path != "/" {
+		return path + "/"
+	}
+	return path
+}
+
+// FullTitle is the <title>: the page title followed by the site name
+func (m PageMeta) FullTitle() string {
+	if m.Title == "" {
+		return SiteName
+	}
+	return m.Title + " - " + SiteName
+}
+
+// OGTitle is the title when the page is shared: the page title, or the
+// site name for pages without one such as the home page
+func (m PageMeta) OGTitle() string {
+	if m.Title == "" {
+		return SiteName
+	}
+	return m.Title
+}
+
+// OGType returns the og:type, "website" unless set
+func (m PageMeta) OGType() string {
+	if m.Type == "" {
+		return PageTypeWebsite
+	}
+	return m.Type
+}
+
+// TwitterCard returns the Twitter card type: a large image when the page
+// has one
+func (m PageMeta) TwitterCard() string {
+	if m.Image != "" {
+		return "summary_large_image"
+	}
+	return "summary"
+}
@@ -1,37 +1,226 @@
 package router
 
 import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	"github.com/claykom/website/internal/assets"
+	"github.com/claykom/website/internal/config"
 	"github.com/claykom/website/internal/handlers"
+	"github.com/claykom/website/internal/health"
+	"github.com/claykom/website/internal/metrics"
 	"github.com/claykom/website/internal/middleware"
+	"github.com/claykom/website/internal/tracing"
 	"github.com/gorilla/mux"
 )
 
-// New creates and configures a new router with all routes and middleware
-func New() *mux.Router {
+// Router is the site's HTTP handler. Its routes are built from the
+// configuration and can be rebuilt while the server runs; the readiness
+// checks, rate limit state and collected CSP reports are kept across
+// rebuilds.
+type Router struct {
+	tracer     *tracing.Tracer
+	health     *health.Registry
+	rateLimits *middleware.RateLimitStore
+	routes     atomic.Pointer[routes]
+
+	noRedirects bool
+}
+
+// pagePrefixes are the paths of the HTML pages besides the home page,
+// which the trailing slash policy applies to
+var pagePrefixes = []string{"/blog", "/portfolio"}
+
+// routes is one build of the router from a configuration
+type routes struct {
+	blog       *handlers.BlogHandler
+	portfolio  *handlers.PortfolioHandler
+	sitemap    *handlers.SitemapHandler
+	cspReports *handlers.CSPReportHandler
+	// handler wraps the mux router with redirects that must see every
+	// request; mux only runs middleware for matched routes
+	handler http.Handler
+
+	// Package defaults and readiness checks for these routes, applied by
+	// activate once they are serving
+	pageCache    *handlers.PageCache
+	siteURLs     handlers.SiteURLs
+	manifest     *assets.Manifest
+	static       fs.FS
+	checkTimeout time.Duration
+}
+
+// ServeHTTP applies host and path redirects, then routes the request
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.routes.Load().handler.ServeHTTP(w, req)
+}
+
+// ReloadContent re-reads blog posts and portfolio projects and drops
+// cached pages
+func (r *Router) ReloadContent() error {
+	rt := r.routes.Load()
+	return errors.Join(rt.blog.Reload(), rt.portfolio.Reload())
+}
+
+// Reload rebuilds the routes from cfg and site, e.g. after the
+// configuration changed, and switches to them. Requests in flight finish
+// on the old routes. If the posts or projects can't be loaded the old
+// routes are kept, along with their settings.
+func (r *Router) Reload(cfg *config.Config, site fs.FS) error {
+	content := siteDir(site, "content")
+	blogHandler := handlers.NewBlogHandler(content)
+	portfolioHandler := handlers.NewPortfolioHandler(content)
+	ctx := context.Background()
+	if err := errors.Join(blogHandler.CheckLoaded(ctx), portfolioHandler.CheckLoaded(ctx)); err != nil {
+		return err
+	}
+
+	rt := r.build(cfg, site, blogHandler, portfolioHandler, r.routes.Load())
+	r.routes.Store(rt)
+	r.activate(rt)
+	return nil
+}
+
+// Pages lists the HTML pages the routes serve: the home page and the blog
+// and portfolio pages for the content currently loaded
+func (r *Router) Pages() []handlers.Page {
+	rt := r.routes.Load()
+	return sitePages(rt.blog, rt.portfolio)
+}
+
+// Files lists the paths of the generated files crawlers read:
+// /robots.txt and the sitemap
+func (r *Router) Files() []string {
+	return append([]string{"/robots.txt"}, r.routes.Load().sitemap.Paths()...)
+}
+
+// sitePages lists the home page and the blog and portfolio pages
+func sitePages(blog *handlers.BlogHandler, portfolio *handlers.PortfolioHandler) []handlers.Page {
+	pages := []handlers.Page{handlers.HomePage}
+	pages = append(pages, blog.Pages()...)
+	return append(pages, portfolio.Pages()...)
+}
+
+// Health returns the registry behind /readyz, for checks that depend on
+// more than the router, and for draining on shutdown
+func (r *Router) Health() *health.Registry {
+	return r.health
+}
+
+// Option customises a Router
+type Option func(*Router)
+
+// WithoutRedirects turns off the canonical host and trailing slash
+// redirects, for rendering pages in-process where a redirect would leave
+// a page unrendered. Pages still link to each other and are listed in the
+// sitemap in the form TRAILING_SLASH gives them.
+func WithoutRedirects() Option {
+	return func(r *Router) {
+		r.noRedirects = true
+	}
+}
+
+// New creates and configures a new router with all routes and middleware.
+// site holds the static/ and content/ directories, either on disk or
+// embedded in the binary.
+func New(cfg *config.Config, tracer *tracing.Tracer, site fs.FS, opts ...Option) *Router {
+	router := &Router{
+		tracer:     tracer,
+		health:     health.NewRegistry(),
+		rateLimits: middleware.NewRateLimitStore(5 * time.Minute),
+	}
+	for _, opt := range opts {
+		opt(router)
+	}
+	content := siteDir(site, "content")
+	rt := router.build(cfg, site, handlers.NewBlogHandler(content), handlers.NewPortfolioHandler(content), nil)
+	router.routes.Store(rt)
+	router.activate(rt)
+	return router
+}
+
+// siteDir returns a top-level directory of site
+func siteDir(site fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(site, dir)
+	if err != nil {
+		log.Fatalf("Invalid %s files: %v", dir, err)
+	}
+	return sub
+}
+
+// activate points the package defaults and readiness checks at rt. It
+// runs after rt is stored, so a build that is never switched to leaves
+// them untouched.
+func (router *Router) activate(rt *routes) {
+	handlers.SetPageCache(rt.pageCache)
+	handlers.SetSiteURLs(rt.siteURLs)
+	assets.SetDefault(rt.manifest)
+
+	router.health.Register("content", rt.checkTimeout, func(ctx context.Context) error {
+		return errors.Join(rt.blog.CheckLoaded(ctx), rt.portfolio.CheckLoaded(ctx))
+	})
+	router.health.Register("static", rt.checkTimeout, health.DirReadable(rt.static, "."))
+}
+
+// build creates the routes and middleware for cfg around the content
+// handlers. It has no effect outside the returned routes, which carry over
+// the CSP reports collected by prev when it isn't nil.
+func (router *Router) build(cfg *config.Config, site fs.FS, blogHandler *handlers.BlogHandler, portfolioHandler *handlers.PortfolioHandler, prev *routes) *routes {
 	r := mux.NewRouter()
+	staticFiles := siteDir(site, "static")
 
-	// Initialize handlers
-	blogHandler := handlers.NewBlogHandler()
-	portfolioHandler := handlers.NewPortfolioHandler()
+	// Cache rendered pages; reloading content purges it
+	var pageCache *handlers.PageCache
+	if cfg.App.PageCacheSize > 0 {
+		pageCache = handlers.NewPageCache(cfg.App.PageCacheSize)
+	}
 
 	// Initialize middleware dependencies
-	rateLimitStore := middleware.NewRateLimitStore(5 * time.Minute)
 	validator := middleware.NewValidator()
 
-	// Apply global middleware in order of importance
+	// Violation reports are kept across rebuilds, like the rate limits
+	cspReports := handlers.NewCSPReportHandler(validator)
+	if prev != nil {
+		cspReports = prev.cspReports
+	}
+
+	// Apply global middleware in order of importance. Metrics wraps Recovery
+	// so that recovered panics are still counted as 5xx responses, and
+	// Tracing runs before Logger so log lines carry the trace ID.
+	r.Use(middleware.Metrics)
+	r.Use(middleware.Tracing(router.tracer))
 	r.Use(middleware.Recovery)
 	r.Use(middleware.Logger)
-	r.Use(middleware.SecureHeaders)
+	if cfg.Compression.Enabled {
+		r.Use(middleware.Compress(cfg.Compression.MinSize))
+	}
+	r.Use(middleware.SecureHeadersWith(headerOptions(cfg)))
 	r.Use(middleware.InputValidation(validator))
-	// Rate limit: 100 requests per minute per IP
-	r.Use(middleware.RateLimit(rateLimitStore, 100, time.Minute))
+	if cfg.Security.RateLimitRequests > 0 {
+		r.Use(middleware.RateLimit(router.rateLimits, cfg.Security.RateLimitRequests, cfg.Security.RateLimitWindow))
+	}
 
 	// Page routes
 	r.HandleFunc("/", handlers.Home).Methods(http.MethodGet)
+	// Liveness and readiness probes; /health is the original liveness path
 	r.HandleFunc("/health", handlers.Health).Methods(http.MethodGet)
+	r.HandleFunc("/healthz", handlers.Health).Methods(http.MethodGet)
+	r.Handle("/readyz", handlers.Readiness(router.health)).Methods(http.MethodGet)
+	r.Handle("/version", handlers.Version(false)).Methods(http.MethodGet)
+
+	// Content-Security-Policy violation reports
+	r.Handle("/csp-report", cspReports).Methods(http.MethodPost)
+
+	// Metrics on the public listener only when protected by a token; the
+	// admin listener from NewAdmin serves them without auth
+	if cfg.Metrics.Token.IsSet() {
+		r.Handle("/metrics", middleware.BearerAuth(cfg.Metrics.Token.Value())(metrics.DefaultRegistry.Handler())).Methods(http.MethodGet)
+	}
 
 	// Blog routes
 	r.HandleFunc("/blog", blogHandler.ListPosts).Methods(http.MethodGet)
@@ -41,20 +230,130 @@ func New() *mux.Router {
 	r.HandleFunc("/portfolio", portfolioHandler.ListProjects).Methods(http.MethodGet)
 	r.HandleFunc("/portfolio/{slug}", portfolioHandler.GetProject).Methods(http.MethodGet)
 
+	// Sitemap of the pages above, and robots.txt pointing at it. Listed
+	// URLs follow the trailing slash policy so none of them redirect.
+	sitemapHandler := handlers.NewSitemapHandler(func() []handlers.Page {
+		pages := sitePages(blogHandler, portfolioHandler)
+		if cfg.Server.TrailingSlash == "add" {
+			for i := range pages {
+				if pages[i].Path != "/" {
+					pages[i].Path += "/"
+				}
+			}
+		}
+		return pages
+	}, cfg.Site.BaseURL)
+	r.HandleFunc("/sitemap.xml", sitemapHandler.Sitemap).Methods(http.MethodGet)
+	r.HandleFunc("/sitemap-{n:[0-9]+}.xml", sitemapHandler.SitemapPart).Methods(http.MethodGet)
+	r.HandleFunc("/robots.txt", handlers.Robots(cfg.Site.RobotsDisallow, cfg.Site.BaseURL)).Methods(http.MethodGet)
+
+	// Fingerprint static files so templates can link to immutable URLs
+	manifest, err := assets.NewManifest(staticFiles)
+	if err != nil {
+		log.Printf("Error building static asset manifest: %v", err)
+	}
+
 	// Secure static files handler
-	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", middleware.SecureStaticHandler(http.Dir("static/"))))
+	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", middleware.SecureStaticHandler(staticFiles, middleware.WithManifest(manifest))))
 
 	// Custom error handlers
+	// (mux skips r.Use middleware for these, so count them explicitly)
+	r.NotFoundHandler = middleware.Metrics(http.HandlerFunc(handlers.NotFound))
+	r.MethodNotAllowedHandler = middleware.Metrics(http.HandlerFunc(handlers.MethodNotAllowed))
+
+	// JSON API, callable from other origins. Routes accept OPTIONS so CORS
+	// preflights reach the middleware rather than MethodNotAllowedHandler.
+	// They are registered on r rather than a subrouter because a mux
+	// subrouter answers 404 instead of 405 for unsupported methods.
+	if cfg.API.Enabled {
+		cors := middleware.CORS(middleware.CORSOptions{
+			AllowedOrigins:   cfg.API.CORSAllowedOrigins,
+			AllowedMethods:   cfg.API.CORSAllowedMethods,
+			AllowedHeaders:   cfg.API.CORSAllowedHeaders,
+			AllowCredentials: cfg.API.CORSAllowCredentials,
+			MaxAge:           cfg.API.CORSMaxAge,
+		})
+		apiMethods := []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+		api := func(path string, h http.HandlerFunc) {
+			r.Handle("/api"+path, cors(h)).Methods(apiMethods...)
+		}
+		api("/blog", blogHandler.ListPostsAPI)
+		api("/blog/{slug}", blogHandler.GetPostAPI)
+		api("/portfolio", portfolioHandler.ListProjectsAPI)
+		api("/portfolio/featured", portfolioHandler.ListFeaturedProjectsAPI)
+		api("/portfolio/{slug}", portfolioHandler.GetProjectAPI)
+	}
+
+	// Host and path redirects run before routing, host first
+	var handler http.Handler = r
+	if !router.noRedirects {
+		handler = middleware.TrailingSlash(cfg.Server.TrailingSlash, pagePrefixes)(handler)
+		handler = middleware.CanonicalHost(cfg.Server.CanonicalHost)(handler)
+	}
+
+	return &routes{
+		blog:       blogHandler,
+		portfolio:  portfolioHandler,
+		sitemap:    sitemapHandler,
+		cspReports: cspReports,
+		handler:    handler,
+		pageCache:  pageCache,
+		// Pages link to themselves under the public URL, in the form the
+		// trailing slash policy serves them
+		siteURLs: handlers.SiteURLs{
+			BaseURL:  cfg.Site.BaseURL,
+			AddSlash: cfg.Server.TrailingSlash == "add",
+		},
+		manifest:     manifest,
+		static:       staticFiles,
+		checkTimeout: cfg.Health.CheckTimeout,
+	}
+}
+
+// headerOptions builds the security header settings from config and logs
+// any contradictory combinations
+func headerOptions(cfg *config.Config) middleware.HeaderOptions {
+	profile, err := middleware.Profile(cfg.Security.HeaderProfile)
+	if err != nil {
+		log.Printf("Security header warning: %v; using default profile", err)
+		profile = middleware.DefaultProfile()
+	}
+
+	opts := middleware.HeaderOptions{
+		Profile:       &profile,
+		CSPReportURI:  cfg.Security.CSPReportURI,
+		CSPReportOnly: cfg.Security.CSPReportOnly,
+	}
+	for prefix, headers := range cfg.Security.RouteHeaders {
+		opts.Routes = append(opts.Routes, middleware.RouteHeaders{PathPrefix: prefix, Headers: headers})
+	}
+
+	if profile.Name == "development" && cfg.App.Environment == "production" {
+		log.Printf("Security header warning: development profile in production sends no HSTS")
+	}
+	for _, warning := range opts.Check() {
+		log.Printf("Security header warning: %s", warning)
+	}
+
+	return opts
+}
+
+// NewAdmin creates the router for the internal admin listener, which is
+// expected to be bound to a private address. It serves the same probes as
+// the main listener, without rate limiting, and /version with the module
+// list.
+func NewAdmin(checks *health.Registry) *mux.Router {
+	r := mux.NewRouter()
+
+	r.Use(middleware.Recovery)
+
+	r.Handle("/metrics", metrics.DefaultRegistry.Handler()).Methods(http.MethodGet)
+	r.HandleFunc("/health", handlers.Health).Methods(http.MethodGet)
+	r.HandleFunc("/healthz", handlers.Health).Methods(http.MethodGet)
+	r.Handle("/readyz", handlers.Readiness(checks)).Methods(http.MethodGet)
+	r.Handle("/version", handlers.Version(true)).Methods(http.MethodGet)
+
 	r.NotFoundHandler = http.HandlerFunc(handlers.NotFound)
-	r.MethodNotAllowedHandler = http.HandlerFunc(handlers.MethodNotAllowed)
-
-	// API routes (commented out - keeping for reference)
-	// api := r.PathPrefix("/api").Subrouter()
-	// api.HandleFunc("/blog", blogHandler.ListPostsAPI).Methods(http.MethodGet)
-	// api.HandleFunc("/blog/{slug}", blogHandler.GetPostAPI).Methods(http.MethodGet)
-	// api.HandleFunc("/portfolio", portfolioHandler.ListProjectsAPI).Methods(http.MethodGet)
-	// api.HandleFunc("/portfolio/featured", portfolioHandler.ListFeaturedProjectsAPI).Methods(http.MethodGet)
-	// api.HandleFunc("/portfolio/{slug}", portfolioHandler.GetProjectAPI).Methods(http.MethodGet)
 
 	return r
 }
@@ -0,0 +1,382 @@
+package router
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/claykom/website/internal/buildinfo"
+	"github.com/claykom/website/internal/config"
+	"github.com/claykom/website/internal/testutils"
+	"github.com/claykom/website/internal/tracing"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		API: config.APIConfig{
+			Enabled:            true,
+			CORSAllowedOrigins: []string{"https://app.example.com"},
+			CORSAllowedMethods: []string{"GET", "HEAD"},
+			CORSAllowedHeaders: []string{"Content-Type"},
+		},
+	}
+}
+
+func testSite() fstest.MapFS {
+	return fstest.MapFS{
+		"static/css/style.css":  {Data: []byte("body {}")},
+		"content/blog/hello.md": {Data: []byte("---\ntitle: Hello\nslug: hello\ndate: 2024-01-01\n---\n\nHi\n")},
+	}
+}
+
+func TestAPIPreflightIsNotMethodNotAllowed(t *testing.T) {
+	r := New(testConfig(), tracing.NewTracer(nil), testSite())
+
+	req := testutils.NewTestRequestWithHeaders("OPTIONS", "/api/blog/hello", map[string]string{
+		"Origin":                         "https://app.example.com",
+		"Access-Control-Request-Method":  "GET",
+		"Access-Control-Request-Headers": "content-type",
+	})
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, req)
+
+	rr.AssertStatusCode(t, http.StatusNoContent)
+	rr.AssertHeader(t, "Access-Control-Allow-Origin", "https://app.example.com")
+	rr.AssertHeader(t, "Access-Control-Allow-Methods", "GET, HEAD")
+	rr.AssertHeader(t, "Access-Control-Allow-Headers", "Content-Type")
+}
+
+func TestAPICrossOriginGet(t *testing.T) {
+	r := New(testConfig(), tracing.NewTracer(nil), testSite())
+
+	req := testutils.NewTestRequestWithHeaders("GET", "/api/blog", map[string]string{"Origin": "https://app.example.com"})
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, req)
+
+	rr.AssertStatusCode(t, http.StatusOK)
+	rr.AssertContentType(t, "application/json")
+	rr.AssertHeader(t, "Access-Control-Allow-Origin", "https://app.example.com")
+	rr.AssertBodyContains(t, `"slug":"hello"`)
+
+	found := false
+	for _, v := range rr.Header().Values("Vary") {
+		if v == "Origin" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected Vary: Origin, got %v", rr.Header().Values("Vary"))
+	}
+
+	// Pages outside /api get no CORS headers
+	req = testutils.NewTestRequestWithHeaders("GET", "/blog", map[string]string{"Origin": "https://app.example.com"})
+	rr = testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, req)
+	rr.AssertHeader(t, "Access-Control-Allow-Origin", "")
+}
+
+func TestAPIDisabled(t *testing.T) {
+	cfg := testConfig()
+	cfg.API.Enabled = false
+	r := New(cfg, tracing.NewTracer(nil), testSite())
+
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/api/blog", ""))
+	rr.AssertStatusCode(t, http.StatusNotFound)
+}
+
+func TestAPIOtherMethodsStillRejected(t *testing.T) {
+	r := New(testConfig(), tracing.NewTracer(nil), testSite())
+
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("DELETE", "/api/blog", ""))
+	rr.AssertStatusCode(t, http.StatusMethodNotAllowed)
+}
+
+func TestTrailingSlashReachesUnmatchedPaths(t *testing.T) {
+	cfg := testConfig()
+	cfg.Server.TrailingSlash = "strip"
+	r := New(cfg, tracing.NewTracer(nil), testSite())
+
+	// "/blog/" matches no route, so this only works outside mux
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/blog/", ""))
+	rr.AssertStatusCode(t, http.StatusMovedPermanently)
+	rr.AssertHeader(t, "Location", "/blog")
+}
+
+func TestTrailingSlashAddServesRoutes(t *testing.T) {
+	cfg := testConfig()
+	cfg.Server.TrailingSlash = "add"
+	r := New(cfg, tracing.NewTracer(nil), testSite())
+
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/blog/hello/", ""))
+	rr.AssertStatusCode(t, http.StatusOK)
+}
+
+func TestTrailingSlashAddOnlyRedirectsPages(t *testing.T) {
+	cfg := testConfig()
+	cfg.Server.TrailingSlash = "add"
+	r := New(cfg, tracing.NewTracer(nil), testSite())
+
+	for _, path := range []string{"/healthz", "/readyz", "/version", "/api/blog", "/api/portfolio/featured"} {
+		rr := testutils.NewTestResponseRecorder()
+		r.ServeHTTP(rr, testutils.NewTestRequest("GET", path, ""))
+		if rr.Code == http.StatusMovedPermanently {
+			t.Errorf("Expected %s not to redirect, got Location %s", path, rr.Header().Get("Location"))
+		}
+	}
+
+	// Links between pages use the slash form, so following them doesn't
+	// redirect
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/blog/", ""))
+	rr.AssertStatusCode(t, http.StatusOK)
+	rr.AssertBodyContains(t, `<a href="/blog/">Blog</a>`)
+	rr.AssertBodyContains(t, `<a href="/portfolio/">Portfolio</a>`)
+	rr.AssertBodyContains(t, `href="/blog/hello/"`)
+}
+
+func TestWithoutRedirects(t *testing.T) {
+	cfg := testConfig()
+	cfg.Site.BaseURL = "https://example.com"
+	cfg.Server.TrailingSlash = "add"
+	cfg.Server.CanonicalHost = "example.com"
+	r := New(cfg, tracing.NewTracer(nil), testSite(), WithoutRedirects())
+
+	// Pages answer at their route, but link to and name themselves in the
+	// slash form
+	req := testutils.NewTestRequest("GET", "/blog/hello", "")
+	req.Host = "www.example.com"
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, req)
+	rr.AssertStatusCode(t, http.StatusOK)
+	rr.AssertBodyContains(t, `<link rel="canonical" href="https://example.com/blog/hello/">`)
+	rr.AssertBodyContains(t, `<a href="/blog/">Blog</a>`)
+
+	rr = testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/sitemap.xml", ""))
+	rr.AssertBodyContains(t, "<loc>https://example.com/blog/hello/</loc>")
+}
+
+func TestCanonicalHostRedirect(t *testing.T) {
+	cfg := testConfig()
+	cfg.Server.CanonicalHost = "example.com"
+	r := New(cfg, tracing.NewTracer(nil), testSite())
+
+	req := testutils.NewTestRequest("GET", "/blog", "")
+	req.Host = "www.example.com"
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, req)
+	rr.AssertStatusCode(t, http.StatusMovedPermanently)
+	rr.AssertHeader(t, "Location", "http://example.com/blog")
+}
+
+func TestProbes(t *testing.T) {
+	r := New(testConfig(), tracing.NewTracer(nil), testSite())
+	admin := NewAdmin(r.Health())
+
+	for _, h := range []http.Handler{r, admin} {
+		for _, path := range []string{"/health", "/healthz", "/readyz"} {
+			rr := testutils.NewTestResponseRecorder()
+			h.ServeHTTP(rr, testutils.NewTestRequest("GET", path, ""))
+			rr.AssertStatusCode(t, http.StatusOK)
+		}
+	}
+
+	// Shutdown fails readiness but not liveness
+	r.Health().Drain()
+	for _, h := range []http.Handler{r, admin} {
+		rr := testutils.NewTestResponseRecorder()
+		h.ServeHTTP(rr, testutils.NewTestRequest("GET", "/readyz", ""))
+		rr.AssertStatusCode(t, http.StatusServiceUnavailable)
+
+		rr = testutils.NewTestResponseRecorder()
+		h.ServeHTTP(rr, testutils.NewTestRequest("GET", "/healthz", ""))
+		rr.AssertStatusCode(t, http.StatusOK)
+	}
+}
+
+func TestReadinessFailsWithoutContent(t *testing.T) {
+	site := fstest.MapFS{"static/css/style.css": {Data: []byte("body {}")}}
+	r := New(testConfig(), tracing.NewTracer(nil), site)
+
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/readyz", ""))
+	rr.AssertStatusCode(t, http.StatusServiceUnavailable)
+	rr.AssertBodyContains(t, "blog posts not loaded")
+}
+
+func TestVersionDepsOnlyOnAdmin(t *testing.T) {
+	r := New(testConfig(), tracing.NewTracer(nil), testSite())
+
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/version", ""))
+	rr.AssertStatusCode(t, http.StatusOK)
+	if strings.Contains(rr.Body.String(), `"deps"`) {
+		t.Error("Expected no module list on the public listener")
+	}
+
+	rr = testutils.NewTestResponseRecorder()
+	NewAdmin(r.Health()).ServeHTTP(rr, testutils.NewTestRequest("GET", "/version", ""))
+	rr.AssertStatusCode(t, http.StatusOK)
+	if len(buildinfo.Get().Deps) > 0 {
+		rr.AssertBodyContains(t, `"deps"`)
+	}
+}
+
+func TestReload(t *testing.T) {
+	r := New(testConfig(), tracing.NewTracer(nil), testSite())
+
+	cfg := testConfig()
+	cfg.Server.TrailingSlash = "strip"
+	site := testSite()
+	site["content/blog/second.md"] = &fstest.MapFile{Data: []byte("---\ntitle: Second\nslug: second\ndate: 2024-02-01\n---\n\nHi\n")}
+	if err := r.Reload(cfg, site); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/blog/", ""))
+	rr.AssertStatusCode(t, http.StatusMovedPermanently)
+
+	rr = testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/blog/second", ""))
+	rr.AssertStatusCode(t, http.StatusOK)
+}
+
+func TestReloadKeepsRoutesWithoutContent(t *testing.T) {
+	r := New(testConfig(), tracing.NewTracer(nil), testSite())
+
+	site := fstest.MapFS{"static/css/style.css": {Data: []byte("body {}")}}
+	if err := r.Reload(testConfig(), site); err == nil {
+		t.Error("Expected an error when the new content can't be loaded")
+	}
+
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/blog/hello", ""))
+	rr.AssertStatusCode(t, http.StatusOK)
+
+	rr = testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/readyz", ""))
+	rr.AssertStatusCode(t, http.StatusOK)
+}
+
+func TestReloadRequiresPortfolio(t *testing.T) {
+	cfg := testConfig()
+	cfg.Site.BaseURL = "https://old.example.com"
+	r := New(cfg, tracing.NewTracer(nil), testSite())
+
+	// A portfolio that exists but can't be read fails the reload, and
+	// leaves the running settings alone
+	next := testConfig()
+	next.Site.BaseURL = "https://new.example.com"
+	site := testSite()
+	site["content/portfolio"] = &fstest.MapFile{Data: []byte("not a directory")}
+	if err := r.Reload(next, site); err == nil {
+		t.Error("Expected an error when the projects can't be loaded")
+	}
+
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/blog/hello", ""))
+	rr.AssertStatusCode(t, http.StatusOK)
+	rr.AssertBodyContains(t, `<link rel="canonical" href="https://old.example.com/blog/hello">`)
+}
+
+func TestReloadKeepsCSPReports(t *testing.T) {
+	r := New(testConfig(), tracing.NewTracer(nil), testSite())
+
+	req := testutils.NewTestRequest("POST", "/csp-report", `{"csp-report": {"document-uri": "https://example.com/", "violated-directive": "img-src 'self'"}}`)
+	req.Header.Set("Content-Type", "application/csp-report")
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, req)
+	rr.AssertStatusCode(t, http.StatusNoContent)
+
+	if err := r.Reload(testConfig(), testSite()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count := len(r.routes.Load().cspReports.Violations()); count != 1 {
+		t.Errorf("Expected 1 violation after reload, got %d", count)
+	}
+}
+
+func TestRateLimitFromConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.Security.RateLimitRequests = 2
+	cfg.Security.RateLimitWindow = time.Minute
+	r := New(cfg, tracing.NewTracer(nil), testSite())
+
+	for i, expected := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		req := testutils.NewTestRequestWithHeaders("GET", "/healthz", map[string]string{"X-Real-IP": "192.0.2.1"})
+		rr := testutils.NewTestResponseRecorder()
+		r.ServeHTTP(rr, req)
+		if rr.Code != expected {
+			t.Errorf("Request %d: expected status %d, got %d", i+1, expected, rr.Code)
+		}
+	}
+}
+
+func TestPages(t *testing.T) {
+	site := testSite()
+	site["content/portfolio/tool.md"] = &fstest.MapFile{Data: []byte("---\ntitle: Tool\nslug: tool\ndate: 2024-03-01\n---\n\nA tool\n")}
+	r := New(testConfig(), tracing.NewTracer(nil), site)
+
+	var paths []string
+	for _, page := range r.Pages() {
+		paths = append(paths, page.Path)
+
+		rr := testutils.NewTestResponseRecorder()
+		r.ServeHTTP(rr, testutils.NewTestRequest("GET", page.Path, ""))
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected %s to be served, got %d", page.Path, rr.Code)
+		}
+	}
+
+	expected := []string{"/", "/blog", "/blog/hello", "/portfolio", "/portfolio/tool"}
+	if strings.Join(paths, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected pages %v, got %v", expected, paths)
+	}
+}
+
+func TestSiteLinks(t *testing.T) {
+	r := New(testConfig(), tracing.NewTracer(nil), os.DirFS("../.."))
+
+	var paths []string
+	for _, page := range r.Pages() {
+		paths = append(paths, page.Path)
+	}
+	testutils.AssertNoBrokenLinks(t, r, paths)
+}
+
+func TestSitemapAndRobots(t *testing.T) {
+	cfg := testConfig()
+	cfg.Site.BaseURL = "https://example.com"
+	cfg.Site.RobotsDisallow = []string{"/api/"}
+	cfg.Server.TrailingSlash = "add"
+	r := New(cfg, tracing.NewTracer(nil), testSite())
+
+	if files := r.Files(); strings.Join(files, " ") != "/robots.txt /sitemap.xml" {
+		t.Errorf("Expected robots.txt and the sitemap, got %v", files)
+	}
+
+	rr := testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/robots.txt", ""))
+	rr.AssertStatusCode(t, http.StatusOK)
+	rr.AssertBodyContains(t, "Disallow: /api/\n")
+	rr.AssertBodyContains(t, "Sitemap: https://example.com/sitemap.xml")
+
+	// Listed URLs follow the trailing slash policy rather than redirecting
+	rr = testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/sitemap.xml", ""))
+	rr.AssertStatusCode(t, http.StatusOK)
+	rr.AssertBodyContains(t, "<loc>https://example.com/blog/hello/</loc>")
+	rr.AssertBodyContains(t, "<loc>https://example.com/</loc>")
+
+	rr = testutils.NewTestResponseRecorder()
+	r.ServeHTTP(rr, testutils.NewTestRequest("GET", "/sitemap-1.xml", ""))
+	rr.AssertStatusCode(t, http.StatusNotFound)
+}
@@ -5,6 +5,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/claykom/website/internal/linkcheck"
 )
 
 // TestResponseRecorder wraps httptest.ResponseRecorder with additional helper methods
@@ -83,6 +85,15 @@ func NewTestRequestWithHeaders(method, path string, headers map[string]string) *
 	return req
 }
 
+// AssertNoBrokenLinks renders pages through handler and reports each
+// broken internal link, missing anchor and image without alt text
+func AssertNoBrokenLinks(t *testing.T, handler http.Handler, pages []string) {
+	t.Helper()
+	for _, problem := range linkcheck.Check(handler, linkcheck.Options{Pages: pages}) {
+		t.Errorf("Expected no broken links, got %s", problem)
+	}
+}
+
 // SetupTestEnvironment sets up common test environment variables
 func SetupTestEnvironment() {
 	// Set test environment variables
@@ -0,0 +1,295 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// otlpSpan mirrors the OTLP/JSON span encoding. IDs are hex strings and
+// 64-bit integers are decimal strings, as the protocol requires.
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	TraceState        string          `json:"traceState,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+// scopeName identifies this instrumentation in exported data
+const scopeName = "github.com/claykom/website/internal/tracing"
+
+func toOTLPSpan(d SpanData) otlpSpan {
+	span := otlpSpan{
+		TraceID:           d.SpanContext.TraceID.String(),
+		SpanID:            d.SpanContext.SpanID.String(),
+		TraceState:        d.SpanContext.TraceState,
+		Name:              d.Name,
+		Kind:              d.Kind,
+		StartTimeUnixNano: strconv.FormatInt(d.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(d.End.UnixNano(), 10),
+		Attributes:        toOTLPAttributes(d.Attributes),
+		Status:            otlpStatus{Code: d.Status, Message: d.StatusMessage},
+	}
+	if d.Parent.IsValid() {
+		span.ParentSpanID = d.Parent.String()
+	}
+	return span
+}
+
+func toOTLPAttributes(attrs []Attribute) []otlpAttribute {
+	out := make([]otlpAttribute, 0, len(attrs))
+	for _, attr := range attrs {
+		var v otlpValue
+		switch value := attr.Value.(type) {
+		case string:
+			v.StringValue = &value
+		case bool:
+			v.BoolValue = &value
+		case int:
+			s := strconv.Itoa(value)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(value, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &value
+		default:
+			s := fmt.Sprint(value)
+			v.StringValue = &s
+		}
+		out = append(out, otlpAttribute{Key: attr.Key, Value: v})
+	}
+	return out
+}
+
+// encodeOTLP builds an OTLP/JSON ExportTraceServiceRequest
+func encodeOTLP(serviceName string, spans []SpanData) ([]byte, error) {
+	otlp := make([]otlpSpan, len(spans))
+	for i, span := range spans {
+		otlp[i] = toOTLPSpan(span)
+	}
+
+	req := otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: toOTLPAttributes([]Attribute{String("service.name", serviceName)}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: scopeName},
+				Spans: otlp,
+			}},
+		}},
+	}
+	return json.Marshal(req)
+}
+
+// WriterExporter writes each span as one line of OTLP/JSON to w. It is
+// meant for stdout, a local file, or a buffer in tests.
+type WriterExporter struct {
+	serviceName string
+	mutex       sync.Mutex
+	w           io.Writer
+}
+
+// NewWriterExporter creates an exporter writing to w
+func NewWriterExporter(serviceName string, w io.Writer) *WriterExporter {
+	return &WriterExporter{serviceName: serviceName, w: w}
+}
+
+// ExportSpans writes the spans immediately
+func (e *WriterExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	body, err := encodeOTLP(e.serviceName, spans)
+	if err != nil {
+		return err
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	_, err = e.w.Write(append(body, '\n'))
+	return err
+}
+
+// Shutdown is a no-op; the caller owns w and closes it if needed
+func (e *WriterExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// OTLPExporter batches spans and posts them to an OTLP/HTTP collector's
+// /v1/traces endpoint using the JSON encoding. Its client doesn't use
+// Transport, since tracing the exports would produce spans to export.
+type OTLPExporter struct {
+	serviceName string
+	url         string
+	client      *http.Client
+
+	queue     chan SpanData
+	flush     chan chan struct{}
+	batchSize int
+	interval  time.Duration
+	closeOnce sync.Once
+}
+
+// NewOTLPExporter creates an exporter for the collector at endpoint (for
+// example http://localhost:4318) and starts its background batcher
+func NewOTLPExporter(serviceName, endpoint string) *OTLPExporter {
+	e := &OTLPExporter{
+		serviceName: serviceName,
+		url:         strings.TrimRight(endpoint, "/") + "/v1/traces",
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan SpanData, 2048),
+		flush:       make(chan chan struct{}),
+		batchSize:   512,
+		interval:    5 * time.Second,
+	}
+
+	go e.run()
+
+	return e
+}
+
+// ExportSpans queues spans for the next batch, dropping them if the queue
+// is full so a slow collector never blocks requests
+func (e *OTLPExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	for _, span := range spans {
+		select {
+		case e.queue <- span:
+		default:
+			return fmt.Errorf("span queue full, dropped %q", span.Name)
+		}
+	}
+	return nil
+}
+
+// Shutdown sends any queued spans and stops the batcher
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	var err error
+	e.closeOnce.Do(func() {
+		flushed := make(chan struct{})
+		select {
+		case e.flush <- flushed:
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
+		select {
+		case <-flushed:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	})
+	return err
+}
+
+func (e *OTLPExporter) run() {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, e.batchSize)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.post(batch); err != nil {
+			log.Printf("Error exporting %d spans: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= e.batchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case flushed := <-e.flush:
+			// Drain whatever is queued, send it and stop
+			for {
+				select {
+				case span := <-e.queue:
+					batch = append(batch, span)
+					continue
+				default:
+				}
+				break
+			}
+			send()
+			close(flushed)
+			return
+		}
+	}
+}
+
+func (e *OTLPExporter) post(spans []SpanData) error {
+	body, err := encodeOTLP(e.serviceName, spans)
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
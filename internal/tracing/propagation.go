@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// W3C Trace Context header names
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// flagSampled is the sampled bit of the trace-flags field
+const flagSampled = 0x01
+
+// maxTracestateLen is the maximum tracestate length we propagate; longer
+// values are dropped as the spec allows
+const maxTracestateLen = 512
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the lowercase hex encoding of the trace ID
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid reports whether the trace ID is non-zero
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// String returns the lowercase hex encoding of the span ID
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsValid reports whether the span ID is non-zero
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// SpanContext is the part of a span that crosses process boundaries
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	Remote     bool
+}
+
+// IsValid reports whether both IDs are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// IsSampled reports whether the sampled flag is set
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&flagSampled != 0
+}
+
+// Traceparent formats the span context as a version 00 traceparent value
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+var errInvalidTraceparent = errors.New("invalid traceparent")
+
+// ParseTraceparent parses a traceparent header value. Unknown future
+// versions are accepted as long as the version 00 fields parse, as the
+// spec requires.
+func ParseTraceparent(value string) (SpanContext, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 {
+		return SpanContext{}, errInvalidTraceparent
+	}
+
+	version, err := hex.DecodeString(value[0:2])
+	if err != nil || version[0] == 0xff || value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	// Version 00 has exactly four fields; later versions may append more
+	if version[0] == 0 && len(value) != 55 {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	if len(value) > 55 && value[55] != '-' {
+		return SpanContext{}, errInvalidTraceparent
+	}
+
+	var sc SpanContext
+	if !decodeLowerHex(sc.TraceID[:], value[3:35]) || !decodeLowerHex(sc.SpanID[:], value[36:52]) {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	var flags [1]byte
+	if !decodeLowerHex(flags[:], value[53:55]) {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	sc.Flags = flags[0]
+
+	if !sc.IsValid() {
+		return SpanContext{}, errInvalidTraceparent
+	}
+
+	sc.Remote = true
+	return sc, nil
+}
+
+// decodeLowerHex decodes src into dst, rejecting uppercase hex digits
+func decodeLowerHex(dst []byte, src string) bool {
+	if strings.ToLower(src) != src {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(src))
+	return err == nil
+}
+
+// Extract reads the trace context headers from an incoming request. The
+// returned span context is invalid if no usable traceparent was present.
+func Extract(h http.Header) SpanContext {
+	sc, err := ParseTraceparent(h.Get(TraceparentHeader))
+	if err != nil {
+		return SpanContext{}
+	}
+
+	if state := strings.Join(h.Values(TracestateHeader), ","); len(state) <= maxTracestateLen {
+		sc.TraceState = state
+	}
+	return sc
+}
+
+// Inject writes the span context to outgoing request headers
+func Inject(sc SpanContext, h http.Header) {
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		h.Set(TracestateHeader, sc.TraceState)
+	} else {
+		h.Del(TracestateHeader)
+	}
+}
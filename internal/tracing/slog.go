@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogHandler is a slog.Handler that adds trace_id and span_id attributes
+// to records logged with a context carrying a span
+type LogHandler struct {
+	slog.Handler
+}
+
+// NewLogHandler wraps h so that log records carry trace IDs
+func NewLogHandler(h slog.Handler) *LogHandler {
+	return &LogHandler{Handler: h}
+}
+
+// Handle adds the trace attributes and forwards the record
+func (h *LogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", sc.TraceID.String()),
+			slog.String("span_id", sc.SpanID.String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs keeps the wrapper around the derived handler
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup keeps the wrapper around the derived handler
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithGroup(name)}
+}
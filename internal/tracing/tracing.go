@@ -0,0 +1,276 @@
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanKind describes the relationship between a span and its callers
+type SpanKind int
+
+// Span kinds, numbered as in the OTLP protocol
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// StatusCode is the span status, numbered as in the OTLP protocol
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// Attribute is a key/value pair attached to a span. Value must be a
+// string, bool, int, int64 or float64.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String creates a string attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int creates an integer attribute
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+// Bool creates a boolean attribute
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SpanData is the immutable record of a finished span handed to exporters
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	SpanContext   SpanContext
+	Parent        SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Exporter receives finished, sampled spans
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// Tracer creates spans and hands finished ones to its exporter. A tracer
+// without an exporter still generates IDs, so trace IDs show up in logs
+// even when nothing is collected.
+type Tracer struct {
+	exporter Exporter
+}
+
+// NewTracer creates a tracer; exporter may be nil
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// Shutdown flushes and stops the exporter
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil || t.exporter == nil {
+		return nil
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+// defaultTracer is used by Start when the context carries no span
+var defaultTracer atomic.Pointer[Tracer]
+
+// SetDefault sets the tracer used for spans started without a parent
+func SetDefault(t *Tracer) {
+	defaultTracer.Store(t)
+}
+
+// Default returns the default tracer, which may be nil
+func Default() *Tracer {
+	return defaultTracer.Load()
+}
+
+// Span is an in-progress unit of work
+type Span struct {
+	tracer *Tracer
+	mutex  sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// SpanContext returns the span's propagation context
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+// SetStatus sets the span status
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.data.Status = code
+	s.data.StatusMessage = message
+}
+
+// RecordError marks the span as failed with the error's message
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End finishes the span and exports it if sampled. Calling End more than
+// once has no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mutex.Unlock()
+
+	if s.tracer.exporter != nil && data.SpanContext.IsSampled() {
+		// Exporters are expected to batch, so this doesn't block on I/O
+		_ = s.tracer.exporter.ExportSpans(context.Background(), []SpanData{data})
+	}
+}
+
+// StartOption customises a span at creation
+type StartOption func(*SpanData)
+
+// WithKind sets the span kind
+func WithKind(kind SpanKind) StartOption {
+	return func(d *SpanData) {
+		d.Kind = kind
+	}
+}
+
+// WithAttributes sets initial attributes
+func WithAttributes(attrs ...Attribute) StartOption {
+	return func(d *SpanData) {
+		d.Attributes = append(d.Attributes, attrs...)
+	}
+}
+
+// Start starts a span as a child of the span or remote context in ctx,
+// or as a new root span if there is neither
+func (t *Tracer) Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+
+	data := SpanData{
+		Name:  name,
+		Kind:  SpanKindInternal,
+		Start: time.Now(),
+	}
+	if parent.IsValid() {
+		data.SpanContext = SpanContext{
+			TraceID:    parent.TraceID,
+			Flags:      parent.Flags,
+			TraceState: parent.TraceState,
+		}
+		data.Parent = parent.SpanID
+	} else {
+		data.SpanContext = SpanContext{
+			TraceID: newTraceID(),
+			Flags:   flagSampled,
+		}
+	}
+	data.SpanContext.SpanID = newSpanID()
+
+	for _, opt := range opts {
+		opt(&data)
+	}
+
+	span := &Span{tracer: t, data: data}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Start starts a span using the tracer of the span already in ctx, falling
+// back to the default tracer. With no tracer at all it returns a nil span,
+// whose methods are no-ops.
+func Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	tracer := Default()
+	if span := SpanFromContext(ctx); span != nil {
+		tracer = span.tracer
+	}
+	if tracer == nil {
+		return ctx, nil
+	}
+	return tracer.Start(ctx, name, opts...)
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// SpanFromContext returns the current span, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext returns a context whose next span will be
+// a child of the given remote span context
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context of the current span, or
+// the remote span context if no local span has been started yet
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+	return id
+}
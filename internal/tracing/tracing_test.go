@@ -0,0 +1,361 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+		sampled     bool
+	}{
+		{"valid sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, true},
+		{"valid not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false, false},
+		{"future version with extra field", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, true},
+		{"version ff is invalid", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, false},
+		{"version 00 with extra field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, false},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", true, false},
+		{"zero span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", true, false},
+		{"uppercase hex", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", true, false},
+		{"bad separators", "00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01", true, false},
+		{"too short", "00-4bf92f35", true, false},
+		{"empty", "", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("Unexpected trace ID %s", sc.TraceID)
+			}
+			if sc.SpanID.String() != "00f067aa0ba902b7" {
+				t.Errorf("Unexpected span ID %s", sc.SpanID)
+			}
+			if sc.IsSampled() != tt.sampled {
+				t.Errorf("Expected sampled=%v", tt.sampled)
+			}
+			if !sc.Remote {
+				t.Error("Expected parsed span context to be remote")
+			}
+		})
+	}
+}
+
+func TestExtractInjectRoundTrip(t *testing.T) {
+	in := http.Header{}
+	in.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	in.Add(TracestateHeader, "vendor1=a")
+	in.Add(TracestateHeader, "vendor2=b")
+
+	sc := Extract(in)
+	if !sc.IsValid() {
+		t.Fatal("Expected valid span context")
+	}
+	if sc.TraceState != "vendor1=a,vendor2=b" {
+		t.Errorf("Expected combined tracestate, got %q", sc.TraceState)
+	}
+
+	out := http.Header{}
+	Inject(sc, out)
+	if out.Get(TraceparentHeader) != in.Get(TraceparentHeader) {
+		t.Errorf("Expected traceparent %q, got %q", in.Get(TraceparentHeader), out.Get(TraceparentHeader))
+	}
+	if out.Get(TracestateHeader) != "vendor1=a,vendor2=b" {
+		t.Errorf("Unexpected tracestate %q", out.Get(TracestateHeader))
+	}
+}
+
+func TestExtractDropsOversizedTracestate(t *testing.T) {
+	h := http.Header{}
+	h.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.Set(TracestateHeader, strings.Repeat("a", maxTracestateLen+1))
+
+	if sc := Extract(h); sc.TraceState != "" {
+		t.Error("Expected oversized tracestate to be dropped")
+	}
+}
+
+// recordingExporter collects exported spans in memory
+type recordingExporter struct {
+	mutex sync.Mutex
+	spans []SpanData
+}
+
+func (e *recordingExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func TestTransportPropagatesTraceContext(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	exporter := &recordingExporter{}
+	ctx, root := NewTracer(exporter).Start(context.Background(), "root")
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := (&http.Client{Transport: NewTransport(nil)}).Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	root.End()
+
+	if req.Header.Get(TraceparentHeader) != "" {
+		t.Error("Expected the caller's request to be left unchanged")
+	}
+	if len(exporter.spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(exporter.spans))
+	}
+	client := exporter.spans[0]
+	if client.Kind != SpanKindClient {
+		t.Errorf("Expected a client span, got kind %d", client.Kind)
+	}
+	if client.Parent != root.SpanContext().SpanID {
+		t.Error("Expected the client span to be a child of the root span")
+	}
+
+	sc := Extract(received)
+	if sc.TraceID != root.SpanContext().TraceID {
+		t.Errorf("Expected trace ID %s, got %s", root.SpanContext().TraceID, sc.TraceID)
+	}
+	if sc.SpanID != client.SpanContext.SpanID {
+		t.Errorf("Expected the client span %s as parent, got %s", client.SpanContext.SpanID, sc.SpanID)
+	}
+}
+
+func TestTransportWithoutTracer(t *testing.T) {
+	SetDefault(nil)
+
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	remote.TraceState = "vendor=value"
+	ctx := ContextWithRemoteSpanContext(context.Background(), remote)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := (&http.Client{Transport: NewTransport(nil)}).Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := received.Get(TraceparentHeader); got != remote.Traceparent() {
+		t.Errorf("Expected traceparent %s, got %s", remote.Traceparent(), got)
+	}
+	if got := received.Get(TracestateHeader); got != "vendor=value" {
+		t.Errorf("Expected tracestate vendor=value, got %s", got)
+	}
+
+	// Without any trace context, no headers are sent
+	resp, err = (&http.Client{Transport: NewTransport(nil)}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if got := received.Get(TraceparentHeader); got != "" {
+		t.Errorf("Expected no traceparent, got %s", got)
+	}
+}
+
+func TestStartChildSpans(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter)
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := Start(ctx, "child")
+	child.End()
+	root.End()
+
+	if len(exporter.spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(exporter.spans))
+	}
+	childData, rootData := exporter.spans[0], exporter.spans[1]
+	if childData.SpanContext.TraceID != rootData.SpanContext.TraceID {
+		t.Error("Expected child to share the root's trace ID")
+	}
+	if childData.Parent != rootData.SpanContext.SpanID {
+		t.Error("Expected child's parent to be the root span")
+	}
+	if rootData.Parent.IsValid() {
+		t.Error("Expected root span to have no parent")
+	}
+}
+
+func TestStartContinuesRemoteTrace(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter)
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ContextWithRemoteSpanContext(context.Background(), remote)
+	_, span := tracer.Start(ctx, "server")
+	span.End()
+
+	data := exporter.spans[0]
+	if data.SpanContext.TraceID != remote.TraceID {
+		t.Error("Expected remote trace ID to be continued")
+	}
+	if data.Parent != remote.SpanID {
+		t.Error("Expected remote span to be the parent")
+	}
+}
+
+func TestUnsampledSpansAreNotExported(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter)
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span := tracer.Start(ContextWithRemoteSpanContext(context.Background(), remote), "server")
+	span.End()
+
+	if len(exporter.spans) != 0 {
+		t.Errorf("Expected no exported spans, got %d", len(exporter.spans))
+	}
+}
+
+func TestStartWithoutTracerIsNoop(t *testing.T) {
+	SetDefault(nil)
+
+	ctx, span := Start(context.Background(), "orphan")
+	span.SetAttributes(String("k", "v"))
+	span.End()
+
+	if SpanFromContext(ctx) != nil {
+		t.Error("Expected no span in context without a tracer")
+	}
+}
+
+func TestEndIsIdempotent(t *testing.T) {
+	exporter := &recordingExporter{}
+	_, span := NewTracer(exporter).Start(context.Background(), "once")
+	span.End()
+	span.End()
+
+	if len(exporter.spans) != 1 {
+		t.Errorf("Expected 1 exported span, got %d", len(exporter.spans))
+	}
+}
+
+func TestWriterExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(NewWriterExporter("test-service", &buf))
+
+	_, span := tracer.Start(context.Background(), "op", WithAttributes(String("a", "b"), Int("n", 3), Bool("ok", true)))
+	span.SetStatus(StatusError, "failed")
+	span.End()
+
+	var req otlpRequest
+	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
+		t.Fatalf("Invalid OTLP JSON: %v\n%s", err, buf.String())
+	}
+
+	rs := req.ResourceSpans[0]
+	if got := *rs.Resource.Attributes[0].Value.StringValue; got != "test-service" {
+		t.Errorf("Expected service.name test-service, got %s", got)
+	}
+	exported := rs.ScopeSpans[0].Spans[0]
+	if exported.Name != "op" || exported.Status.Code != StatusError {
+		t.Errorf("Unexpected span: %+v", exported)
+	}
+	if len(exported.TraceID) != 32 || len(exported.SpanID) != 16 {
+		t.Errorf("Expected hex IDs, got %s/%s", exported.TraceID, exported.SpanID)
+	}
+	if *exported.Attributes[1].Value.IntValue != "3" {
+		t.Error("Expected integer attribute encoded as a decimal string")
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var mutex sync.Mutex
+	var bodies []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		mutex.Lock()
+		bodies = append(bodies, string(body))
+		mutex.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter("test-service", collector.URL)
+	tracer := NewTracer(exporter)
+	for i := 0; i < 3; i++ {
+		_, span := tracer.Start(context.Background(), "batched")
+		span.End()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(bodies) != 1 {
+		t.Fatalf("Expected one batched export, got %d", len(bodies))
+	}
+	if strings.Count(bodies[0], `"name":"batched"`) != 3 {
+		t.Errorf("Expected 3 spans in the batch, got: %s", bodies[0])
+	}
+}
+
+func TestLogHandlerAddsTraceIDs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewTextHandler(&buf, nil)))
+
+	ctx, span := NewTracer(nil).Start(context.Background(), "op")
+	logger.InfoContext(ctx, "hello")
+
+	out := buf.String()
+	if !strings.Contains(out, "trace_id="+span.SpanContext().TraceID.String()) {
+		t.Errorf("Expected trace_id in log output, got %s", out)
+	}
+	if !strings.Contains(out, "span_id="+span.SpanContext().SpanID.String()) {
+		t.Errorf("Expected span_id in log output, got %s", out)
+	}
+
+	buf.Reset()
+	logger.With("component", "test").Info("no context")
+	if strings.Contains(buf.String(), "trace_id") {
+		t.Error("Expected no trace_id without a span in context")
+	}
+}
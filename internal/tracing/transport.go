@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"net/http"
+)
+
+// Transport is an http.RoundTripper that traces outgoing requests. Each
+// request gets a client span, a child of the span in its context, and
+// carries the trace context headers so the server called continues the
+// trace. Without a tracer the span is skipped, but a remote trace context
+// in the request's context is still propagated.
+type Transport struct {
+	// Base makes the requests, http.DefaultTransport if nil
+	Base http.RoundTripper
+}
+
+// NewTransport traces requests made through base
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ctx, span := Start(req.Context(), "HTTP "+req.Method,
+		WithKind(SpanKindClient),
+		WithAttributes(
+			String("http.request.method", req.Method),
+			String("server.address", req.URL.Host),
+			String("url.full", req.URL.Redacted()),
+		),
+	)
+	defer span.End()
+
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(ctx)
+	Inject(SpanContextFromContext(ctx), req.Header)
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttributes(Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 500 {
+		span.SetStatus(StatusError, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
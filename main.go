@@ -2,27 +2,131 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/claykom/website/internal/buildinfo"
+	"github.com/claykom/website/internal/certs"
 	"github.com/claykom/website/internal/config"
+	"github.com/claykom/website/internal/health"
+	"github.com/claykom/website/internal/metrics"
+	"github.com/claykom/website/internal/middleware"
 	"github.com/claykom/website/internal/router"
+	"github.com/claykom/website/internal/tracing"
 )
 
+const usage = `Usage: website [command] [flags]
+
+Commands:
+  serve                      run the web server (the default)
+  check                      validate the configuration and all content
+  new post [flags] TITLE     create a blog post in content/blog
+  new project [flags] TITLE  create a portfolio project in content/portfolio
+  links [-external HOSTS]     check every page for broken links and missing alt text
+  export [-out dist]          write the site as static files for object storage
+  config print               print the effective configuration as YAML
+  healthcheck                exit non-zero unless the local server is healthy
+  version                    print build information
+
+Run "website COMMAND -h" for a command's flags.
+`
+
 func main() {
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	var err error
+	switch command {
+	case "serve":
+		serve(args)
+	case "check":
+		err = check(args)
+	case "new":
+		err = newContent(args)
+	case "links":
+		err = checkLinks(args)
+	case "export":
+		err = exportSite(args)
+	case "config":
+		err = configCommand(args)
+	case "healthcheck":
+		err = healthcheck(args)
+	case "version":
+		fmt.Println("website", buildinfo.Get())
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "website: unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "website %s: %v\n", command, err)
+		os.Exit(1)
+	}
+}
+
+// configFlags defines -config and a flag for every setting on flags. After
+// flags.Parse, the returned function gives the configuration sources.
+func configFlags(flags *flag.FlagSet) func() config.Options {
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML config file (CONFIG_FILE)")
+	overrides := config.Flags(flags)
+	return func() config.Options {
+		return config.Options{File: *configFile, DotEnv: ".env", Flags: overrides()}
+	}
+}
+
+// serve runs the web server until it receives SIGINT or SIGTERM
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	showVersion := flags.Bool("version", false, "print build information and exit")
+	options := configFlags(flags)
+	flags.Parse(args)
+	if *showVersion {
+		fmt.Println("website", buildinfo.Get())
+		return
+	}
+
 	// Load configuration
-	cfg, err := config.Load()
+	sources := options()
+	cfg, err := config.LoadFrom(sources)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Structured logging with trace IDs; the standard logger is routed
+	// through the same handler. The level can change on reload.
+	var logLevel slog.LevelVar
+	logLevel.Set(parseLogLevel(cfg.App.LogLevel))
+	slog.SetDefault(slog.New(tracing.NewLogHandler(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: &logLevel,
+	}))))
+
+	log.Printf("website %s", buildinfo.Get())
+
+	// Tracing must be set up before the router loads content so that
+	// markdown rendering is traced too
+	tracer, closeTracing, err := newTracer(cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	tracing.SetDefault(tracer)
+
+	// Export Go runtime stats alongside the application metrics
+	metrics.DefaultRegistry.RegisterRuntimeMetrics()
+
 	// Create router
-	r := router.New()
+	r := router.New(cfg, tracer, siteFiles(cfg.App.PreferDisk))
 
 	// Configure server
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
@@ -34,11 +138,28 @@ func main() {
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
+	// Certificates from files, ACME, or ACME with the files as fallback
+	var certManager *certs.Manager
+	if cfg.TLS.Enabled {
+		certManager, err = certs.New(cfg.TLS)
+		if err != nil {
+			log.Fatalf("Failed to set up TLS: %v", err)
+		}
+		srv.TLSConfig = certManager.TLSConfig()
+		if len(cfg.TLS.ACME.Domains) > 0 {
+			log.Printf("Using ACME certificates for %s", strings.Join(cfg.TLS.ACME.Domains, ", "))
+		}
+		r.Health().Register("certificate", cfg.Health.CheckTimeout, certManager.ExpiryCheck(cfg.Health.CertMinValidity))
+	}
+	if cfg.Health.DiskMinFreeMB > 0 {
+		r.Health().Register("disk", cfg.Health.CheckTimeout, health.DiskSpace(cfg.Health.DiskPath, uint64(cfg.Health.DiskMinFreeMB)<<20))
+	}
+
 	// Start server in a goroutine
 	go func() {
 		if cfg.TLS.Enabled {
 			log.Printf("Starting HTTPS server on %s", addr)
-			if err := srv.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile); err != nil && err != http.ErrServerClosed {
+			if err := srv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 				log.Fatalf("HTTPS server failed to start: %v", err)
 			}
 		} else {
@@ -49,6 +170,63 @@ func main() {
 		}
 	}()
 
+	// Redirect plain HTTP to HTTPS when serving TLS directly. The same
+	// listener answers ACME HTTP-01 challenges.
+	var redirectSrv *http.Server
+	if cfg.TLS.Enabled && cfg.TLS.RedirectAddr != "" {
+		redirectSrv = &http.Server{
+			Addr:         cfg.TLS.RedirectAddr,
+			Handler:      certManager.HTTPHandler(middleware.HTTPSRedirect(cfg.Server.CanonicalHost, cfg.TLS.PublicPort)),
+			ReadTimeout:  cfg.Server.ReadTimeout,
+			WriteTimeout: cfg.Server.WriteTimeout,
+			IdleTimeout:  cfg.Server.IdleTimeout,
+		}
+
+		go func() {
+			log.Printf("Starting HTTP redirect server on %s", cfg.TLS.RedirectAddr)
+			if err := redirectSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("HTTP redirect server failed to start: %v", err)
+			}
+		}()
+	}
+
+	// Start the admin listener for /metrics if configured
+	var adminSrv *http.Server
+	if cfg.Metrics.Addr != "" {
+		adminSrv = &http.Server{
+			Addr:         cfg.Metrics.Addr,
+			Handler:      router.NewAdmin(r.Health()),
+			ReadTimeout:  cfg.Server.ReadTimeout,
+			WriteTimeout: cfg.Server.WriteTimeout,
+			IdleTimeout:  cfg.Server.IdleTimeout,
+		}
+
+		go func() {
+			log.Printf("Starting admin server on %s", cfg.Metrics.Addr)
+			if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Admin server failed to start: %v", err)
+			}
+		}()
+	}
+
+	// Reload configuration and content on SIGHUP, e.g. after editing posts
+	// with PREFER_DISK, and certificate files, e.g. after a renewal. Changed
+	// certificate files are also picked up on their own within a few
+	// seconds.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		running := cfg
+		for range hup {
+			if certManager != nil {
+				if err := certManager.Reload(); err != nil {
+					log.Printf("Error reloading TLS certificate: %v", err)
+				}
+			}
+			running = reload(r, running, sources, &logLevel)
+		}
+	}()
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -56,6 +234,14 @@ func main() {
 
 	log.Println("Shutting down server...")
 
+	// Fail readiness first so load balancers stop routing to us, and keep
+	// serving while they notice
+	r.Health().Drain()
+	if cfg.Health.DrainDelay > 0 {
+		log.Printf("Draining for %s", cfg.Health.DrainDelay)
+		time.Sleep(cfg.Health.DrainDelay)
+	}
+
 	// Create a deadline for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -64,6 +250,83 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	if redirectSrv != nil {
+		if err := redirectSrv.Shutdown(ctx); err != nil {
+			log.Printf("HTTP redirect server forced to shutdown: %v", err)
+		}
+	}
+	if adminSrv != nil {
+		if err := adminSrv.Shutdown(ctx); err != nil {
+			log.Printf("Admin server forced to shutdown: %v", err)
+		}
+	}
+
+	// Flush spans from the requests that just finished
+	if err := tracer.Shutdown(ctx); err != nil {
+		log.Printf("Error flushing traces: %v", err)
+	}
+	closeTracing()
 
 	log.Println("Server exited")
 }
+
+// reload re-reads the configuration and applies it to the router and the
+// log level, logging each changed setting. Settings that need a restart
+// are reported and left alone. If the configuration is invalid only the
+// content is reloaded. It returns the configuration now in effect.
+func reload(r *router.Router, running *config.Config, sources config.Options, logLevel *slog.LevelVar) *config.Config {
+	next, err := config.LoadFrom(sources)
+	if err != nil {
+		log.Printf("Error reloading configuration, keeping the current one: %v", err)
+		if err := r.ReloadContent(); err != nil {
+			log.Printf("Error reloading content: %v", err)
+		} else {
+			log.Println("Content reloaded")
+		}
+		return running
+	}
+
+	next, changes := config.Reload(running, next)
+	if err := r.Reload(next, siteFiles(next.App.PreferDisk)); err != nil {
+		log.Printf("Error reloading content, keeping the current configuration: %v", err)
+		return running
+	}
+	logLevel.Set(parseLogLevel(next.App.LogLevel))
+
+	for _, change := range changes {
+		log.Printf("Configuration changed: %s", change)
+	}
+	log.Printf("Configuration and content reloaded (%d changes)", len(changes))
+	return next
+}
+
+// parseLogLevel maps LOG_LEVEL to a slog level, defaulting to info
+func parseLogLevel(level string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// newTracer creates the tracer for the configured exporter. The returned
+// function closes any file the exporter writes to.
+func newTracer(cfg config.TracingConfig) (*tracing.Tracer, func(), error) {
+	switch cfg.Exporter {
+	case "otlp":
+		log.Printf("Exporting traces to %s", cfg.Endpoint)
+		return tracing.NewTracer(tracing.NewOTLPExporter(cfg.ServiceName, cfg.Endpoint)), func() {}, nil
+	case "stdout":
+		return tracing.NewTracer(tracing.NewWriterExporter(cfg.ServiceName, os.Stdout)), func() {}, nil
+	case "file":
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tracing.NewTracer(tracing.NewWriterExporter(cfg.ServiceName, f)), func() { f.Close() }, nil
+	default:
+		// IDs are still generated so logs can be correlated with upstream
+		// proxies that send traceparent
+		return tracing.NewTracer(nil), func() {}, nil
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !embed
+
+package main
+
+import (
+	"io/fs"
+	"os"
+)
+
+// siteFiles returns the working directory; static/ and content/ must be
+// next to where the server is started. Build with -tags embed for a
+// self-contained binary.
+func siteFiles(bool) fs.FS {
+	return os.DirFS(".")
+}
@@ -0,0 +1,26 @@
+//go:build embed
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+	"os"
+)
+
+// embedded holds static/ and content/ so the binary can run from any
+// directory. Build with -tags embed to include them.
+//
+//go:embed static content
+var embedded embed.FS
+
+// siteFiles returns the embedded files, or the working directory when
+// preferDisk is set so edits show up without a rebuild
+func siteFiles(preferDisk bool) fs.FS {
+	if preferDisk {
+		log.Printf("Serving static files and content from disk")
+		return os.DirFS(".")
+	}
+	return embedded
+}